@@ -141,6 +141,9 @@ func RevertAction(writer io.Writer, actionName string, tuneApp *app.App) {
 	if actionName != "all" {
 		PrintHelpAndExit(writer, 1)
 	}
+	if system.IsFlagSet("to-pristine") {
+		tuneApp.RevertToPristine = true
+	}
 	reportSuc := false
 	if len(tuneApp.NoteApplyOrder) != 0 {
 		reportSuc = true
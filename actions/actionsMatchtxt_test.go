@@ -52,6 +52,7 @@ tuned.service:            disabled/active (profile: 'balanced')
 systemd system state:     running
 virtualization:           %s
 tuning:                   not tuned
+reboot required:          no
 
 Remember: if you wish to automatically activate the note's and solution's tuning options after a reboot, you must enable saptune.service by running:
  'saptune service enable'.
@@ -77,6 +78,7 @@ tuned.service:            disabled/active (profile: '')
 systemd system state:     running
 virtualization:           %s
 tuning:                   not tuned
+reboot required:          no
 
 Remember: if you wish to automatically activate the note's and solution's tuning options after a reboot, you must enable saptune.service by running:
  'saptune service enable'.
@@ -102,6 +104,7 @@ tuned.service:            disabled/active (profile: '%s')
 systemd system state:     running
 virtualization:           %s
 tuning:                   not tuned
+reboot required:          no
 
 Remember: if you wish to automatically activate the note's and solution's tuning options after a reboot, you must enable saptune.service by running:
  'saptune service enablestart'.
@@ -16,12 +16,15 @@ Daemon control:
 Tune system according to SAP and SUSE notes:
   saptune [--format FORMAT] [--force-color] [--fun] note ( list | verify | revertall | enabled | applied )
   saptune [--format FORMAT] [--force-color] [--fun] note ( apply | simulate | customise | create | edit | revert | show | delete ) NOTEID
+  saptune [--format FORMAT] [--force-color] [--fun] note apply [--atomic] NOTEID
+  saptune [--format FORMAT] [--force-color] [--fun] note revert [--to-pristine] NOTEID
   saptune [--format FORMAT] [--force-color] [--fun] note refresh [NOTEID|applied] ATTENTION: experimental
   saptune [--format FORMAT] [--force-color] [--fun] note verify [--colorscheme SCHEME] [--show-non-compliant] [NOTEID|applied]
   saptune [--format FORMAT] [--force-color] [--fun] note rename NOTEID NEWNOTEID
 Tune system for all notes applicable to your SAP solution:
   saptune [--format FORMAT] [--force-color] [--fun] solution ( list | verify | enabled | applied )
   saptune [--format FORMAT] [--force-color] [--fun] solution ( apply | simulate | customise | create | edit | revert | show | delete ) SOLUTIONNAME
+  saptune [--format FORMAT] [--force-color] [--fun] solution apply [--atomic] SOLUTIONNAME
   saptune [--format FORMAT] [--force-color] [--fun] solution change [--force] SOLUTIONNAME
   saptune [--format FORMAT] [--force-color] [--fun] solution verify [--colorscheme SCHEME] [--show-non-compliant] [SOLUTIONNAME]
   saptune [--format FORMAT] [--force-color] [--fun] solution rename SOLUTIONNAME NEWSOLUTIONNAME
@@ -30,14 +33,14 @@ Staging control:
    saptune [--format FORMAT] [--force-color] [--fun] staging ( analysis | diff ) [ ( NOTEID | SOLUTIONNAME.sol )... | all ]
    saptune [--format FORMAT] [--force-color] [--fun] staging release [--force|--dry-run] [ ( NOTEID | SOLUTIONNAME.sol )... | all ]
 Config (re-)settings:
-  saptune [--format FORMAT] [--force-color] [--fun] configure ( COLOR_SCHEME | SKIP_SYSCTL_FILES | IGNORE_RELOAD | DEBUG | TrentoASDP ) Value
+  saptune [--format FORMAT] [--force-color] [--fun] configure ( COLOR_SCHEME | SKIP_SYSCTL_FILES | IGNORE_RELOAD | DEBUG | TrentoASDP | ATOMIC_APPLY | WATCH_INTERVAL | DRIFT_REMEDIATION | PRISTINE_BASELINE | BLOCK_DEVICE_EXCLUDE | LIMITS_RUNTIME_CHECK | SYSCTL_DROPIN ) Value
   saptune [--format FORMAT] [--force-color] [--fun] configure ( reset | show )
 Verify all applied Notes:
   saptune [--format FORMAT] [--force-color] [--fun] verify applied
 Refresh all applied Notes:
   saptune [--format FORMAT] [--force-color] [--fun] refresh applied ATTENTION: experimental
 Revert all parameters tuned by the SAP notes or solutions:
-  saptune [--format FORMAT] [--force-color] [--fun] revert all
+  saptune [--format FORMAT] [--force-color] [--fun] revert all [--to-pristine]
 Remove the pending lock file from a former saptune call
   saptune [--format FORMAT] [--force-color] [--fun] lock remove
 Call external script '/usr/sbin/saptune_check'
@@ -65,12 +68,15 @@ Daemon control:
 Tune system according to SAP and SUSE notes:
   saptune [--format FORMAT] [--force-color] [--fun] note ( list | verify | revertall | enabled | applied )
   saptune [--format FORMAT] [--force-color] [--fun] note ( apply | customise | create | edit | revert | show | delete ) NOTEID
+  saptune [--format FORMAT] [--force-color] [--fun] note apply [--atomic] NOTEID
+  saptune [--format FORMAT] [--force-color] [--fun] note revert [--to-pristine] NOTEID
   saptune [--format FORMAT] [--force-color] [--fun] note refresh [NOTEID|applied] ATTENTION: experimental
   saptune [--format FORMAT] [--force-color] [--fun] note verify [--colorscheme SCHEME] [--show-non-compliant] [NOTEID|applied]
   saptune [--format FORMAT] [--force-color] [--fun] note rename NOTEID NEWNOTEID
 Tune system for all notes applicable to your SAP solution:
   saptune [--format FORMAT] [--force-color] [--fun] solution ( list | verify | enabled | applied )
   saptune [--format FORMAT] [--force-color] [--fun] solution ( apply | customise | create | edit | revert | show | delete ) SOLUTIONNAME
+  saptune [--format FORMAT] [--force-color] [--fun] solution apply [--atomic] SOLUTIONNAME
   saptune [--format FORMAT] [--force-color] [--fun] solution change [--force] SOLUTIONNAME
   saptune [--format FORMAT] [--force-color] [--fun] solution verify [--colorscheme SCHEME] [--show-non-compliant] [SOLUTIONNAME]
   saptune [--format FORMAT] [--force-color] [--fun] solution rename SOLUTIONNAME NEWSOLUTIONNAME
@@ -79,14 +85,14 @@ Staging control:
    saptune [--format FORMAT] [--force-color] [--fun] staging ( analysis | diff ) [ ( NOTEID | SOLUTIONNAME.sol )... | all ]
    saptune [--format FORMAT] [--force-color] [--fun] staging release [--force|--dry-run] [ ( NOTEID | SOLUTIONNAME.sol )... | all ]
 Config (re-)settings:
-  saptune [--format FORMAT] [--force-color] [--fun] configure ( COLOR_SCHEME | SKIP_SYSCTL_FILES | IGNORE_RELOAD | DEBUG | TrentoASDP ) Value
+  saptune [--format FORMAT] [--force-color] [--fun] configure ( COLOR_SCHEME | SKIP_SYSCTL_FILES | IGNORE_RELOAD | DEBUG | TrentoASDP | ATOMIC_APPLY | WATCH_INTERVAL | DRIFT_REMEDIATION | PRISTINE_BASELINE | BLOCK_DEVICE_EXCLUDE | LIMITS_RUNTIME_CHECK | SYSCTL_DROPIN ) Value
   saptune [--format FORMAT] [--force-color] [--fun] configure ( reset | show )
 Verify all applied Notes:
   saptune [--format FORMAT] [--force-color] [--fun] verify applied
 Refresh all applied Notes:
   saptune [--format FORMAT] [--force-color] [--fun] refresh applied ATTENTION: experimental
 Revert all parameters tuned by the SAP notes or solutions:
-  saptune [--format FORMAT] [--force-color] [--fun] revert all
+  saptune [--format FORMAT] [--force-color] [--fun] revert all [--to-pristine]
 Remove the pending lock file from a former saptune call
   saptune [--format FORMAT] [--force-color] [--fun] lock remove
 Call external script '/usr/sbin/saptune_check'
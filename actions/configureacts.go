@@ -3,6 +3,7 @@ package actions
 import (
 	"fmt"
 	"github.com/SUSE/saptune/app"
+	"github.com/SUSE/saptune/sap/note"
 	"github.com/SUSE/saptune/system"
 	"github.com/SUSE/saptune/txtparser"
 	"io"
@@ -11,7 +12,7 @@ import (
 )
 
 var mandatoryConfigKeys = []string{app.TuneForSolutionsKey, app.TuneForNotesKey, app.NoteApplyOrderKey, "SAPTUNE_VERSION", "STAGING", "COLOR_SCHEME", "SKIP_SYSCTL_FILES", "IGNORE_RELOAD"}
-var changeableConfigKeys = []string{"COLOR_SCHEME", "SKIP_SYSCTL_FILES", "IGNORE_RELOAD", "DEBUG", "TrentoASDP"}
+var changeableConfigKeys = []string{"COLOR_SCHEME", "SKIP_SYSCTL_FILES", "IGNORE_RELOAD", "DEBUG", "TrentoASDP", app.AtomicApplyKey, "WATCH_INTERVAL", app.DriftRemediationKey, app.PristineBaselineKey, "BLOCK_DEVICE_EXCLUDE", "LIMITS_RUNTIME_CHECK", "SYSCTL_DROPIN"}
 
 // MandKeyList returns a list of mandatory configuration parameter, which need
 // to be available in the saptune configuration file
@@ -45,6 +46,20 @@ func ConfigureAction(writer io.Writer, configEntry string, configVals []string,
 		ConfigureActionSetDebug(configVals[0])
 	case "TrentoASDP":
 		ConfigureActionSetTrentoASDP(configVals[0])
+	case app.AtomicApplyKey:
+		ConfigureActionSetAtomicApply(configVals[0])
+	case "WATCH_INTERVAL":
+		ConfigureActionSetWatchInterval(configVals[0])
+	case app.DriftRemediationKey:
+		ConfigureActionSetDriftRemediation(configVals)
+	case app.PristineBaselineKey:
+		ConfigureActionSetPristineBaseline(configVals[0])
+	case "BLOCK_DEVICE_EXCLUDE":
+		ConfigureActionSetBlockDeviceExclude(configVals)
+	case "LIMITS_RUNTIME_CHECK":
+		ConfigureActionSetLimitsRuntimeCheck(configVals[0])
+	case "SYSCTL_DROPIN":
+		ConfigureActionSetSysctlDropIn(configVals[0], tuneApp)
 	case "reset":
 		ConfigureActionReset(os.Stdin, writer, tuneApp)
 	case "show":
@@ -84,6 +99,120 @@ func ConfigureActionSetDebug(configVal string) {
 	}
 }
 
+// ConfigureActionSetAtomicApply sets the variable ATOMIC_APPLY
+func ConfigureActionSetAtomicApply(configVal string) {
+	switch configVal {
+	case "yes", "no":
+		writeConfigEntry(app.AtomicApplyKey, configVal)
+	default:
+		system.ErrorExit("wrong value '%s' for config variable '%s'. Only 'yes' or 'no' supported. Please check.", configVal, app.AtomicApplyKey)
+	}
+}
+
+// ConfigureActionSetWatchInterval sets the interval (in seconds) of the
+// drift detection timer 'saptune-watch.timer'
+func ConfigureActionSetWatchInterval(configVal string) {
+	if err := system.ChkWatchInterval(configVal); err != nil {
+		system.ErrorExit("%v. Please check.", err)
+	}
+	changed, err := system.SetWatchInterval(configVal)
+	if err != nil {
+		system.ErrorExit("failed to set the interval of '%s' - %v", system.SaptuneWatchTimer, err)
+	}
+	writeConfigEntry(app.WatchIntervalKey, configVal)
+	if changed {
+		if err := system.SystemctlDaemonReload(); err != nil {
+			system.ErrorExit("", 1)
+		}
+		if active, _ := system.SystemctlIsRunning(system.SaptuneWatchTimer); active {
+			// restart the timer to use the new interval immediately
+			if err := system.SystemctlRestart(system.SaptuneWatchTimer); err != nil {
+				system.ErrorExit("", 1)
+			}
+		}
+	}
+}
+
+// ConfigureActionSetDriftRemediation sets the list of note sections, which
+// will be remediated after a drift was detected by 'saptune-watch.timer'
+// An empty value disables the remediation
+func ConfigureActionSetDriftRemediation(configVals []string) {
+	sections := []string{}
+	for _, val := range configVals {
+		for _, sect := range strings.Split(val, ",") {
+			sect = strings.TrimSpace(sect)
+			if sect == "" {
+				continue
+			}
+			if !app.IsSectionRemediable(sect) {
+				system.ErrorExit("wrong value '%s' for config variable '%s'. Only the sections 'sysctl', 'sys', 'vm' and 'block' are supported. Please check.", sect, app.DriftRemediationKey)
+			}
+			sections = append(sections, sect)
+		}
+	}
+	writeConfigEntry(app.DriftRemediationKey, strings.Join(sections, " "))
+}
+
+// ConfigureActionSetPristineBaseline sets the variable PRISTINE_BASELINE
+func ConfigureActionSetPristineBaseline(configVal string) {
+	switch configVal {
+	case "yes":
+		writeConfigEntry(app.PristineBaselineKey, configVal)
+		// Notes already applied, the start values of the
+		// parameter state files are the values before the tuning
+		note.CreatePristineFromStartValues()
+	case "no":
+		writeConfigEntry(app.PristineBaselineKey, configVal)
+	default:
+		system.ErrorExit("wrong value '%s' for config variable '%s'. Only 'yes' or 'no' supported. Please check.", configVal, app.PristineBaselineKey)
+	}
+}
+
+// ConfigureActionSetBlockDeviceExclude sets the list of block devices,
+// which should never be tuned
+func ConfigureActionSetBlockDeviceExclude(configVals []string) {
+	devs := []string{}
+	for _, val := range configVals {
+		devs = append(devs, strings.FieldsFunc(val, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+	writeConfigEntry("BLOCK_DEVICE_EXCLUDE", strings.Join(devs, " "))
+}
+
+// ConfigureActionSetLimitsRuntimeCheck enables or disables the check of the
+// effective limits of the running processes during verify
+func ConfigureActionSetLimitsRuntimeCheck(configVal string) {
+	switch configVal {
+	case "yes", "no":
+		writeConfigEntry("LIMITS_RUNTIME_CHECK", configVal)
+	default:
+		system.ErrorExit("wrong value '%s' for config variable '%s'. Only 'yes' or 'no' supported. Please check.", configVal, "LIMITS_RUNTIME_CHECK")
+	}
+}
+
+// ConfigureActionSetSysctlDropIn sets the location of the saptune sysctl
+// drop-in file, which contains the sysctl values of the applied Notes.
+// An existing drop-in file is moved to the new location or removed, if
+// set to 'no'. If the drop-in file gets enabled, the values of the already
+// applied Notes are written
+func ConfigureActionSetSysctlDropIn(configVal string, tuneApp *app.App) {
+	if !system.IsValidSysctlDropIn(configVal) {
+		system.ErrorExit("wrong value '%s' for config variable '%s'. Only 'no', 'etc' or 'run' supported. Please check.", configVal, "SYSCTL_DROPIN")
+	}
+	oldVal := system.SysctlDropInLocation()
+	if err := system.MoveSysctlDropIn(oldVal, configVal); err != nil {
+		system.ErrorExit("failed to move the saptune sysctl drop-in file - %v", err)
+	}
+	system.SetSysctlDropIn(configVal)
+	if oldVal == "no" && configVal != "no" {
+		if err := note.WriteSysctlDropIn(tuneApp.NoteApplyOrder); err != nil {
+			system.ErrorExit("failed to write the saptune sysctl drop-in file '%s' - %v", system.SysctlDropIn(), err)
+		}
+	}
+	writeConfigEntry("SYSCTL_DROPIN", configVal)
+}
+
 // ConfigureActionSetTrentoASDP sets the saptune-discovery-period of the
 // Trento Agent
 func ConfigureActionSetTrentoASDP(configVal string) {
@@ -172,6 +301,8 @@ func ConfigureActionReset(reader io.Reader, writer io.Writer, tuneApp *app.App)
 		os.RemoveAll(system.SaptuneSectionDir)
 		os.RemoveAll(system.SaptuneParameterStateDir)
 		os.RemoveAll(system.SaptuneSavedStateDir)
+		note.CleanUpPristine()
+		system.CleanUpSysctlDropIn()
 
 		// set configuration file back to default/delivery
 		saptuneTemplate := system.SaptuneConfigTemplate()
@@ -30,6 +30,13 @@ const (
 	footnote14   = "[14] the parameter value exceeds the maximum possible number of open files. Check and increase fs.nr_open if really needed."
 	footnote15   = "[15] the parameter is only used to calculate the size of tmpfs (/dev/shm)"
 	footnote16   = "[16] parameter not available on the system, setting not possible"
+	footnote17   = "[17] configured, but only active after a reboot: REASON"
+	footnote18   = "[18] value differs between the layers of the block device stack: LAYERS"
+	footnote19   = "[19] setting not possible for the block device: REASON"
+	footnote20   = "[20] expected value not offered by the kernel, valid values: CHOICES"
+	footnote21   = "[21] limit not effective for services, lower systemd manager default: DEFAULT"
+	footnote22   = "[22] effective limit of the running processes: PROCESSES"
+	footnote23   = "[23] CPU vulnerability known by the kernel, but without expectation in the Note"
 )
 
 // set 'unsupported' footnote regarding the architecture
@@ -64,7 +71,7 @@ func prepFN(comparison note.FieldComparison, compliant, inform string) bool {
 
 // prepareFootnote prepares the content of the last column and the
 // corresponding footnotes
-func prepareFootnote(comparison note.FieldComparison, compliant, comment, inform string, footnote []string) (string, string, []string) {
+func prepareFootnote(comparison note.FieldComparison, compliant, comment, inform string, pending map[string]string, footnote []string) (string, string, []string) {
 	if !prepFN(comparison, compliant, inform) {
 		return compliant, comment, footnote
 	}
@@ -80,7 +87,7 @@ func prepareFootnote(comparison note.FieldComparison, compliant, comment, inform
 	}
 	// set footnote for unsupported or not available parameter [1],[2]
 	compliant, comment, footnote = setUsNa(comparison.ActualValue.(string), compliant, comment, footnote)
-	// set footnote for rpm or grub parameter [3],[6]
+	// set footnote for rpm, grub, swap, timesync or mitigations parameter [3],[6]
 	compliant, comment, footnote = setRpmGrub(comparison, compliant, comment, footnote)
 	// set footnote for diffs in force_latency parameter [4]
 	compliant, comment, footnote = setFLdiffs(comparison.ReflectMapKey, compliant, comment, inform, footnote)
@@ -104,6 +111,20 @@ func prepareFootnote(comparison note.FieldComparison, compliant, comment, inform
 	compliant, comment, footnote = setNofile(comparison.ReflectMapKey, compliant, comment, inform, footnote)
 	// set footnote for VSZ_TMPFS_PERCENT parameter from mem section
 	compliant, comment, footnote = setMem(comparison.ReflectMapKey, compliant, comment, footnote)
+	// set footnote for parameter waiting for a reboot [17]
+	compliant, comment, footnote = setPendingReboot(comparison, pending, compliant, comment, footnote)
+	// set footnote for block device stack layers with different values [18]
+	compliant, comment, footnote = setBlkLayers(comparison.ReflectMapKey, compliant, comment, inform, footnote)
+	// set footnote for invalid block device tunables [19]
+	compliant, comment, footnote = setBlkInval(comparison.ReflectMapKey, compliant, comment, inform, footnote)
+	// set footnote for values not offered by the kernel [20]
+	compliant, comment, footnote = setChoices(comparison.ReflectMapKey, compliant, comment, inform, footnote)
+	// set footnote for limits undermined by the systemd manager default [21]
+	compliant, comment, footnote = setManagerLimit(comparison.ReflectMapKey, compliant, comment, inform, footnote)
+	// set footnote for the effective limits of the running processes [22]
+	compliant, comment, footnote = setRuntimeLimit(comparison.ReflectMapKey, compliant, comment, inform, footnote)
+	// set footnote for CPU vulnerabilities without expectation [23]
+	compliant, comment, footnote = setUnexpectedVuln(comparison.ReflectMapKey, compliant, comment, inform, footnote)
 	return compliant, comment, footnote
 }
 
@@ -129,10 +150,10 @@ func setUsNa(actVal, compliant, comment string, footnote []string) (string, stri
 	return compliant, comment, footnote
 }
 
-// setRpmGrub sets footnote for rpm or grub parameter
+// setRpmGrub sets footnote for rpm, grub, swap, timesync or mitigations parameter
 func setRpmGrub(comparison note.FieldComparison, compliant, comment string, footnote []string) (string, string, []string) {
 	mapKey := comparison.ReflectMapKey
-	if strings.Contains(mapKey, "rpm") || strings.Contains(mapKey, "grub") {
+	if strings.Contains(mapKey, "rpm") || strings.Contains(mapKey, "grub") || strings.HasPrefix(mapKey, "swap:") || strings.HasPrefix(mapKey, "timesync:") || strings.HasPrefix(mapKey, "mitigations:") {
 		compliant = compliant + " [3]"
 		comment = comment + " [3]"
 		footnote[2] = footnote3
@@ -212,27 +233,30 @@ func setLimited(mapKey, compliant, comment, info string, footnote []string) (str
 
 // setDouble sets footnote for double defined sys parameters
 func setDouble(mapKey, compliant, comment, info string, footnote []string) (string, string, []string) {
-	if (system.IsSched.MatchString(mapKey) || system.IsNrreq.MatchString(mapKey) || system.IsRahead.MatchString(mapKey) || system.IsMsect.MatchString(mapKey)) && info != "" {
+	if system.BlockKeyDevice(mapKey) != "" && info != "" {
 		// check for double defined parameters
 		sect := regexp.MustCompile(`.*\[\w+\].*`)
-		inf := strings.Split(info, "§")
-		if len(inf) > 1 {
-			if inf[0] != "limited" && inf[0] != "NA" {
-				info = inf[0]
-			} else {
-				info = inf[1]
+		// info may contain additional block device information
+		// separated by '§' (e.g. 'limited', 'NA' or 'layers:...')
+		for _, inf := range strings.Split(info, "§") {
+			if inf != "limited" && inf != "NA" && sect.MatchString(inf) {
+				compliant = compliant + " [10]"
+				comment = comment + " [10]"
+				footnote[9] = writeFN(footnote[9], footnote10, inf, "SECT")
+				break
 			}
 		}
-		if info != "limited" && info != "NA" && sect.MatchString(info) {
-			compliant = compliant + " [10]"
-			comment = comment + " [10]"
-			footnote[9] = writeFN(footnote[9], footnote10, info, "SECT")
-		}
 	}
 	if (strings.Contains(mapKey, "THP") || strings.Contains(mapKey, "KSM")) && info != "" {
-		compliant = compliant + " [10]"
-		comment = comment + " [10]"
-		footnote[9] = writeFN(footnote[9], footnote10, info, "SECT")
+		// skip the valid values offered by the kernel ('choices:...')
+		for _, inf := range strings.Split(info, "§") {
+			if inf != "" && !strings.HasPrefix(inf, "choices:") {
+				compliant = compliant + " [10]"
+				comment = comment + " [10]"
+				footnote[9] = writeFN(footnote[9], footnote10, inf, "SECT")
+				break
+			}
+		}
 	}
 	if strings.Contains(mapKey, "sys:") && info != "" {
 		compliant = compliant + " [10]"
@@ -274,7 +298,7 @@ func setFSOptions(comparison note.FieldComparison, compliant, comment, info stri
 
 // setNofile sets footnote for unsupported nofile limit value
 func setNofile(mapKey, compliant, comment, info string, footnote []string) (string, string, []string) {
-	if strings.Contains(mapKey, "LIMIT_") && info == "limit_exceeded" {
+	if strings.Contains(mapKey, "LIMIT_") && strings.Contains(info, "limit_exceeded") {
 		compliant = compliant + " [14]"
 		comment = comment + " [14]"
 		footnote[13] = footnote14
@@ -296,6 +320,62 @@ func setMem(mapKey, compliant, comment string, footnote []string) (string, strin
 	return compliant, comment, footnote
 }
 
+// setPendingReboot sets footnote for parameter, which are configured, but
+// only get active after a reboot. 'pending' are the registered 'pending
+// reboot' settings, read once per table
+func setPendingReboot(comparison note.FieldComparison, pending map[string]string, compliant, comment string, footnote []string) (string, string, []string) {
+	if comparison.MatchExpectation {
+		return compliant, comment, footnote
+	}
+	expVal, _ := comparison.ExpectedValue.(string)
+	if reason := system.PendingRebootReasonOf(pending, comparison.ReflectMapKey, expVal); reason != "" {
+		compliant = compliant + " [17]"
+		comment = comment + " [17]"
+		footnote[16] = writeFN(footnote[16], footnote17, comparison.ReflectMapKey+" - "+reason, "REASON")
+	}
+	return compliant, comment, footnote
+}
+
+// setBlkLayers sets footnote for block device parameters, whose value
+// differs between the layers of a block device stack
+func setBlkLayers(mapKey, compliant, comment, info string, footnote []string) (string, string, []string) {
+	for _, inf := range strings.Split(info, "§") {
+		if strings.HasPrefix(inf, "layers:") {
+			compliant = compliant + " [18]"
+			comment = comment + " [18]"
+			footnote[17] = writeFN(footnote[17], footnote18, mapKey+" - "+strings.TrimPrefix(inf, "layers:"), "LAYERS")
+		}
+	}
+	return compliant, comment, footnote
+}
+
+// setBlkInval sets footnote for block device tunables, which can not be set
+// for the block device (invalid value or the needed I/O scheduler is not
+// used for the block device)
+func setBlkInval(mapKey, compliant, comment, info string, footnote []string) (string, string, []string) {
+	for _, inf := range strings.Split(info, "§") {
+		if strings.HasPrefix(inf, "blkinval:") {
+			compliant = compliant + " [19]"
+			comment = comment + " [19]"
+			footnote[18] = writeFN(footnote[18], footnote19, mapKey+" - "+strings.TrimPrefix(inf, "blkinval:"), "REASON")
+		}
+	}
+	return compliant, comment, footnote
+}
+
+// setChoices sets footnote for parameters, whose expected value is not
+// offered by the kernel and lists the valid values
+func setChoices(mapKey, compliant, comment, info string, footnote []string) (string, string, []string) {
+	for _, inf := range strings.Split(info, "§") {
+		if strings.HasPrefix(inf, "choices:") {
+			compliant = compliant + " [20]"
+			comment = comment + " [20]"
+			footnote[19] = writeFN(footnote[19], footnote20, mapKey+" - "+strings.TrimPrefix(inf, "choices:"), "CHOICES")
+		}
+	}
+	return compliant, comment, footnote
+}
+
 // writeFN customizes the text for footnotes by replacing strings/placeholder
 func writeFN(footnote, fntxt, info, pat string) string {
 	if footnote == "" {
@@ -305,3 +385,40 @@ func writeFN(footnote, fntxt, info, pat string) string {
 	}
 	return footnote
 }
+
+// setManagerLimit sets footnote for limits, which are undermined for
+// services by a lower systemd manager default
+func setManagerLimit(mapKey, compliant, comment, info string, footnote []string) (string, string, []string) {
+	for _, inf := range strings.Split(info, "§") {
+		if strings.HasPrefix(inf, "manager:") {
+			compliant = compliant + " [21]"
+			comment = comment + " [21]"
+			footnote[20] = writeFN(footnote[20], footnote21, mapKey+" - "+strings.TrimPrefix(inf, "manager:"), "DEFAULT")
+		}
+	}
+	return compliant, comment, footnote
+}
+
+// setRuntimeLimit sets footnote with the effective limits of the running
+// processes of a limits domain (LIMITS_RUNTIME_CHECK)
+func setRuntimeLimit(mapKey, compliant, comment, info string, footnote []string) (string, string, []string) {
+	for _, inf := range strings.Split(info, "§") {
+		if strings.HasPrefix(inf, "runtime:") {
+			compliant = compliant + " [22]"
+			comment = comment + " [22]"
+			footnote[21] = writeFN(footnote[21], footnote22, mapKey+" - "+strings.TrimPrefix(inf, "runtime:"), "PROCESSES")
+		}
+	}
+	return compliant, comment, footnote
+}
+
+// setUnexpectedVuln sets footnote for CPU vulnerabilities known by the
+// kernel, which have no expectation in the [mitigations] section of the Note
+func setUnexpectedVuln(mapKey, compliant, comment, info string, footnote []string) (string, string, []string) {
+	if strings.HasPrefix(mapKey, "mitigations:") && info == "unexpected" {
+		compliant = compliant + " [23]"
+		comment = comment + " [23]"
+		footnote[22] = footnote23
+	}
+	return compliant, comment, footnote
+}
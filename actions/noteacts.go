@@ -72,6 +72,9 @@ func NoteActionApply(writer io.Writer, noteID string, tuneApp *app.App) {
 		}
 		system.ErrorExit("", 0)
 	}
+	if system.IsFlagSet("atomic") {
+		tuneApp.AtomicApply = true
+	}
 	if err := tuneApp.TuneNote(noteID); err != nil {
 		system.ErrorExit("Failed to tune for note %s: %v", noteID, err)
 	}
@@ -434,6 +437,9 @@ func NoteActionRevert(writer io.Writer, noteID string, tuneApp *app.App) {
 	if noteID == "" {
 		PrintHelpAndExit(writer, 1)
 	}
+	if system.IsFlagSet("to-pristine") {
+		tuneApp.RevertToPristine = true
+	}
 	// 'ok' only used to control the log messages
 	// call RevertNote in any case to get the chance of clean up
 	_, ok := tuneApp.IsNoteApplied(noteID)
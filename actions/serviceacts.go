@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/SUSE/saptune/app"
+	"github.com/SUSE/saptune/sap/note"
 	"github.com/SUSE/saptune/system"
 	"github.com/SUSE/saptune/txtparser"
 	"io"
@@ -61,6 +62,9 @@ func ServiceAction(writer io.Writer, actionName, saptuneVersion string, tApp *ap
 		ServiceActionStop(false)
 	case "takeover":
 		ServiceActionTakeover(tApp)
+	case "watch":
+		// This action name is only used by saptune-watch.service, hence it is not advertised to end user.
+		ServiceActionWatch(tApp)
 	default:
 		PrintHelpAndExit(writer, 1)
 	}
@@ -170,6 +174,61 @@ func ServiceActionApply(tuneApp *app.App) {
 	if err := tuneApp.TuneAll(); err != nil {
 		system.ErrorExit("%v", err)
 	}
+	rebuildSysctlDropIn(tuneApp)
+	syncWatchInterval(tuneApp)
+}
+
+// rebuildSysctlDropIn writes the saptune sysctl drop-in file from scratch
+// with the values of the applied Notes. A drop-in file, which survived a
+// reboot, may contain keys of Notes, which are no longer applied
+func rebuildSysctlDropIn(tuneApp *app.App) {
+	if system.SysctlDropIn() == "" {
+		return
+	}
+	if err := system.RemoveSysctlDropIn(); err != nil {
+		system.WarningLog("failed to remove the saptune sysctl drop-in file '%s' - %v", system.SysctlDropIn(), err)
+		return
+	}
+	if err := note.WriteSysctlDropIn(tuneApp.NoteApplyOrder); err != nil {
+		system.WarningLog("failed to write the saptune sysctl drop-in file '%s' - %v", system.SysctlDropIn(), err)
+	}
+}
+
+// removeSysctlDropIn removes the saptune sysctl drop-in file, so that
+// systemd-sysctl does not set the tuned values during the next boot
+func removeSysctlDropIn() {
+	if err := system.RemoveSysctlDropIn(); err != nil {
+		system.WarningLog("failed to remove the saptune sysctl drop-in file '%s' - %v", system.SysctlDropIn(), err)
+	}
+}
+
+// syncWatchInterval syncs the interval of the drift detection timer with
+// WATCH_INTERVAL of the saptune configuration during start and reload of
+// the saptune service
+func syncWatchInterval(tuneApp *app.App) {
+	changed, err := system.SyncWatchInterval(tuneApp.WatchInterval)
+	if err != nil {
+		system.WarningLog("failed to set the interval of '%s' from '%s' - %v", system.SaptuneWatchTimer, app.WatchIntervalKey, err)
+		return
+	}
+	if changed {
+		// the timer uses the new interval for the next elapse
+		_ = system.SystemctlDaemonReload()
+	}
+}
+
+// ServiceActionWatch runs the drift detection of the applied notes
+// It is triggered periodically by the systemd timer 'saptune-watch.timer'
+func ServiceActionWatch(tuneApp *app.App) {
+	status, err := tuneApp.WatchDrift(system.SaptuneWatchStatusFile)
+	if err != nil {
+		system.ErrorExit("drift detection failed - %v", err)
+	}
+	if status.Compliant {
+		system.InfoLog("drift detection: all applied Notes are compliant")
+	} else {
+		system.InfoLog("drift detection: %d parameters of the applied Notes are not compliant, see '%s'", len(status.Drifts), system.SaptuneWatchStatusFile)
+	}
 }
 
 // ServiceActionEnable enables the saptune service
@@ -202,6 +261,10 @@ func ServiceActionStatus(writer io.Writer, tuneApp *app.App, saptuneVersion stri
 	jstatus := system.JStatus{}
 	jstatServs := system.JStatusServs{}
 	jstatStage := system.JStatusStaging{}
+	// fetch the states of the services of interest with a single query
+	system.EnableFactCache()
+	defer system.DisableFactCache()
+	system.QueryUnitStates(SaptuneService, SapconfService, TunedService)
 	fmt.Fprintln(writer, "")
 	// check for running saptune.service
 	infoTrigger["saptuneStopped"], infoTrigger["remember"], infoTrigger["stenabled"] = printSaptuneStatus(writer, &jstatServs)
@@ -228,7 +291,11 @@ func ServiceActionStatus(writer io.Writer, tuneApp *app.App, saptuneVersion stri
 	printVirtStatus(writer, &jstatus)
 
 	// check tuning result
-	infoTrigger["notCompliant"] = chkTuningResult(writer, tuneApp, &jstatus)
+	var comparisons map[string]map[string]note.FieldComparison
+	infoTrigger["notCompliant"], comparisons = chkTuningResult(writer, tuneApp, &jstatus)
+
+	// check for settings waiting for a reboot
+	infoTrigger["rebootRequired"] = printRebootStatus(writer, comparisons, &jstatus)
 
 	infoMsg := bytes.Buffer{}
 	if system.GetFlagVal("format") == "json" {
@@ -279,6 +346,9 @@ func ServiceActionStop(disableService bool) {
 	if err != nil {
 		system.ErrorExit("%v", err)
 	}
+	if disableService {
+		removeSysctlDropIn()
+	}
 	system.NoticeLog(saptuneInfo)
 	// saptune.service then calls `saptune daemon revert` to
 	// revert all tuned parameter
@@ -325,9 +395,19 @@ func ServiceActionRevert(tuneApp *app.App) {
 	} else {
 		system.WarningLog("ignore flag set, skipping check for active sapconf service")
 	}
+	if state, _ := system.GetSystemState(); state == "stopping" && system.SysctlDropInLocation() == "etc" {
+		// keep the saptune sysctl drop-in file during system shutdown,
+		// so that systemd-sysctl sets the tuned values during the
+		// next boot
+		system.NoticeLog("system is shutting down, keeping the saptune sysctl drop-in file '%s'", system.SysctlDropIn())
+		system.SetSysctlDropIn("no")
+	}
 	if err := tuneApp.RevertAll(false); err != nil {
 		system.ErrorExit("%v", err)
 	}
+	// remove the keys of Notes, which are no longer applied, too
+	// Nothing to do during shutdown, the drop-in file is kept above
+	removeSysctlDropIn()
 }
 
 // ServiceActionDisable disables the saptune service
@@ -338,6 +418,7 @@ func ServiceActionDisable() {
 		system.ErrorExit("%v", err)
 	}
 	system.NoticeLog("Service 'saptune.service' has been disabled.")
+	removeSysctlDropIn()
 	active, err := system.SystemctlIsRunning(SaptuneService)
 	if err != nil {
 		system.ErrorExit("%v", err)
@@ -720,7 +801,11 @@ func printSystemdStatus(writer io.Writer, jstat *system.JStatus) bool {
 }
 
 // chkTuningResult verifies the tuning state of the system
-func chkTuningResult(writer io.Writer, tuneApp *app.App, jstat *system.JStatus) bool {
+// returns the comparison results of the verify for later use
+func chkTuningResult(writer io.Writer, tuneApp *app.App, jstat *system.JStatus) (bool, map[string]map[string]note.FieldComparison) {
+	var comparisons map[string]map[string]note.FieldComparison
+	var unsatisfiedNotes []string
+	var err error
 	notCompliant := false
 	tuningResult := "not-present"
 	appliedNotes := tuneApp.AppliedNotes()
@@ -730,7 +815,7 @@ func chkTuningResult(writer io.Writer, tuneApp *app.App, jstat *system.JStatus)
 		tuningResult = "not tuned"
 	} else {
 		oldStdout, oldStderr := system.SwitchOffOut()
-		unsatisfiedNotes, _, err := tuneApp.VerifyAll(false)
+		unsatisfiedNotes, comparisons, err = tuneApp.VerifyAll(false)
 		system.SwitchOnOut(oldStdout, oldStderr)
 		if err != nil {
 			system.WarningLog("Failed to verify the tuning state of the current system: %v", err)
@@ -746,7 +831,26 @@ func chkTuningResult(writer io.Writer, tuneApp *app.App, jstat *system.JStatus)
 	}
 	fmt.Fprintf(writer, "tuning:                   %s\n", tuningResult)
 	jstat.TuningState = tuningResult
-	return notCompliant
+	return notCompliant, comparisons
+}
+
+// printRebootStatus prints, if settings are configured, which only get
+// active after a reboot of the system
+func printRebootStatus(writer io.Writer, comparisons map[string]map[string]note.FieldComparison, jstat *system.JStatus) bool {
+	reboots := app.PendingReboots(comparisons)
+	jstat.RebootParams = reboots
+	jstat.RebootRequired = len(reboots) != 0
+	if len(reboots) == 0 {
+		fmt.Fprintf(writer, "reboot required:          no\n")
+		return false
+	}
+	params := []string{}
+	for _, entry := range reboots {
+		system.InfoLog("reboot required for '%s' - %s", entry.Parameter, entry.Reason)
+		params = append(params, entry.Parameter)
+	}
+	fmt.Fprintf(writer, "reboot required:          yes (%s)\n", strings.Join(params, ", "))
+	return true
 }
 
 // printVirtStatus prints the virtualization environment
@@ -777,6 +881,9 @@ func printInfoBlock(writer io.Writer, infoTrigger map[string]bool) {
 	if infoTrigger["notCompliant"] {
 		fmt.Fprintf(writer, "Regarding the tuning state of the system please use 'saptune note verify' for detailed information.\n")
 	}
+	if infoTrigger["rebootRequired"] {
+		fmt.Fprintf(writer, "Some settings are configured, but only get active after a reboot. Please reboot the system.\n")
+	}
 	if infoTrigger["chkHint"] {
 		fmt.Fprintf(writer, "The systemd system state is NOT ok.\n")
 	}
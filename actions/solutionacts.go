@@ -67,6 +67,9 @@ func SolutionActionApply(writer io.Writer, solName string, tuneApp *app.App) {
 		// do not apply another solution. Does not make sense
 		system.ErrorExit("There is already one solution applied. Applying another solution is NOT supported.", 1)
 	}
+	if system.IsFlagSet("atomic") {
+		tuneApp.AtomicApply = true
+	}
 	applySolution(writer, solName, tuneApp)
 }
 
@@ -37,14 +37,19 @@ func PrintNoteFields(writer io.Writer, header string, noteComparisons map[string
 
 	var compliant string
 	var comment string
-	var footnote []string = make([]string, 16)
+	var footnote []string = make([]string, 23)
 
 	colorScheme := getColorScheme()
 	// sort output
 	sortkeys := sortNoteComparisonsOutput(noteComparisons)
+	// stable identifiers of the block devices, displayed next to the
+	// kernel name
+	stableIDs := system.BlockDeviceStableIDs()
+	// parameters waiting for a reboot, read once for all rows
+	pendingReboots := system.PendingRebootReasons()
 
 	// setup table format values
-	fmtlen0, fmtlen1, fmtlen2, fmtlen3, fmtlen4, format := setupTableFormat(sortkeys, noteComparisons, printComparison)
+	fmtlen0, fmtlen1, fmtlen2, fmtlen3, fmtlen4, format := setupTableFormat(sortkeys, noteComparisons, printComparison, stableIDs)
 
 	// print
 	noteID := ""
@@ -66,7 +71,7 @@ func PrintNoteFields(writer io.Writer, header string, noteComparisons map[string
 		inform := getInformSettings(noteID, noteComparisons, comparison)
 
 		// prepare footnote
-		compliant, comment, footnote = prepareFootnote(comparison, compliant, comment, inform, footnote)
+		compliant, comment, footnote = prepareFootnote(comparison, compliant, comment, inform, pendingReboots, footnote)
 
 		// print table header
 		if printHead != "" {
@@ -90,6 +95,7 @@ func PrintNoteFields(writer io.Writer, header string, noteComparisons map[string
 		noteLine.ActValue = &pAct
 
 		tableColumns := make(map[string]string)
+		param := blockParameter(comparison.ReflectMapKey, stableIDs)
 		if printComparison {
 			// verify
 			if system.IsFlagSet("show-non-compliant") && (strings.Contains(compliant, "yes") || strings.Contains(compliant, "-")) {
@@ -97,10 +103,10 @@ func PrintNoteFields(writer io.Writer, header string, noteComparisons map[string
 				continue
 			}
 			colFormat, colCompliant = colorPrint(format, compliant, colorScheme)
-			tableColumns = map[string]string{"type": "verify", "colFormat": colFormat, "note": noteField, "parameter": comparison.ReflectMapKey, "expected": pExp, "override": override, "actual": pAct, "compliant": colCompliant}
+			tableColumns = map[string]string{"type": "verify", "colFormat": colFormat, "note": noteField, "parameter": param, "expected": pExp, "override": override, "actual": pAct, "compliant": colCompliant}
 		} else {
 			// simulate
-			tableColumns = map[string]string{"type": "simulate", "colFormat": format, "parameter": comparison.ReflectMapKey, "actual": pAct, "expected": pExp, "override": override, "comment": comment}
+			tableColumns = map[string]string{"type": "simulate", "colFormat": format, "parameter": param, "actual": pAct, "expected": pExp, "override": override, "comment": comment}
 		}
 		printTableRow(writer, tableColumns)
 		noteLine = collectMRO(noteLine, compliant, noteID, noteComparisons, comparison, pExp, override, printComparison, comment, footnote, pAct)
@@ -197,7 +203,7 @@ func sortNoteComparisonsOutput(noteCompare map[string]map[string]note.FieldCompa
 }
 
 // setupTableFormat sets the format of the table columns dependent on the content
-func setupTableFormat(skeys []string, noteCompare map[string]map[string]note.FieldComparison, printComp bool) (int, int, int, int, int, string) {
+func setupTableFormat(skeys []string, noteCompare map[string]map[string]note.FieldComparison, printComp bool, stableIDs map[string]string) (int, int, int, int, int, string) {
 	var fmtlen0, fmtlen1, fmtlen2, fmtlen3, fmtlen4 int
 	format := "\t%s : %s\n"
 	// define start values for the column width
@@ -225,6 +231,9 @@ func setupTableFormat(skeys []string, noteCompare map[string]map[string]note.Fie
 			if comparison.ReflectMapKey == "reminder" || comparison.ReflectFieldName == "Inform" {
 				continue
 			}
+			if comparison.ReflectFieldName != "OverrideParams" {
+				comparison.ReflectMapKey = blockParameter(comparison.ReflectMapKey, stableIDs)
+			}
 			if printComp {
 				// verify
 				if len(noteField) > fmtlen0 {
@@ -244,6 +253,17 @@ func setupTableFormat(skeys []string, noteCompare map[string]map[string]note.Fie
 	return fmtlen0, fmtlen1, fmtlen2, fmtlen3, fmtlen4, format
 }
 
+// blockParameter returns the parameter name displayed in the table.
+// For block device parameters the stable identifier (WWN, by-id link or
+// serial number) of the block device is added to the kernel name, as the
+// kernel name may change between reboots
+func blockParameter(mapKey string, stableIDs map[string]string) string {
+	if id, ok := stableIDs[system.BlockKeyDevice(mapKey)]; ok {
+		return fmt.Sprintf("%s (%s)", mapKey, id)
+	}
+	return mapKey
+}
+
 // printHeadline prints a headline for the table
 func printHeadline(writer io.Writer, header, id string, noteComparisons map[string]map[string]note.FieldComparison) {
 	if header != "NONE" {
@@ -546,3 +546,19 @@ func TestColorPrint(t *testing.T) {
 		t.Errorf("got: %+v, expected: %+v\n", cCompl, compliant)
 	}
 }
+
+func TestBlockParameter(t *testing.T) {
+	ids := map[string]string{"sda": "naa.5000c500a1b2c3d4", "nvme0n1": "nvme-eui.0025388b91b0a1b2"}
+	tests := map[string]string{
+		"IO_SCHEDULER_sda":      "IO_SCHEDULER_sda (naa.5000c500a1b2c3d4)",
+		"NRREQ_sda":             "NRREQ_sda (naa.5000c500a1b2c3d4)",
+		"READ_AHEAD_KB_nvme0n1": "READ_AHEAD_KB_nvme0n1 (nvme-eui.0025388b91b0a1b2)",
+		"MAX_SECTORS_KB_sdb":    "MAX_SECTORS_KB_sdb",
+		"vm.swappiness":         "vm.swappiness",
+	}
+	for key, exp := range tests {
+		if val := blockParameter(key, ids); val != exp {
+			t.Errorf("expected: '%s', got: '%s'", exp, val)
+		}
+	}
+}
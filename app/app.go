@@ -17,6 +17,10 @@ const (
 	TuneForSolutionsKey = "TUNE_FOR_SOLUTIONS"
 	TuneForNotesKey     = "TUNE_FOR_NOTES"
 	NoteApplyOrderKey   = "NOTE_APPLY_ORDER"
+	AtomicApplyKey      = "ATOMIC_APPLY"
+	DriftRemediationKey = "DRIFT_REMEDIATION"
+	PristineBaselineKey = "PRISTINE_BASELINE"
+	WatchIntervalKey    = "WATCH_INTERVAL"
 )
 
 // App defines the application configuration and serialised state information.
@@ -27,6 +31,11 @@ type App struct {
 	TuneForSolutions []string                     // list of solution names to tune, must always be sorted in ascending order.
 	TuneForNotes     []string                     // list of additional notes to tune, must always be sorted in ascending order.
 	NoteApplyOrder   []string                     // list of notes in applied order. Do NOT sort.
+	AtomicApply      bool                         // all-or-nothing apply, roll back on partial failure
+	DriftRemediation []string                     // list of note sections, which will be remediated after a detected drift
+	PristineBaseline bool                         // capture a persistent pristine baseline of the parameters during apply
+	RevertToPristine bool                         // revert to the pristine baseline instead of the session start values
+	WatchInterval    string                       // interval of the drift detection timer in seconds, empty for the default of the timer unit
 	State            *State                       // examine and manage serialised notes.
 }
 
@@ -46,10 +55,15 @@ func InitialiseApp(sysconfigPrefix, stateDirPrefix string, allNotes map[string]n
 		app.TuneForSolutions = sysconf.GetStringArray(TuneForSolutionsKey, []string{})
 		app.TuneForNotes = sysconf.GetStringArray(TuneForNotesKey, []string{})
 		app.NoteApplyOrder = sysconf.GetStringArray(NoteApplyOrderKey, []string{})
+		app.AtomicApply = sysconf.GetString(AtomicApplyKey, "no") == "yes"
+		app.DriftRemediation = sysconf.GetStringArray(DriftRemediationKey, []string{})
+		app.PristineBaseline = sysconf.GetString(PristineBaselineKey, "no") == "yes"
+		app.WatchInterval = sysconf.GetString(WatchIntervalKey, "")
 	} else {
 		app.TuneForSolutions = []string{}
 		app.TuneForNotes = []string{}
 		app.NoteApplyOrder = []string{}
+		app.DriftRemediation = []string{}
 	}
 	sort.Strings(app.TuneForSolutions)
 	sort.Strings(app.TuneForNotes)
@@ -180,6 +194,7 @@ func (app *App) RevertAll(permanent bool) error {
 func (app *App) VerifyAll(chkApplied bool) (unsatisfiedNotes []string, comparisons map[string]map[string]note.FieldComparison, err error) {
 	unsatisfiedNotes = make([]string, 0)
 	comparisons = make(map[string]map[string]note.FieldComparison)
+	noteIDs := []string{}
 	for _, noteID := range app.NoteApplyOrder {
 		// Collect field comparison results from all enabled notes
 		if chkApplied {
@@ -189,7 +204,18 @@ func (app *App) VerifyAll(chkApplied bool) (unsatisfiedNotes []string, compariso
 				continue
 			}
 		}
-		conforming, noteComparisons, _, err := app.VerifyNote(noteID)
+		noteIDs = append(noteIDs, noteID)
+	}
+	// read each system value only once, even if needed by several notes
+	if !system.FactCacheEnabled() {
+		system.EnableFactCache()
+		defer system.DisableFactCache()
+	}
+	initialised := app.initialiseNotes(noteIDs)
+	// the verification is done in note order to get a deterministic
+	// output
+	for _, noteID := range noteIDs {
+		conforming, noteComparisons, _, err := app.verifyNote(noteID, initialised[noteID])
 		if err != nil {
 			return nil, nil, err
 		} else if !conforming {
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -237,6 +238,43 @@ func TestVerifyNoteAndSolutions(t *testing.T) {
 	}
 }
 
+func TestVerifyAllConcurrent(t *testing.T) {
+	os.RemoveAll(SampleNoteDataDir)
+	defer os.RemoveAll(SampleNoteDataDir)
+	allNotes := note.GetTuningOptions(path.Join(OSPackageInGOPATH, "usr/share/saptune/notes"), "")
+	tuneApp := InitialiseApp(path.Join(SampleNoteDataDir, "conf"), path.Join(SampleNoteDataDir, "data"), allNotes, AllTestSolutions)
+	for noteID := range allNotes {
+		tuneApp.NoteApplyOrder = append(tuneApp.NoteApplyOrder, noteID)
+	}
+	sort.Strings(tuneApp.NoteApplyOrder)
+
+	// the notes are initialised concurrently, the result has to be the
+	// same as the one of the sequential verification of each note
+	unsatisfied, comparisons, err := tuneApp.VerifyAll(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comparisons) != len(allNotes) {
+		t.Errorf("expected comparisons of %d notes, got %d", len(allNotes), len(comparisons))
+	}
+	expUnsatisfied := []string{}
+	for _, noteID := range tuneApp.NoteApplyOrder {
+		conforming, noteComparisons, _, err := tuneApp.VerifyNote(noteID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !conforming {
+			expUnsatisfied = append(expUnsatisfied, noteID)
+		}
+		if !reflect.DeepEqual(comparisons[noteID], noteComparisons) {
+			t.Errorf("different comparisons of note '%s'", noteID)
+		}
+	}
+	if !reflect.DeepEqual(unsatisfied, expUnsatisfied) {
+		t.Errorf("expected '%+v', got '%+v'", expUnsatisfied, unsatisfied)
+	}
+}
+
 func TestTuneAll(t *testing.T) {
 	os.RemoveAll(SampleNoteDataDir)
 	defer os.RemoveAll(SampleNoteDataDir)
@@ -5,11 +5,14 @@ import (
 	"github.com/SUSE/saptune/sap"
 	"github.com/SUSE/saptune/sap/note"
 	"github.com/SUSE/saptune/system"
+	"github.com/SUSE/saptune/txtparser"
 	"io"
 	"os"
 	"reflect"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 )
 
 // PrintNoteApplyOrder prints out the order of the currently enabled notes
@@ -172,12 +175,15 @@ and then please double check your input`, id)
 // TuneNote apply tuning for a note.
 // If the note is not yet covered by one of the enabled solutions,
 // the note number will be added into the list of additional notes.
-func (app *App) TuneNote(noteID string) error {
+func (app *App) TuneNote(noteID string) (err error) {
 	savConf := false
 	aNote, err := app.GetNoteByID(noteID)
 	if err != nil {
 		return err
 	}
+	// remember the configuration before the apply for a rollback
+	prevTuneForNotes := append([]string{}, app.TuneForNotes...)
+	prevNoteApplyOrder := append([]string{}, app.NoteApplyOrder...)
 	solNotes := app.GetSortedSolutionEnabledNotes()
 	searchInSol := sort.SearchStrings(solNotes, noteID)
 	searchInNote := sort.SearchStrings(app.TuneForNotes, noteID)
@@ -198,6 +204,34 @@ func (app *App) TuneNote(noteID string) error {
 			return err
 		}
 	}
+	// parameter state files created by this apply
+	startStates := map[string]string{}
+	if app.AtomicApply {
+		// roll back on every failure after the configuration was
+		// changed, not only on a failed apply
+		defer func() {
+			if err != nil {
+				app.rollbackNote(noteID, startStates, prevTuneForNotes, prevNoteApplyOrder)
+			}
+		}()
+	}
+
+	// the system is only read up to the apply, so the facts are
+	// cached and the states of the services are fetched at once
+	cachedRead := !system.FactCacheEnabled()
+	if cachedRead {
+		system.EnableFactCache()
+		defer func() {
+			if cachedRead {
+				system.DisableFactCache()
+			}
+		}()
+	}
+	if iniNote, ok := aNote.(note.INISettings); ok {
+		if ini, err := noteSectionInfo(iniNote); err == nil {
+			system.QueryUnitStates(note.ServiceUnits(ini)...)
+		}
+	}
 
 	// check, if system already complies with the requirements.
 	// set values for later use
@@ -206,8 +240,16 @@ func (app *App) TuneNote(noteID string) error {
 		return err
 	}
 
+	if app.PristineBaseline && reflect.TypeOf(aNote).String() == "note.INISettings" {
+		// capture the persistent pristine baseline of the parameters
+		// together with the start values
+		aNote = aNote.(note.INISettings).SetValuesToApply([]string{"pristine-capture"})
+	}
 	// Save current state for the Note in any case
 	currentState, err := aNote.Initialise()
+	if iniState, ok := currentState.(note.INISettings); ok && iniState.StartStates != nil {
+		startStates = iniState.StartStates
+	}
 	if err != nil {
 		system.ErrorLog("Failed to examine system for the current status of note %s - %v", noteID, err)
 		return err
@@ -224,6 +266,9 @@ func (app *App) TuneNote(noteID string) error {
 		return err
 	}
 	if len(valApplyList) != 0 {
+		if app.AtomicApply {
+			valApplyList = append(valApplyList, "atomic")
+		}
 		optimised = optimised.(note.INISettings).SetValuesToApply(valApplyList)
 	}
 
@@ -232,7 +277,12 @@ func (app *App) TuneNote(noteID string) error {
 		// the requirements.
 		return nil
 	}
-	if err := optimised.Apply(); err != nil {
+	if cachedRead {
+		// the apply changes the system, drop the cached facts
+		system.DisableFactCache()
+		cachedRead = false
+	}
+	if err = optimised.Apply(); err != nil {
 		system.ErrorLog("Failed to apply note %s - %v", noteID, err)
 		return err
 	}
@@ -240,6 +290,29 @@ func (app *App) TuneNote(noteID string) error {
 	return nil
 }
 
+// rollbackNote cleans up the saved states of a note, which failed to apply
+// in atomic mode, and restores the previous configuration.
+// Called for every failure after the configuration was changed.
+// The parameters changed during the apply were already rolled back by the
+// note itself. 'startStates' are the parameter state files created by the
+// apply.
+func (app *App) rollbackNote(noteID string, startStates map[string]string, prevTuneForNotes, prevNoteApplyOrder []string) {
+	system.NoticeLog("rolling back the apply of note '%s'", noteID)
+	note.RemoveNoteFromParameters(noteID, startStates)
+	if err := app.State.Remove(noteID); err != nil {
+		system.ErrorLog("Failed to remove the saved state of note %s - %v", noteID, err)
+	}
+	// remove section saved state file
+	_, _ = txtparser.GetSectionInfo("sns", noteID, true)
+	if !reflect.DeepEqual(app.TuneForNotes, prevTuneForNotes) || !reflect.DeepEqual(app.NoteApplyOrder, prevNoteApplyOrder) {
+		app.TuneForNotes = prevTuneForNotes
+		app.NoteApplyOrder = prevNoteApplyOrder
+		if err := app.SaveConfig(); err != nil {
+			system.ErrorLog("Failed to restore the saptune configuration - %v", err)
+		}
+	}
+}
+
 // RevertNote revert parameters tuned by the note and clear its stored states.
 func (app *App) RevertNote(noteID string, permanent bool) error {
 
@@ -272,7 +345,11 @@ func (app *App) RevertNote(noteID string, permanent bool) error {
 		//var noteRecovered note.Note = noteIface.(note.Note)
 		var noteRecovered = noteIface.(note.Note)
 		if reflect.TypeOf(noteRecovered).String() == "*note.INISettings" {
-			noteRecovered = noteRecovered.(*note.INISettings).SetValuesToApply([]string{"revert"})
+			revertList := []string{"revert"}
+			if app.RevertToPristine {
+				revertList = append(revertList, "pristine-revert")
+			}
+			noteRecovered = noteRecovered.(*note.INISettings).SetValuesToApply(revertList)
 		}
 
 		if err := noteRecovered.Apply(); err != nil {
@@ -286,35 +363,130 @@ func (app *App) RevertNote(noteID string, permanent bool) error {
 	return nil
 }
 
-// VerifyNote inspect the system and verify that all parameters conform
-// to the note's guidelines.
-// The note comparison results will always contain all fields, no matter
-// the note is currently conforming or not.
-func (app *App) VerifyNote(noteID string) (conforming bool, comparisons map[string]note.FieldComparison, valApplyList []string, err error) {
-	theNote, err := app.GetNoteByID(noteID)
+// initialisedNote contains the results of the two independent Initialise
+// calls of a note needed by the verification
+type initialisedNote struct {
+	inspected note.Note
+	optimised note.Note
+	err       error
+}
+
+// initialiseNotes initialises the given notes concurrently for the
+// verification. The system values are read into the per-run fact cache of
+// the system package, so values needed by several notes are read only once.
+// Only done, if the fact cache is enabled. The notes are verified
+// afterwards in the given order to get a deterministic output.
+// noteSectionInfo returns the stored section information of a note or
+// parses the note definition file, if no section information is available
+func noteSectionInfo(iniNote note.INISettings) (*txtparser.INIFile, error) {
+	ini, err := txtparser.GetSectionInfo("sns", iniNote.ID, false)
 	if err != nil {
-		return
+		ini, err = txtparser.ParseINIFile(iniNote.ConfFilePath, false)
 	}
+	return ini, err
+}
+
+func (app *App) initialiseNotes(noteIDs []string) map[string]*initialisedNote {
+	initialised := make(map[string]*initialisedNote)
+	if !system.FactCacheEnabled() {
+		return initialised
+	}
+	units := []string{}
+	rpms := []string{}
+	blockDevs := false
+	iniNotes := []string{}
+	for _, noteID := range noteIDs {
+		iniNote, ok := app.AllNotes[noteID].(note.INISettings)
+		if !ok {
+			continue
+		}
+		ini, err := noteSectionInfo(iniNote)
+		if err != nil {
+			// will be reported during verification of the note
+			continue
+		}
+		units = append(units, note.ServiceUnits(ini)...)
+		rpms = append(rpms, note.RpmPackages(ini)...)
+		if !blockDevs && len(ini.KeyValue[note.INISectionBlock]) != 0 {
+			// the block device information is shared by all
+			// notes and refreshed while reading, so read it
+			// once before the concurrent initialisation
+			_, _ = system.GetBlockDeviceInfo()
+			blockDevs = true
+		}
+		iniNotes = append(iniNotes, noteID)
+	}
+	// the states of the services and the package versions of all
+	// notes are fetched at once
+	system.QueryUnitStates(units...)
+	system.QueryRpmPackages(rpms...)
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	limit := make(chan bool, runtime.NumCPU())
+	for _, noteID := range iniNotes {
+		wg.Add(1)
+		limit <- true
+		go func(noteID string, theNote note.Note) {
+			defer wg.Done()
+			inspected, optimised, err := initialiseForVerify(theNote)
+			mutex.Lock()
+			initialised[noteID] = &initialisedNote{inspected: inspected, optimised: optimised, err: err}
+			mutex.Unlock()
+			<-limit
+		}(noteID, app.AllNotes[noteID])
+	}
+	wg.Wait()
+	return initialised
+}
+
+// initialiseForVerify initialises the note twice, as the current system
+// values and the optimised values are compared by the verification
+func initialiseForVerify(theNote note.Note) (note.Note, note.Note, error) {
 	if reflect.TypeOf(theNote).String() == "note.INISettings" {
 		// workaround to prevent storing of parameter state files
 		// during verify
 		theNote = theNote.(note.INISettings).SetValuesToApply([]string{"verify"})
 	}
-	// Run optimisation routine and compare it against current status
 	inspectedNote, err := theNote.Initialise()
 	if err != nil {
-		return false, nil, nil, err
+		return nil, nil, err
 	}
-
-	// to get Apply work:
+	// if used inspectedNote as before, inspectedNote and optimisedNote
+	// will have the same content after 'Optimise()'
+	// so CompareNoteFields wont find a difference and NO Apply will done
 	optimisedNote, err := theNote.Initialise()
+	if err != nil {
+		return nil, nil, err
+	}
+	return inspectedNote, optimisedNote, nil
+}
+
+// VerifyNote inspect the system and verify that all parameters conform
+// to the note's guidelines.
+// The note comparison results will always contain all fields, no matter
+// the note is currently conforming or not.
+func (app *App) VerifyNote(noteID string) (conforming bool, comparisons map[string]note.FieldComparison, valApplyList []string, err error) {
+	return app.verifyNote(noteID, nil)
+}
+
+// verifyNote verifies the note. 'initialised' contains the already
+// initialised note or is nil, if the note needs to be initialised
+func (app *App) verifyNote(noteID string, initialised *initialisedNote) (conforming bool, comparisons map[string]note.FieldComparison, valApplyList []string, err error) {
+	theNote, err := app.GetNoteByID(noteID)
+	if err != nil {
+		return
+	}
+	var inspectedNote, optimisedNote note.Note
+	if initialised != nil {
+		inspectedNote, optimisedNote, err = initialised.inspected, initialised.optimised, initialised.err
+	} else {
+		inspectedNote, optimisedNote, err = initialiseForVerify(theNote)
+	}
 	if err != nil {
 		return false, nil, nil, err
 	}
-	// if used inspectedNote as before, inspectedNote and optimisedNote
-	// will have the same content after 'Optimise()'
-	// so CompareNoteFields wont find a difference and NO Apply will done
-	//optimisedNote, err := inspectedNote.Optimise()
+	// to get Apply work:
 	optimisedNote, err = optimisedNote.Optimise()
 	if err != nil {
 		return false, nil, nil, err
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"github.com/SUSE/saptune/sap/note"
 	"github.com/SUSE/saptune/system"
 	"os"
 	"path"
@@ -276,3 +277,136 @@ func TestGetSortedAllNotes(t *testing.T) {
 		t.Errorf("got: %+v, expected: %+v\n", allNotes, expNotes)
 	}
 }
+
+func TestTuneNoteAtomicRollback(t *testing.T) {
+	os.RemoveAll(SampleNoteDataDir)
+	defer os.RemoveAll(SampleNoteDataDir)
+	noteDir := path.Join(SampleNoteDataDir, "notes")
+	if err := os.MkdirAll(noteDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	startVal, err := system.GetSysctlString("vm.swappiness")
+	if err != nil {
+		t.Skip("sysctl 'vm.swappiness' not available")
+	}
+	tuneVal := "13"
+	if startVal == tuneVal {
+		tuneVal = "14"
+	}
+	defer func() { _ = system.SetSysctlString("vm.swappiness", startVal) }()
+	// 'kernel.osrelease' is read-only, so the apply fails after
+	// 'vm.swappiness' was already set
+	noteFile := path.Join(noteDir, "4711atomic")
+	WriteFileOrPanic(noteFile, "[sysctl]\nvm.swappiness = "+tuneVal+"\nkernel.osrelease = saptune_atomic_test\n")
+	allNotes := map[string]note.Note{"1001": SampleNote1{}, "4711atomic": note.INISettings{ConfFilePath: noteFile, ID: "4711atomic", DescriptiveName: "atomic rollback test"}}
+	tuneApp := InitialiseApp(path.Join(SampleNoteDataDir, "conf"), path.Join(SampleNoteDataDir, "data"), allNotes, AllTestSolutions)
+	tuneApp.AtomicApply = true
+	defer note.RemoveNoteFromParameters("4711atomic", map[string]string{"vm.swappiness": startVal, "kernel.osrelease": ""})
+
+	if err := tuneApp.TuneNote("4711atomic"); err == nil {
+		t.Fatal("apply of note '4711atomic' should fail")
+	}
+	if val, _ := system.GetSysctlString("vm.swappiness"); val != startVal {
+		t.Errorf("'vm.swappiness' not rolled back, expected '%s', got '%s'", startVal, val)
+	}
+	if !note.IsLastNoteOfParameter("vm.swappiness") || !note.IsLastNoteOfParameter("kernel.osrelease") {
+		t.Error("parameter state files not cleaned up")
+	}
+	if len(tuneApp.TuneForNotes) != 0 || len(tuneApp.NoteApplyOrder) != 0 {
+		t.Errorf("configuration not rolled back: '%+v', '%+v'", tuneApp.TuneForNotes, tuneApp.NoteApplyOrder)
+	}
+	appReloaded := InitialiseApp(tuneApp.SysconfigPrefix, tuneApp.State.StateDirPrefix, allNotes, AllTestSolutions)
+	if len(appReloaded.TuneForNotes) != 0 || len(appReloaded.NoteApplyOrder) != 0 {
+		t.Errorf("stored configuration not rolled back: '%+v', '%+v'", appReloaded.TuneForNotes, appReloaded.NoteApplyOrder)
+	}
+	if _, err := os.Stat(tuneApp.State.GetPathToNote("4711atomic")); !os.IsNotExist(err) {
+		t.Error("saved state of note '4711atomic' not removed")
+	}
+}
+
+func TestTuneNoteAtomicRollbackStates(t *testing.T) {
+	os.RemoveAll(SampleNoteDataDir)
+	defer os.RemoveAll(SampleNoteDataDir)
+	noteDir := path.Join(SampleNoteDataDir, "notes")
+	if err := os.MkdirAll(noteDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	startVal, err := system.GetSysctlString("vm.swappiness")
+	if err != nil {
+		t.Skip("sysctl 'vm.swappiness' not available")
+	}
+	tuneVal := "13"
+	if startVal == tuneVal {
+		tuneVal = "14"
+	}
+	defer func() { _ = system.SetSysctlString("vm.swappiness", startVal) }()
+	// 'vm.swappiness' is already tuned by another applied note and a
+	// start-only state file of a concurrent apply exists, both must
+	// survive the rollback
+	note.CreateParameterStartValues("vm.swappiness", "60")
+	note.AddParameterNoteValues("vm.swappiness", startVal, "4712other", "add")
+	note.CreateParameterStartValues("TEST_FOREIGN_START", "foreign")
+	defer note.CleanUpParamFile("vm.swappiness")
+	defer note.CleanUpParamFile("TEST_FOREIGN_START")
+	// 'kernel.osrelease' is read-only, so the apply fails after
+	// 'vm.swappiness' was already set
+	noteFile := path.Join(noteDir, "4711atomic")
+	WriteFileOrPanic(noteFile, "[sysctl]\nvm.swappiness = "+tuneVal+"\nkernel.osrelease = saptune_atomic_test\n")
+	allNotes := map[string]note.Note{"4711atomic": note.INISettings{ConfFilePath: noteFile, ID: "4711atomic", DescriptiveName: "atomic rollback test"}}
+	tuneApp := InitialiseApp(path.Join(SampleNoteDataDir, "conf"), path.Join(SampleNoteDataDir, "data"), allNotes, AllTestSolutions)
+	tuneApp.AtomicApply = true
+	defer note.CleanUpParamFile("kernel.osrelease")
+
+	if err := tuneApp.TuneNote("4711atomic"); err == nil {
+		t.Fatal("apply of note '4711atomic' should fail")
+	}
+	if val, _ := system.GetSysctlString("vm.swappiness"); val != startVal {
+		t.Errorf("'vm.swappiness' not rolled back, expected '%s', got '%s'", startVal, val)
+	}
+	pEntries := note.GetSavedParameterNotes("vm.swappiness")
+	if len(pEntries.AllNotes) != 2 || pEntries.AllNotes[0].Value != "60" || pEntries.AllNotes[1].NoteID != "4712other" || pEntries.AllNotes[1].Value != startVal {
+		t.Errorf("parameter state file of 'vm.swappiness' not restored: '%+v'", pEntries)
+	}
+	pEntries = note.GetSavedParameterNotes("TEST_FOREIGN_START")
+	if len(pEntries.AllNotes) != 1 || pEntries.AllNotes[0].Value != "foreign" {
+		t.Errorf("start-only parameter state file not created by the apply was removed: '%+v'", pEntries)
+	}
+	if !note.IsLastNoteOfParameter("kernel.osrelease") {
+		t.Error("start-only parameter state file created by the apply not removed")
+	}
+}
+
+func TestTuneNoteFactCache(t *testing.T) {
+	os.RemoveAll(SampleNoteDataDir)
+	defer os.RemoveAll(SampleNoteDataDir)
+	noteDir := path.Join(SampleNoteDataDir, "notes")
+	if err := os.MkdirAll(noteDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	startVal, err := system.GetSysctlString("vm.swappiness")
+	if err != nil {
+		t.Skip("sysctl 'vm.swappiness' not available")
+	}
+	defer note.CleanUpParamFile("vm.swappiness")
+	noteFile := path.Join(noteDir, "4711cache")
+	WriteFileOrPanic(noteFile, "[sysctl]\nvm.swappiness = "+startVal+"\n")
+	allNotes := map[string]note.Note{"4711cache": note.INISettings{ConfFilePath: noteFile, ID: "4711cache", DescriptiveName: "fact cache test"}}
+	tuneApp := InitialiseApp(path.Join(SampleNoteDataDir, "conf"), path.Join(SampleNoteDataDir, "data"), allNotes, AllTestSolutions)
+
+	// the fact cache is only used while the system is read
+	if err := tuneApp.TuneNote("4711cache"); err != nil {
+		t.Fatal(err)
+	}
+	if system.FactCacheEnabled() {
+		t.Error("fact cache still enabled after the apply")
+	}
+	// an already enabled fact cache is left to the caller
+	system.EnableFactCache()
+	defer system.DisableFactCache()
+	if err := tuneApp.TuneNote("4711cache"); err != nil {
+		t.Fatal(err)
+	}
+	if !system.FactCacheEnabled() {
+		t.Error("fact cache of the caller disabled by the apply")
+	}
+}
@@ -70,11 +70,11 @@ func collectChangedParameterInfo(noteID, fileName string, comparisons map[string
 // supported for refresh
 func isSectionSupportedForRefresh(param, section string) bool {
 	switch section {
-	case note.INISectionVersion, note.INISectionRpm, note.INISectionGrub, note.INISectionFS, note.INISectionReminder:
+	case note.INISectionVersion, note.INISectionRpm, note.INISectionGrub, note.INISectionFS, note.INISectionSwap, note.INISectionTimesync, note.INISectionMitigations, note.INISectionReminder:
 		// These parameters are only checked, but not applied.
 		// So nothing to do during refresh
 		return false
-	case note.INISectionCPU, note.INISectionMEM, note.INISectionService, note.INISectionBlock, note.INISectionLimits, note.INISectionLogin, note.INISectionPagecache:
+	case note.INISectionCPU, note.INISectionMEM, note.INISectionService, note.INISectionBlock, note.INISectionLimits, note.INISectionLogin, note.INISectionSystemd, note.INISectionCoredump, note.INISectionPagecache:
 		// currently not supported for 'refresh'
 		system.InfoLog("parameters (%s) from section '%s' currently not supported and not evaluated for 'refresh' operation", param, section)
 		return false
@@ -441,6 +441,7 @@ func adjustParameterFile(noteApplyOrder []string, param map[string]interface{},
 		// note is last note in noteApplyOrder
 		param["isLastNote"] = true
 	}
+	param["pristineCapture"] = app.PristineBaseline
 	savedStateChange := make(map[string]string)
 	needApply := false
 	paramStateValues := note.GetSavedParameterNotes(key)
@@ -543,6 +544,9 @@ func paramFileNotAvailorStart(param map[string]interface{}, comparison note.Fiel
 			// new or changed parameter, create parameter file
 			system.DebugLog("Create parameter file for parameter '$s'.", key)
 			note.CreateParameterStartValues(key, comparison.ActualValue.(string))
+			if pristine, ok := param["pristineCapture"].(bool); ok && pristine {
+				note.CreatePristineValue(key, comparison.ActualValue.(string), param["noteID"].(string))
+			}
 		}
 		if param["isUntouched"].(bool) {
 			return needApply
@@ -880,3 +884,102 @@ func pNoteInsertPosition(noteID string, noteApplyOrder []string, paramStateValue
 	system.DebugLog("pNoteInsertPosition - noteID is '%s', noteApplyOrder is '%+v', paramStateValues is '%+v', index is '%+v'", noteID, noteApplyOrder, paramStateValues, index)
 	return index
 }
+
+// remediableSections contains the note sections, which support the
+// remediation of a parameter changed underneath saptune (drift).
+// Parameters of other sections like [service] or [limits] will never be
+// remediated.
+var remediableSections = []string{note.INISectionSysctl, note.INISectionSys, note.INISectionVM, note.INISectionBlock}
+
+// IsSectionRemediable returns true, if the parameters of the given section
+// can be remediated after a drift
+func IsSectionRemediable(section string) bool {
+	for _, sect := range remediableSections {
+		if sect == section {
+			return true
+		}
+	}
+	return false
+}
+
+// parameterSection returns the section of the parameter 'key' in the stored
+// section information of the applied note 'noteID'
+func parameterSection(noteID, key string) string {
+	sectCont, err := txtparser.GetSectionInfo("rosi", noteID, false)
+	if err != nil {
+		return ""
+	}
+	for _, param := range sectCont.AllValues {
+		if param.Key == key {
+			return param.Section
+		}
+	}
+	return ""
+}
+
+// chkRemediation checks, if the drifted parameter 'key' of the applied note
+// 'noteID' is allowed to be remediated.
+// The section of the parameter needs to be part of the configured sections
+// and the note needs to be the last note in the parameter state file, which
+// set the parameter value. Otherwise the expected value is not the
+// effective value of the tuning.
+func (app *App) chkRemediation(noteID, key string) (string, bool) {
+	section := parameterSection(noteID, key)
+	if section == "" || !IsSectionRemediable(section) {
+		return section, false
+	}
+	configured := false
+	for _, sect := range app.DriftRemediation {
+		if sect == section {
+			configured = true
+			break
+		}
+	}
+	if !configured {
+		return section, false
+	}
+	paramStateValues := note.GetSavedParameterNotes(key)
+	if len(paramStateValues.AllNotes) == 0 || paramStateValues.AllNotes[len(paramStateValues.AllNotes)-1].NoteID != noteID {
+		system.InfoLog("parameter '%s' of note '%s' is not set by this note, skipping remediation", key, noteID)
+		return section, false
+	}
+	return section, true
+}
+
+// remediateParameters restores the expected values of the given parameters
+// of the applied note 'noteID' without a revert/apply cycle of the note.
+// The saved start values and the parameter state files stay untouched.
+// Each parameter is applied on its own, so a parameter, which can not be
+// written (e.g. because of the sandbox of the watch service), does not hide
+// the result of the others. Returns the parameters, which could not be
+// restored.
+func (app *App) remediateParameters(noteID string, keys []string) ([]string, error) {
+	aNote, err := app.GetNoteByID(noteID)
+	if err != nil {
+		return keys, err
+	}
+	iniNote, ok := aNote.(note.INISettings)
+	if !ok {
+		return keys, fmt.Errorf("note '%s' does not support the remediation of parameters", noteID)
+	}
+	// 'verify' prevents storing of parameter state files during
+	// Initialise, so the saved start values are preserved
+	initNote, err := iniNote.SetValuesToApply([]string{"verify"}).Initialise()
+	if err != nil {
+		return keys, err
+	}
+	optNote, err := initNote.Optimise()
+	if err != nil {
+		return keys, err
+	}
+	failed := []string{}
+	for _, key := range keys {
+		if err := optNote.(note.INISettings).SetValuesToApply([]string{key}).Apply(); err != nil {
+			failed = append(failed, key)
+		}
+	}
+	if len(failed) != 0 {
+		err = fmt.Errorf("could not restore the parameters '%s'", strings.Join(failed, ", "))
+	}
+	return failed, err
+}
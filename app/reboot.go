@@ -0,0 +1,37 @@
+package app
+
+import (
+	"github.com/SUSE/saptune/sap/note"
+	"github.com/SUSE/saptune/system"
+	"sort"
+	"strings"
+)
+
+// PendingReboots returns the parameters of the given note comparisons,
+// which are configured, but will only get active after the next reboot
+// of the system, together with the settings registered as 'pending reboot'
+// during apply. Each parameter is listed only once.
+func PendingReboots(comparisons map[string]map[string]note.FieldComparison) []system.PendingReboot {
+	reboots := system.PendingReboots()
+	seen := make(map[string]bool)
+	pending := system.PendingRebootReasons()
+	for _, entry := range reboots {
+		seen[entry.Parameter] = true
+	}
+	for _, noteComparisons := range comparisons {
+		for _, comparison := range noteComparisons {
+			if comparison.ReflectFieldName != "SysctlParams" || comparison.MatchExpectation || seen[comparison.ReflectMapKey] {
+				continue
+			}
+			if !strings.HasPrefix(comparison.ReflectMapKey, "grub:") {
+				continue
+			}
+			if reason := system.PendingRebootReasonOf(pending, comparison.ReflectMapKey, comparison.ExpectedValue.(string)); reason != "" {
+				reboots = append(reboots, system.PendingReboot{Parameter: comparison.ReflectMapKey, Reason: reason})
+				seen[comparison.ReflectMapKey] = true
+			}
+		}
+	}
+	sort.Slice(reboots, func(i, j int) bool { return reboots[i].Parameter < reboots[j].Parameter })
+	return reboots
+}
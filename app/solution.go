@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/SUSE/saptune/sap/note"
 	"github.com/SUSE/saptune/sap/solution"
+	"github.com/SUSE/saptune/system"
 	"sort"
 )
 
@@ -75,6 +76,8 @@ and then please double check your input`, name)
 // If the solution covers any of the additional notes, those notes will be removed.
 func (app *App) TuneSolution(solName string) (removedExplicitNotes []string, err error) {
 	removedExplicitNotes = make([]string, 0)
+	tunedNotes := make([]string, 0)
+	newSol := false
 	sol, err := app.GetSolutionByName(solName)
 	if err != nil {
 		return
@@ -86,10 +89,18 @@ func (app *App) TuneSolution(solName string) (removedExplicitNotes []string, err
 	if i := sort.SearchStrings(app.TuneForSolutions, solName); !(i < len(app.TuneForSolutions) && app.TuneForSolutions[i] == solName) {
 		app.TuneForSolutions = append(app.TuneForSolutions, solName)
 		sort.Strings(app.TuneForSolutions)
+		newSol = true
 		if err = app.SaveConfig(); err != nil {
 			return
 		}
 	}
+	if app.AtomicApply {
+		defer func() {
+			if err != nil {
+				app.rollbackSolution(solName, newSol, tunedNotes, removedExplicitNotes)
+			}
+		}()
+	}
 	for _, noteID := range sol {
 		// Remove solution's notes from additional notes list.
 		if i := sort.SearchStrings(app.TuneForNotes, noteID); i < len(app.TuneForNotes) && app.TuneForNotes[i] == noteID {
@@ -105,10 +116,36 @@ func (app *App) TuneSolution(solName string) (removedExplicitNotes []string, err
 		if err = app.TuneNote(noteID); err != nil {
 			return
 		}
+		tunedNotes = append(tunedNotes, noteID)
 	}
 	return
 }
 
+// rollbackSolution reverts the notes applied during a failed atomic solution
+// apply in reverse order and restores the previous configuration
+func (app *App) rollbackSolution(solName string, newSol bool, tunedNotes, removedExplicitNotes []string) {
+	system.NoticeLog("rolling back the apply of solution '%s'", solName)
+	for i := len(tunedNotes) - 1; i >= 0; i-- {
+		if err := app.RevertNote(tunedNotes[i], true); err != nil {
+			system.ErrorLog("Failed to roll back note %s - %v", tunedNotes[i], err)
+		}
+	}
+	if len(removedExplicitNotes) != 0 {
+		app.TuneForNotes = append(app.TuneForNotes, removedExplicitNotes...)
+		sort.Strings(app.TuneForNotes)
+		if err := app.SaveConfig(); err != nil {
+			system.ErrorLog("Failed to restore the saptune configuration - %v", err)
+		}
+	}
+	if newSol {
+		// remove run time info of the solution
+		_, _ = solution.GetActiveSolNoteInfo(solName, true)
+		if err := app.RemoveSolFromConfig(solName); err != nil {
+			system.ErrorLog("Failed to restore the saptune configuration - %v", err)
+		}
+	}
+}
+
 // RemoveSolFromConfig removes the given solution from the configuration
 func (app *App) RemoveSolFromConfig(solName string) error {
 	i := sort.SearchStrings(app.TuneForSolutions, solName)
@@ -182,6 +219,7 @@ func (app *App) VerifySolution(solName, chkNotes string) (unsatisfiedNotes []str
 	if err != nil {
 		return nil, nil, err
 	}
+	noteIDs := []string{}
 	for _, noteID := range sol {
 		// Collect field comparison results from all enabled notes of
 		// the solution
@@ -201,7 +239,15 @@ func (app *App) VerifySolution(solName, chkNotes string) (unsatisfiedNotes []str
 				continue
 			}
 		}
-		conforming, noteComparisons, _, err := app.VerifyNote(noteID)
+		noteIDs = append(noteIDs, noteID)
+	}
+	if !system.FactCacheEnabled() {
+		system.EnableFactCache()
+		defer system.DisableFactCache()
+	}
+	initialised := app.initialiseNotes(noteIDs)
+	for _, noteID := range noteIDs {
+		conforming, noteComparisons, _, err := app.verifyNote(noteID, initialised[noteID])
 		if err != nil {
 			return nil, nil, err
 		} else if !conforming {
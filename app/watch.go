@@ -0,0 +1,302 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SUSE/saptune/sap/note"
+	"github.com/SUSE/saptune/system"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DriftEntry describes a single parameter of an applied note, which is no
+// longer compliant with the expected value
+type DriftEntry struct {
+	NoteID    string `json:"Note ID"`
+	Parameter string `json:"parameter"`
+	ExpValue  string `json:"expected value"`
+	ActValue  string `json:"actual value"`
+	Since     string `json:"detected since"`
+}
+
+// Remediation describes a drifted parameter, which was restored by saptune.
+// OverwrittenValue is the value set by the external actor, which was
+// overwritten with the expected value of the note
+type Remediation struct {
+	NoteID           string `json:"Note ID"`
+	Section          string `json:"section"`
+	Parameter        string `json:"parameter"`
+	OverwrittenValue string `json:"overwritten value"`
+	RestoredValue    string `json:"restored value"`
+	Remediated       string `json:"remediated at"`
+}
+
+// WatchStatus is the machine-readable result of a drift detection run
+// written to system.SaptuneWatchStatusFile
+type WatchStatus struct {
+	Checked   string        `json:"last check"`
+	Compliant bool          `json:"compliant"`
+	Notes     []string      `json:"Notes applied"`
+	Drifts    []DriftEntry  `json:"non-compliant parameters"`
+	Remedies  []Remediation `json:"remediated parameters,omitempty"`
+}
+
+// driftKey returns the key to identify a drift entry across runs
+func driftKey(entry DriftEntry) string {
+	return entry.NoteID + "#" + entry.Parameter
+}
+
+// CollectDrift verifies all applied notes and returns the current drift
+// status of the system. The field 'Since' of the drift entries is set to
+// the given timestamp.
+func (app *App) CollectDrift(timestamp string) (WatchStatus, error) {
+	status := WatchStatus{Checked: timestamp, Compliant: true, Notes: []string{}, Drifts: []DriftEntry{}}
+	_, comparisons, err := app.VerifyAll(true)
+	if err != nil {
+		return status, err
+	}
+	status.Drifts = collectDriftEntries(comparisons, timestamp)
+	for _, noteID := range app.NoteApplyOrder {
+		if _, ok := comparisons[noteID]; ok {
+			status.Notes = append(status.Notes, noteID)
+		}
+	}
+	status.Compliant = len(status.Drifts) == 0
+	return status, nil
+}
+
+// collectDriftEntries returns the non-compliant parameters of the note
+// comparisons, sorted by note and parameter name
+func collectDriftEntries(comparisons map[string]map[string]note.FieldComparison, timestamp string) []DriftEntry {
+	drifts := []DriftEntry{}
+	for noteID, noteComparisons := range comparisons {
+		for _, comparison := range noteComparisons {
+			if comparison.ReflectFieldName != "SysctlParams" || comparison.MatchExpectation {
+				continue
+			}
+			actVal := fmt.Sprintf("%v", comparison.ActualValue)
+			if !note.AffectsCompliance(comparison.ReflectMapKey, actVal) {
+				continue
+			}
+			drifts = append(drifts, DriftEntry{
+				NoteID:    noteID,
+				Parameter: comparison.ReflectMapKey,
+				ExpValue:  fmt.Sprintf("%v", comparison.ExpectedValue),
+				ActValue:  actVal,
+				Since:     timestamp,
+			})
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].NoteID != drifts[j].NoteID {
+			return drifts[i].NoteID < drifts[j].NoteID
+		}
+		return drifts[i].Parameter < drifts[j].Parameter
+	})
+	return drifts
+}
+
+// CompareDrift compares the current drift status with the previous one.
+// It returns the parameters, which are newly non-compliant and the
+// parameters, which are compliant again.
+// For parameters still non-compliant the detection time of the previous
+// run is preserved in the current status.
+func CompareDrift(prev, cur *WatchStatus) (newDrifts, resolved []DriftEntry) {
+	prevDrifts := make(map[string]DriftEntry)
+	for _, entry := range prev.Drifts {
+		prevDrifts[driftKey(entry)] = entry
+	}
+	curDrifts := make(map[string]bool)
+	for i, entry := range cur.Drifts {
+		curDrifts[driftKey(entry)] = true
+		if old, ok := prevDrifts[driftKey(entry)]; ok && old.ActValue == entry.ActValue {
+			cur.Drifts[i].Since = old.Since
+			continue
+		}
+		newDrifts = append(newDrifts, entry)
+	}
+	for _, entry := range prev.Drifts {
+		if !curDrifts[driftKey(entry)] {
+			resolved = append(resolved, entry)
+		}
+	}
+	return
+}
+
+// ReadWatchStatus reads the result of the previous drift detection run.
+// If no previous result is available an empty status is returned.
+func ReadWatchStatus(fileName string) (WatchStatus, error) {
+	status := WatchStatus{Compliant: true, Notes: []string{}, Drifts: []DriftEntry{}}
+	content, err := os.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return status, nil
+	} else if err != nil {
+		return status, err
+	}
+	err = json.Unmarshal(content, &status)
+	return status, err
+}
+
+// WriteWatchStatus writes the result of the drift detection run to the
+// status file
+func WriteWatchStatus(fileName string, status WatchStatus) error {
+	content, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(fileName), 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first to never expose a partial file
+	// to monitoring agents
+	tmpFile := fileName + ".new"
+	if err := os.WriteFile(tmpFile, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, fileName)
+}
+
+// WatchDrift runs a drift detection of the applied notes, compares the
+// result with the previous run, emits a compliance event to the systemd
+// journal for each newly non-compliant parameter and for each parameter,
+// which is compliant again, and writes the status file.
+func (app *App) WatchDrift(statusFile string) (WatchStatus, error) {
+	prev, err := ReadWatchStatus(statusFile)
+	if err != nil {
+		system.WarningLog("failed to read previous drift status from '%s' - %v. Starting with an empty status.", statusFile, err)
+	}
+	cur, err := app.CollectDrift(time.Now().Format(time.RFC3339))
+	if err != nil {
+		return cur, err
+	}
+	newDrifts, resolved := CompareDrift(&prev, &cur)
+	for _, entry := range newDrifts {
+		emitDriftEvent("drift", entry)
+	}
+	for _, entry := range resolved {
+		emitDriftEvent("compliant", entry)
+	}
+	if len(newDrifts) == 0 && len(resolved) == 0 {
+		system.InfoLog("drift detection: no changes in compliance found (%d non-compliant parameters)", len(cur.Drifts))
+	}
+	if len(app.DriftRemediation) != 0 && len(cur.Drifts) != 0 {
+		app.RemediateDrift(&cur)
+	}
+	return cur, WriteWatchStatus(statusFile, cur)
+}
+
+// RemediateDrift restores the expected values of the drifted parameters of
+// the sections configured in DRIFT_REMEDIATION. Only the drifted parameters
+// are applied again, there is no revert/apply cycle of the note, so the
+// saved start values are preserved.
+// Successfully remediated parameters are moved from the drift list to the
+// list of remedies of the status and reported with the overwritten value.
+func (app *App) RemediateDrift(status *WatchStatus) {
+	remediate := make(map[string][]string)
+	sections := make(map[string]string)
+	noteOrder := []string{}
+	for _, entry := range status.Drifts {
+		section, ok := app.chkRemediation(entry.NoteID, entry.Parameter)
+		if !ok {
+			continue
+		}
+		if _, ok := remediate[entry.NoteID]; !ok {
+			noteOrder = append(noteOrder, entry.NoteID)
+		}
+		remediate[entry.NoteID] = append(remediate[entry.NoteID], entry.Parameter)
+		sections[driftKey(entry)] = section
+	}
+	if len(remediate) == 0 {
+		return
+	}
+	failed := make(map[string]bool)
+	for _, noteID := range noteOrder {
+		keys, err := app.remediateParameters(noteID, remediate[noteID])
+		if err != nil {
+			system.ErrorLog("remediation of the parameters '%s' of Note '%s' failed - %v", strings.Join(keys, ", "), noteID, err)
+			for _, key := range keys {
+				failed[noteID+"#"+key] = true
+			}
+		}
+	}
+	drifts := []DriftEntry{}
+	for _, entry := range status.Drifts {
+		section, ok := sections[driftKey(entry)]
+		if !ok || failed[driftKey(entry)] {
+			drifts = append(drifts, entry)
+			continue
+		}
+		remedy := Remediation{
+			NoteID:           entry.NoteID,
+			Section:          section,
+			Parameter:        entry.Parameter,
+			OverwrittenValue: entry.ActValue,
+			RestoredValue:    entry.ExpValue,
+			Remediated:       status.Checked,
+		}
+		status.Remedies = append(status.Remedies, remedy)
+		emitRemediationEvent(remedy)
+	}
+	status.Drifts = drifts
+	status.Compliant = len(status.Drifts) == 0
+}
+
+// emitRemediationEvent sends a structured remediation event to the systemd
+// journal, which contains the overwritten external value
+func emitRemediationEvent(remedy Remediation) {
+	fields := remediationEventFields(remedy)
+	system.NoticeLog("%s", fields["MESSAGE"])
+	if err := system.JournalSend(fields); err != nil {
+		system.WarningLog("remediation event not sent - %v", err)
+	}
+}
+
+// remediationEventFields returns the journal fields of a remediation event
+func remediationEventFields(remedy Remediation) map[string]string {
+	return map[string]string{
+		"MESSAGE":             fmt.Sprintf("parameter '%s' of Note '%s' remediated - external value '%s' overwritten with '%s'", remedy.Parameter, remedy.NoteID, remedy.OverwrittenValue, remedy.RestoredValue),
+		"MESSAGE_ID":          system.SaptuneDriftMsgID,
+		"PRIORITY":            system.JournalPrioNotice,
+		"SAPTUNE_EVENT":       "remediated",
+		"SAPTUNE_NOTE":        remedy.NoteID,
+		"SAPTUNE_SECTION":     remedy.Section,
+		"SAPTUNE_PARAMETER":   remedy.Parameter,
+		"SAPTUNE_EXPECTED":    remedy.RestoredValue,
+		"SAPTUNE_ACTUAL":      remedy.OverwrittenValue,
+		"SAPTUNE_OVERWRITTEN": remedy.OverwrittenValue,
+	}
+}
+
+// emitDriftEvent sends a structured compliance event to the systemd journal
+// event is 'drift' for a newly non-compliant parameter and 'compliant' for
+// a parameter, which is compliant again
+func emitDriftEvent(event string, entry DriftEntry) {
+	fields := driftEventFields(event, entry)
+	system.InfoLog("%s", fields["MESSAGE"])
+	if err := system.JournalSend(fields); err != nil {
+		system.WarningLog("compliance event not sent - %v", err)
+	}
+}
+
+// driftEventFields returns the journal fields of a compliance event
+func driftEventFields(event string, entry DriftEntry) map[string]string {
+	msg := fmt.Sprintf("parameter '%s' of Note '%s' is no longer compliant - expected '%s', actual '%s'", entry.Parameter, entry.NoteID, entry.ExpValue, entry.ActValue)
+	prio := system.JournalPrioWarning
+	if event == "compliant" {
+		msg = fmt.Sprintf("parameter '%s' of Note '%s' is compliant again", entry.Parameter, entry.NoteID)
+		prio = system.JournalPrioNotice
+	}
+	return map[string]string{
+		"MESSAGE":           msg,
+		"MESSAGE_ID":        system.SaptuneDriftMsgID,
+		"PRIORITY":          prio,
+		"SAPTUNE_EVENT":     event,
+		"SAPTUNE_NOTE":      entry.NoteID,
+		"SAPTUNE_PARAMETER": entry.Parameter,
+		"SAPTUNE_EXPECTED":  entry.ExpValue,
+		"SAPTUNE_ACTUAL":    entry.ActValue,
+	}
+}
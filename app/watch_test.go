@@ -0,0 +1,227 @@
+package app
+
+import (
+	"github.com/SUSE/saptune/sap/note"
+	"github.com/SUSE/saptune/system"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestCollectDriftEntries(t *testing.T) {
+	comparisons := map[string]map[string]note.FieldComparison{
+		"4711": {
+			"SysctlParams[vm.swappiness]":     {ReflectFieldName: "SysctlParams", ReflectMapKey: "vm.swappiness", ActualValue: "60", ExpectedValue: "10", MatchExpectation: false},
+			"SysctlParams[kernel.shmmni]":     {ReflectFieldName: "SysctlParams", ReflectMapKey: "kernel.shmmni", ActualValue: "32768", ExpectedValue: "32768", MatchExpectation: true},
+			"SysctlParams[kernel.sem]":        {ReflectFieldName: "SysctlParams", ReflectMapKey: "kernel.sem", ActualValue: "all:none", ExpectedValue: "1 2 3 4", MatchExpectation: false},
+			"SysctlParams[VSZ_TMPFS_PERCENT]": {ReflectFieldName: "SysctlParams", ReflectMapKey: "VSZ_TMPFS_PERCENT", ActualValue: "50", ExpectedValue: "75", MatchExpectation: false},
+			"ConfFilePath":                    {ReflectFieldName: "ConfFilePath", ActualValue: "a", ExpectedValue: "b", MatchExpectation: false},
+		},
+		"0815": {
+			"SysctlParams[IO_SCHEDULER_sda]": {ReflectFieldName: "SysctlParams", ReflectMapKey: "IO_SCHEDULER_sda", ActualValue: "bfq", ExpectedValue: "none", MatchExpectation: false},
+		},
+	}
+	exp := []DriftEntry{
+		{NoteID: "0815", Parameter: "IO_SCHEDULER_sda", ExpValue: "none", ActValue: "bfq", Since: "now"},
+		{NoteID: "4711", Parameter: "vm.swappiness", ExpValue: "10", ActValue: "60", Since: "now"},
+	}
+	drifts := collectDriftEntries(comparisons, "now")
+	if !reflect.DeepEqual(drifts, exp) {
+		t.Errorf("got: %+v, expected: %+v\n", drifts, exp)
+	}
+}
+
+func TestCompareDrift(t *testing.T) {
+	prev := WatchStatus{Drifts: []DriftEntry{
+		{NoteID: "4711", Parameter: "vm.swappiness", ExpValue: "10", ActValue: "60", Since: "t1"},
+		{NoteID: "4711", Parameter: "kernel.shmmni", ExpValue: "32768", ActValue: "4096", Since: "t1"},
+		{NoteID: "0815", Parameter: "IO_SCHEDULER_sda", ExpValue: "none", ActValue: "bfq", Since: "t1"},
+	}}
+	cur := WatchStatus{Drifts: []DriftEntry{
+		{NoteID: "4711", Parameter: "vm.swappiness", ExpValue: "10", ActValue: "60", Since: "t2"},
+		{NoteID: "4711", Parameter: "kernel.shmmni", ExpValue: "32768", ActValue: "8192", Since: "t2"},
+		{NoteID: "4711", Parameter: "vm.dirty_bytes", ExpValue: "629145600", ActValue: "0", Since: "t2"},
+	}}
+	newDrifts, resolved := CompareDrift(&prev, &cur)
+	expNew := []DriftEntry{
+		{NoteID: "4711", Parameter: "kernel.shmmni", ExpValue: "32768", ActValue: "8192", Since: "t2"},
+		{NoteID: "4711", Parameter: "vm.dirty_bytes", ExpValue: "629145600", ActValue: "0", Since: "t2"},
+	}
+	expResolved := []DriftEntry{
+		{NoteID: "0815", Parameter: "IO_SCHEDULER_sda", ExpValue: "none", ActValue: "bfq", Since: "t1"},
+	}
+	if !reflect.DeepEqual(newDrifts, expNew) {
+		t.Errorf("got: %+v, expected: %+v\n", newDrifts, expNew)
+	}
+	if !reflect.DeepEqual(resolved, expResolved) {
+		t.Errorf("got: %+v, expected: %+v\n", resolved, expResolved)
+	}
+	// unchanged drift keeps the time of the first detection
+	if cur.Drifts[0].Since != "t1" {
+		t.Errorf("got: '%s', expected: 't1'\n", cur.Drifts[0].Since)
+	}
+}
+
+func TestReadWriteWatchStatus(t *testing.T) {
+	statusFile := path.Join(os.TempDir(), "saptune_watch", "status.json")
+	defer os.RemoveAll(path.Dir(statusFile))
+
+	status, err := ReadWatchStatus(statusFile)
+	if err != nil || !status.Compliant || len(status.Drifts) != 0 {
+		t.Errorf("expected empty status for missing file, got: %+v, %v\n", status, err)
+	}
+	status = WatchStatus{Checked: "t1", Compliant: false, Notes: []string{"4711"}, Drifts: []DriftEntry{{NoteID: "4711", Parameter: "vm.swappiness", ExpValue: "10", ActValue: "60", Since: "t1"}}}
+	if err := WriteWatchStatus(statusFile, status); err != nil {
+		t.Error(err)
+	}
+	readStatus, err := ReadWatchStatus(statusFile)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(readStatus, status) {
+		t.Errorf("got: %+v, expected: %+v\n", readStatus, status)
+	}
+}
+
+func TestDriftEventFields(t *testing.T) {
+	entry := DriftEntry{NoteID: "4711", Parameter: "vm.swappiness", ExpValue: "10", ActValue: "60"}
+	fields := driftEventFields("drift", entry)
+	if fields["SAPTUNE_EVENT"] != "drift" || fields["PRIORITY"] != "4" || fields["SAPTUNE_PARAMETER"] != "vm.swappiness" || fields["SAPTUNE_ACTUAL"] != "60" {
+		t.Errorf("unexpected fields: %+v\n", fields)
+	}
+	fields = driftEventFields("compliant", entry)
+	if fields["SAPTUNE_EVENT"] != "compliant" || fields["PRIORITY"] != "5" || fields["MESSAGE"] != "parameter 'vm.swappiness' of Note '4711' is compliant again" {
+		t.Errorf("unexpected fields: %+v\n", fields)
+	}
+}
+
+func TestIsSectionRemediable(t *testing.T) {
+	for _, sect := range []string{"sysctl", "sys", "vm", "block"} {
+		if !IsSectionRemediable(sect) {
+			t.Errorf("section '%s' should be remediable", sect)
+		}
+	}
+	for _, sect := range []string{"service", "limits", "login", "grub", "rpm", ""} {
+		if IsSectionRemediable(sect) {
+			t.Errorf("section '%s' should never be remediable", sect)
+		}
+	}
+}
+
+func TestRemediateDriftNotConfigured(t *testing.T) {
+	tApp := &App{DriftRemediation: []string{"service"}}
+	drifts := []DriftEntry{{NoteID: "not_applied_note", Parameter: "vm.swappiness", ExpValue: "10", ActValue: "60", Since: "t1"}}
+	status := WatchStatus{Checked: "t1", Compliant: false, Drifts: drifts}
+	tApp.RemediateDrift(&status)
+	if !reflect.DeepEqual(status.Drifts, drifts) || len(status.Remedies) != 0 || status.Compliant {
+		t.Errorf("expected unchanged status, got: %+v\n", status)
+	}
+}
+
+func TestRemediationEventFields(t *testing.T) {
+	remedy := Remediation{NoteID: "4711", Section: "vm", Parameter: "THP", OverwrittenValue: "always", RestoredValue: "never", Remediated: "t1"}
+	fields := remediationEventFields(remedy)
+	exp := "parameter 'THP' of Note '4711' remediated - external value 'always' overwritten with 'never'"
+	if fields["MESSAGE"] != exp {
+		t.Errorf("got: '%s', expected: '%s'\n", fields["MESSAGE"], exp)
+	}
+	if fields["SAPTUNE_EVENT"] != "remediated" || fields["SAPTUNE_OVERWRITTEN"] != "always" || fields["SAPTUNE_SECTION"] != "vm" {
+		t.Errorf("unexpected fields: %+v\n", fields)
+	}
+}
+
+// setupRemediation applies a note with the given content and returns the
+// app with enabled remediation of the sysctl section
+func setupRemediation(t *testing.T, noteID, content string) *App {
+	noteDir := path.Join(SampleNoteDataDir, "notes")
+	if err := os.MkdirAll(noteDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	noteFile := path.Join(noteDir, noteID)
+	WriteFileOrPanic(noteFile, content)
+	allNotes := map[string]note.Note{noteID: note.INISettings{ConfFilePath: noteFile, ID: noteID, DescriptiveName: "remediation test"}}
+	tuneApp := InitialiseApp(path.Join(SampleNoteDataDir, "conf"), path.Join(SampleNoteDataDir, "data"), allNotes, AllTestSolutions)
+	tuneApp.DriftRemediation = []string{"sysctl"}
+	if err := tuneApp.TuneNote(noteID); err != nil {
+		t.Fatal(err)
+	}
+	return tuneApp
+}
+
+func TestRemediateParameters(t *testing.T) {
+	os.RemoveAll(SampleNoteDataDir)
+	defer os.RemoveAll(SampleNoteDataDir)
+	startVal, err := system.GetSysctlString("vm.swappiness")
+	if err != nil || os.Getuid() != 0 {
+		t.Skip("sysctl 'vm.swappiness' not available or not writable")
+	}
+	defer func() { _ = system.SetSysctlString("vm.swappiness", startVal) }()
+	tuneVal, driftVal := "13", "14"
+	if startVal == tuneVal || startVal == driftVal {
+		tuneVal, driftVal = "15", "16"
+	}
+	tuneApp := setupRemediation(t, "4711remedy", "[sysctl]\nvm.swappiness = "+tuneVal+"\n")
+	defer func() { _ = tuneApp.RevertNote("4711remedy", true) }()
+
+	// external change of the applied value
+	if err := system.SetSysctlString("vm.swappiness", driftVal); err != nil {
+		t.Fatal(err)
+	}
+	status := WatchStatus{Checked: "now", Drifts: []DriftEntry{{NoteID: "4711remedy", Parameter: "vm.swappiness", ExpValue: tuneVal, ActValue: driftVal, Since: "now"}}}
+	tuneApp.RemediateDrift(&status)
+	if val, _ := system.GetSysctlString("vm.swappiness"); val != tuneVal {
+		t.Errorf("'vm.swappiness' not restored, expected '%s', got '%s'", tuneVal, val)
+	}
+	if len(status.Drifts) != 0 || !status.Compliant || len(status.Remedies) != 1 {
+		t.Fatalf("unexpected status after remediation: '%+v'", status)
+	}
+	if remedy := status.Remedies[0]; remedy.OverwrittenValue != driftVal || remedy.RestoredValue != tuneVal || remedy.Section != "sysctl" {
+		t.Errorf("unexpected remedy: '%+v'", remedy)
+	}
+	// the saved start value is preserved
+	if start := note.GetSavedParameterNotes("vm.swappiness"); len(start.AllNotes) == 0 || start.AllNotes[0].Value != startVal {
+		t.Errorf("saved start value changed: '%+v'", start)
+	}
+}
+
+func TestRemediateParametersWriteFails(t *testing.T) {
+	os.RemoveAll(SampleNoteDataDir)
+	defer os.RemoveAll(SampleNoteDataDir)
+	startVal, err := system.GetSysctlString("vm.swappiness")
+	if err != nil || os.Getuid() != 0 {
+		t.Skip("sysctl 'vm.swappiness' not available or not writable")
+	}
+	defer func() { _ = system.SetSysctlString("vm.swappiness", startVal) }()
+	tuneVal, driftVal := "13", "14"
+	if startVal == tuneVal || startVal == driftVal {
+		tuneVal, driftVal = "15", "16"
+	}
+	// 'kernel.osrelease' is read-only like a key outside of the
+	// writable paths of the watch service sandbox, so the write fails
+	tuneApp := setupRemediation(t, "4711remedyfail", "[sysctl]\nvm.swappiness = "+tuneVal+"\nkernel.osrelease = saptune_remedy_test\n")
+	defer func() { _ = tuneApp.RevertNote("4711remedyfail", true) }()
+	osrelease, _ := system.GetSysctlString("kernel.osrelease")
+
+	if err := system.SetSysctlString("vm.swappiness", driftVal); err != nil {
+		t.Fatal(err)
+	}
+	if failed, err := tuneApp.remediateParameters("4711remedyfail", []string{"kernel.osrelease"}); err == nil || len(failed) != 1 {
+		t.Errorf("expected failed remediation of 'kernel.osrelease', got: '%v', '%v'", failed, err)
+	}
+	status := WatchStatus{Checked: "now", Drifts: []DriftEntry{
+		{NoteID: "4711remedyfail", Parameter: "kernel.osrelease", ExpValue: "saptune_remedy_test", ActValue: osrelease, Since: "now"},
+		{NoteID: "4711remedyfail", Parameter: "vm.swappiness", ExpValue: tuneVal, ActValue: driftVal, Since: "now"},
+	}}
+	tuneApp.RemediateDrift(&status)
+	// the failing parameter stays a drift, the other one is restored
+	if len(status.Drifts) != 1 || status.Drifts[0].Parameter != "kernel.osrelease" || status.Compliant {
+		t.Errorf("unexpected drifts after failed remediation: '%+v'", status)
+	}
+	if len(status.Remedies) != 1 || status.Remedies[0].Parameter != "vm.swappiness" {
+		t.Errorf("unexpected remedies after failed remediation: '%+v'", status.Remedies)
+	}
+	if val, _ := system.GetSysctlString("vm.swappiness"); val != tuneVal {
+		t.Errorf("'vm.swappiness' not restored, expected '%s', got '%s'", tuneVal, val)
+	}
+}
@@ -313,6 +313,12 @@ func checkSaptuneConfigFile(saptuneConf string) string {
 	}
 	// set internal 'excludeDirs' for later use during parsing Notes
 	txtparser.GetSysctlExcludes(sconf.GetString("SKIP_SYSCTL_FILES", ""))
+	// set block devices, which should never be tuned
+	system.SetBlockDeviceExcludes(sconf.GetString("BLOCK_DEVICE_EXCLUDE", ""))
+	// check the effective limits of the running processes during verify
+	system.SetLimitsRuntimeCheck(sconf.GetString("LIMITS_RUNTIME_CHECK", "no"))
+	// keep the applied sysctl values in a sysctl drop-in file
+	system.SetSysctlDropIn(sconf.GetString("SYSCTL_DROPIN", "no"))
 	stageVal := sconf.GetString("STAGING", "")
 	if stageVal != "true" && stageVal != "false" {
 		system.ErrorExit("Variable 'STAGING' from file '%s' contains a wrong value '%s'. Needs to be 'true' or 'false'", saptuneConf, stageVal, 128)
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/SUSE/saptune/actions"
+	"github.com/SUSE/saptune/sap/note"
 	"github.com/SUSE/saptune/system"
 	"io"
 	"os"
+	"os/exec"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -142,12 +145,15 @@ Daemon control:
 Tune system according to SAP and SUSE notes:
   saptune [--format FORMAT] [--force-color] [--fun] note ( list | verify | revertall | enabled | applied )
   saptune [--format FORMAT] [--force-color] [--fun] note ( apply | simulate | customise | create | edit | revert | show | delete ) NOTEID
+  saptune [--format FORMAT] [--force-color] [--fun] note apply [--atomic] NOTEID
+  saptune [--format FORMAT] [--force-color] [--fun] note revert [--to-pristine] NOTEID
   saptune [--format FORMAT] [--force-color] [--fun] note refresh [NOTEID|applied] ATTENTION: experimental
   saptune [--format FORMAT] [--force-color] [--fun] note verify [--colorscheme SCHEME] [--show-non-compliant] [NOTEID|applied]
   saptune [--format FORMAT] [--force-color] [--fun] note rename NOTEID NEWNOTEID
 Tune system for all notes applicable to your SAP solution:
   saptune [--format FORMAT] [--force-color] [--fun] solution ( list | verify | enabled | applied )
   saptune [--format FORMAT] [--force-color] [--fun] solution ( apply | simulate | customise | create | edit | revert | show | delete ) SOLUTIONNAME
+  saptune [--format FORMAT] [--force-color] [--fun] solution apply [--atomic] SOLUTIONNAME
   saptune [--format FORMAT] [--force-color] [--fun] solution change [--force] SOLUTIONNAME
   saptune [--format FORMAT] [--force-color] [--fun] solution verify [--colorscheme SCHEME] [--show-non-compliant] [SOLUTIONNAME]
   saptune [--format FORMAT] [--force-color] [--fun] solution rename SOLUTIONNAME NEWSOLUTIONNAME
@@ -156,14 +162,14 @@ Staging control:
    saptune [--format FORMAT] [--force-color] [--fun] staging ( analysis | diff ) [ ( NOTEID | SOLUTIONNAME.sol )... | all ]
    saptune [--format FORMAT] [--force-color] [--fun] staging release [--force|--dry-run] [ ( NOTEID | SOLUTIONNAME.sol )... | all ]
 Config (re-)settings:
-  saptune [--format FORMAT] [--force-color] [--fun] configure ( COLOR_SCHEME | SKIP_SYSCTL_FILES | IGNORE_RELOAD | DEBUG | TrentoASDP ) Value
+  saptune [--format FORMAT] [--force-color] [--fun] configure ( COLOR_SCHEME | SKIP_SYSCTL_FILES | IGNORE_RELOAD | DEBUG | TrentoASDP | ATOMIC_APPLY | WATCH_INTERVAL | DRIFT_REMEDIATION | PRISTINE_BASELINE | BLOCK_DEVICE_EXCLUDE | LIMITS_RUNTIME_CHECK | SYSCTL_DROPIN ) Value
   saptune [--format FORMAT] [--force-color] [--fun] configure ( reset | show )
 Verify all applied Notes:
   saptune [--format FORMAT] [--force-color] [--fun] verify applied
 Refresh all applied Notes:
   saptune [--format FORMAT] [--force-color] [--fun] refresh applied ATTENTION: experimental
 Revert all parameters tuned by the SAP notes or solutions:
-  saptune [--format FORMAT] [--force-color] [--fun] revert all
+  saptune [--format FORMAT] [--force-color] [--fun] revert all [--to-pristine]
 Remove the pending lock file from a former saptune call
   saptune [--format FORMAT] [--force-color] [--fun] lock remove
 Call external script '/usr/sbin/saptune_check'
@@ -473,3 +479,113 @@ func TestCheckSaptuneServiceDropIn(t *testing.T) {
 	os.Remove("/etc/systemd/system/saptune.service.d")
 	system.TCSP = "skip"
 }
+
+// unitReadWritePaths returns the paths of all 'ReadWritePaths=' lines of
+// the systemd unit file 'unit' without the optional '-' prefix
+func unitReadWritePaths(t *testing.T, unit string) []string {
+	t.Helper()
+	content, err := os.ReadFile(unit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rwPaths := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, "ReadWritePaths=") {
+			continue
+		}
+		for _, rwPath := range strings.Fields(strings.TrimPrefix(line, "ReadWritePaths=")) {
+			rwPaths = append(rwPaths, strings.TrimSuffix(strings.TrimPrefix(rwPath, "-"), "/"))
+		}
+	}
+	return rwPaths
+}
+
+// unitCoversPath returns true, if 'dir' is one of the 'rwPaths' or
+// located below one of them
+func unitCoversPath(rwPaths []string, dir string) bool {
+	for _, rwPath := range rwPaths {
+		if dir == rwPath || strings.HasPrefix(dir, rwPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// unitWriteDirs contains the directories saptune writes to from within the
+// hardened units
+var unitWriteDirs = map[string][]string{
+	"saptune.service":       {"/etc/systemd/system", "/etc/security/limits.d", note.LogindConfDir, system.SystemdConfDir, system.CoredumpConfDir, "/etc/sysctl.d"},
+	"saptune.service_15":    {"/etc/systemd/system", "/etc/security/limits.d", note.LogindConfDir, system.SystemdConfDir, system.CoredumpConfDir, "/etc/sysctl.d"},
+	"saptune-watch.service": {"/run/saptune", "/etc/sysctl.d"},
+}
+
+// unitSandboxProperties returns the sandbox settings of the [Service]
+// section of the systemd unit file 'unit' as 'systemd-run' properties
+func unitSandboxProperties(t *testing.T, unit string) []string {
+	t.Helper()
+	content, err := os.ReadFile(unit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		for _, prefix := range []string{"Protect", "Private", "ReadWritePaths=", "MountAPIVFS=", "RestrictRealtime="} {
+			if strings.HasPrefix(line, prefix) {
+				props = append(props, "-p", line)
+			}
+		}
+	}
+	return props
+}
+
+func TestServiceUnitWritePaths(t *testing.T) {
+	svcDir := path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/ospackage/svc")
+	for unit, dirs := range unitWriteDirs {
+		rwPaths := unitReadWritePaths(t, path.Join(svcDir, unit))
+		for _, dir := range dirs {
+			if !unitCoversPath(rwPaths, dir) {
+				t.Errorf("'%s' is not writable in '%s', ReadWritePaths are '%+v'", dir, unit, rwPaths)
+			}
+		}
+	}
+}
+
+func TestServiceUnitDevices(t *testing.T) {
+	svcDir := path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/ospackage/svc")
+	// the drift detection needs /dev/cpu_dma_latency (force_latency)
+	for _, unit := range []string{"saptune-watch.service"} {
+		for _, prop := range unitSandboxProperties(t, path.Join(svcDir, unit)) {
+			if prop == "PrivateDevices=true" {
+				t.Errorf("'%s' hides the devices needed by the drift detection", unit)
+			}
+		}
+	}
+}
+
+func TestServiceUnitSandbox(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("needs root permissions to start a transient unit. Skip sandbox test")
+	}
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		t.Skip("systemd not running. Skip sandbox test")
+	}
+	svcDir := path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/ospackage/svc")
+	for unit, dirs := range unitWriteDirs {
+		// same as 'ExecStartPre' of the units
+		for _, dir := range dirs {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		script := ""
+		for _, dir := range dirs {
+			tstFile := path.Join(dir, ".saptune_sandbox_test")
+			script = script + fmt.Sprintf("touch %s && rm %s || exit 1; ", tstFile, tstFile)
+		}
+		args := append([]string{"--wait", "--quiet"}, unitSandboxProperties(t, path.Join(svcDir, unit))...)
+		args = append(args, "/bin/sh", "-c", script)
+		if out, err := exec.Command("/usr/bin/systemd-run", args...).CombinedOutput(); err != nil {
+			t.Errorf("writing to '%+v' in the sandbox of '%s' failed - '%v', '%s'", dirs, unit, err, string(out))
+		}
+	}
+}
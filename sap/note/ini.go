@@ -1,6 +1,7 @@
 package note
 
 import (
+	"fmt"
 	"github.com/SUSE/saptune/sap"
 	"github.com/SUSE/saptune/sap/param"
 	"github.com/SUSE/saptune/system"
@@ -9,25 +10,31 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // and section name definition
 const (
-	INISectionSysctl    = "sysctl"
-	INISectionSys       = "sys"
-	INISectionVM        = "vm"
-	INISectionFS        = "filesystem"
-	INISectionCPU       = "cpu"
-	INISectionMEM       = "mem"
-	INISectionBlock     = "block"
-	INISectionService   = "service"
-	INISectionLimits    = "limits"
-	INISectionLogin     = "login"
-	INISectionVersion   = "version"
-	INISectionPagecache = "pagecache"
-	INISectionRpm       = "rpm"
-	INISectionGrub      = "grub"
-	INISectionReminder  = "reminder"
+	INISectionSysctl      = "sysctl"
+	INISectionSys         = "sys"
+	INISectionVM          = "vm"
+	INISectionFS          = "filesystem"
+	INISectionCPU         = "cpu"
+	INISectionMEM         = "mem"
+	INISectionBlock       = "block"
+	INISectionService     = "service"
+	INISectionLimits      = "limits"
+	INISectionLogin       = "login"
+	INISectionVersion     = "version"
+	INISectionPagecache   = "pagecache"
+	INISectionRpm         = "rpm"
+	INISectionGrub        = "grub"
+	INISectionReminder    = "reminder"
+	INISectionSystemd     = "systemd"
+	INISectionCoredump    = "coredump"
+	INISectionSwap        = "swap"
+	INISectionTimesync    = "timesync"
+	INISectionMitigations = "mitigations"
 
 	// LoginConfDir is the path to systemd's logind configuration directory under /etc.
 	LogindConfDir = "/etc/systemd/logind.conf.d"
@@ -35,14 +42,40 @@ const (
 	LogindSAPConfFile = "saptune-UserTasksMax.conf"
 )
 
-var ini *txtparser.INIFile
-var pc = LinuxPagingImprovements{}
-
-var blck = resetToFactoryBlockDevices()
-
 var isLimitSoft = regexp.MustCompile(`LIMIT_.*_soft_memlock`)
 var isLimitHard = regexp.MustCompile(`LIMIT_.*_hard_memlock`)
-var flstates = ""
+
+// noteRunState contains the values read by Initialise, which are needed by
+// Optimise and Apply of the same note
+type noteRunState struct {
+	pc       LinuxPagingImprovements
+	blck     param.BlockDeviceQueue
+	flstates string
+}
+
+// newNoteRunState returns an empty run state
+func newNoteRunState() *noteRunState {
+	return &noteRunState{blck: resetToFactoryBlockDevices()}
+}
+
+// vendRunStates contains the run states of the 'vend' data stored by
+// Optimise during 'verify', needed by the Apply of 'refresh'
+var vendRunStates = map[string]*noteRunState{}
+var vendRunStatesMutex sync.Mutex
+
+// storeVendRunState remembers the run state of the stored 'vend' data
+func storeVendRunState(noteID string, run *noteRunState) {
+	vendRunStatesMutex.Lock()
+	defer vendRunStatesMutex.Unlock()
+	vendRunStates[noteID] = run
+}
+
+// vendRunState returns the run state of the stored 'vend' data or nil
+func vendRunState(noteID string) *noteRunState {
+	vendRunStatesMutex.Lock()
+	defer vendRunStatesMutex.Unlock()
+	return vendRunStates[noteID]
+}
 
 // Tuning options composed by a third party vendor.
 
@@ -55,6 +88,8 @@ type INISettings struct {
 	ValuesToApply   map[string]string // values to apply
 	OverrideParams  map[string]string // parameter values from the override file
 	Inform          map[string]string // special information for parameter values
+	StartStates     map[string]string `json:"-"` // parameter state files created by Initialise, with the start values
+	run             *noteRunState     // values read during Initialise, needed by Optimise and Apply
 }
 
 // Initialise a BlockDeviceQueue
@@ -64,6 +99,7 @@ func resetToFactoryBlockDevices() param.BlockDeviceQueue {
 		BlockDeviceNrRequests:   param.BlockDeviceNrRequests{NrRequests: make(map[string]int)},
 		BlockDeviceReadAheadKB:  param.BlockDeviceReadAheadKB{ReadAheadKB: make(map[string]int)},
 		BlockDeviceMaxSectorsKB: param.BlockDeviceMaxSectorsKB{MaxSectorsKB: make(map[string]int)},
+		BlockDeviceTunables:     param.BlockDeviceTunables{Tunables: make(map[string]map[string]string)},
 	}
 }
 
@@ -99,8 +135,8 @@ func (vend INISettings) Initialise() (Note, error) {
 	vend.SysctlParams = make(map[string]string)
 	vend.OverrideParams = make(map[string]string)
 	vend.Inform = make(map[string]string)
-	pc = LinuxPagingImprovements{}
-	blck = resetToFactoryBlockDevices()
+	vend.StartStates = make(map[string]string)
+	vend.run = newNoteRunState()
 	for _, param := range ini.AllValues {
 		if override && len(ow.KeyValue[param.Section]) != 0 {
 			param.Key, param.Value, param.Operator = vend.handleInitOverride(param.Key, param.Value, param.Section, param.Operator, ow)
@@ -118,23 +154,40 @@ func (vend INISettings) Initialise() (Note, error) {
 			vend.SysctlParams[param.Key], vend.Inform[param.Key] = GetFSVal(param.Key, param.Value)
 			continue
 		case INISectionBlock:
-			vend.SysctlParams[param.Key], vend.Inform[param.Key], _ = GetBlkVal(param.Key, &blck)
+			vend.SysctlParams[param.Key], vend.Inform[param.Key], _ = GetBlkVal(param.Key, &vend.run.blck)
 		case INISectionLimits:
 			vend.SysctlParams[param.Key], vend.Inform[param.Key], _ = GetLimitsVal(param.Value)
 		case INISectionService:
 			vend.SysctlParams[param.Key] = GetServiceVal(param.Key)
 		case INISectionLogin:
 			vend.SysctlParams[param.Key], _ = GetLoginVal(param.Key)
+		case INISectionSystemd:
+			vend.SysctlParams[param.Key] = GetSystemdVal(param.Key)
+		case INISectionCoredump:
+			vend.SysctlParams[param.Key], vend.Inform[param.Key] = GetCoredumpVal(param.Key, param.Value, ini.KeyValue[INISectionCoredump]["core_limit"].Value)
+			if param.Key == "full_core_size" {
+				// only checked, but not applied
+				continue
+			}
 		case INISectionMEM:
 			vend.SysctlParams[param.Key] = GetMemVal(param.Key)
 		case INISectionCPU:
-			vend.SysctlParams[param.Key], flstates, vend.Inform[param.Key] = GetCPUVal(param.Key)
+			vend.SysctlParams[param.Key], vend.run.flstates, vend.Inform[param.Key] = GetCPUVal(param.Key)
 		case INISectionRpm:
 			vend.SysctlParams[param.Key] = GetRpmVal(param.Key)
 			continue
 		case INISectionGrub:
 			vend.SysctlParams[param.Key] = GetGrubVal(param.Key)
 			continue
+		case INISectionSwap:
+			vend.SysctlParams[param.Key] = GetSwapVal(param.Key)
+			continue
+		case INISectionTimesync:
+			vend.SysctlParams[param.Key] = GetTimesyncVal(param.Key)
+			continue
+		case INISectionMitigations:
+			vend.SysctlParams[param.Key] = GetMitigationsVal(param.Key)
+			continue
 		case INISectionReminder:
 			vend.SysctlParams[param.Key] = param.Value
 			continue
@@ -144,17 +197,26 @@ func (vend INISettings) Initialise() (Note, error) {
 			// page cache is special, has it's own config file
 			// so adjust path to pagecache config file, if needed
 			if override {
-				pc.PagingConfig = path.Join(txtparser.OverrideTuningSheets, vend.ID)
+				vend.run.pc.PagingConfig = path.Join(txtparser.OverrideTuningSheets, vend.ID)
 			} else {
-				pc.PagingConfig = vend.ConfFilePath
+				vend.run.pc.PagingConfig = vend.ConfFilePath
 			}
-			vend.SysctlParams[param.Key] = GetPagecacheVal(param.Key, &pc)
+			vend.SysctlParams[param.Key] = GetPagecacheVal(param.Key, &vend.run.pc)
 		default:
 			system.WarningLog("3rdPartyTuningOption %s: skip unknown section %s", vend.ConfFilePath, param.Section)
 			continue
 		}
 		// create parameter saved state file, if NOT in 'verify'
-		vend.createParamSavedStates(param.Key, flstates)
+		vend.createParamSavedStates(param.Key, vend.run.flstates)
+	}
+	if expected, ok := ini.KeyValue[INISectionMitigations]; ok {
+		// report the CPU vulnerabilities known by the kernel, but
+		// without expectation in the Note. The current state is
+		// used as expected value
+		for _, key := range unexpectedVulnerabilities(expected) {
+			vend.SysctlParams[key] = GetMitigationsVal(key)
+			vend.Inform[key] = "unexpected"
+		}
 	}
 	return vend, nil
 }
@@ -164,6 +226,9 @@ func (vend INISettings) Optimise() (Note, error) {
 	blckOK := make(map[string][]string)
 	scheds := ""
 	next := false
+	if vend.run == nil {
+		vend.run = newNoteRunState()
+	}
 
 	// read saved section data == config data from configuration file
 	ini, err := txtparser.GetSectionInfo("sns", vend.ID, false)
@@ -202,22 +267,34 @@ func (vend INISettings) Optimise() (Note, error) {
 			vend.SysctlParams[param.Key] = OptSysVal(param.Operator, param.Key, vend.SysctlParams[param.Key], param.Value)
 		case INISectionVM:
 			vend.Inform[param.Key] = vend.chkDoubles(param.Key, vend.Inform[param.Key])
+			vend.Inform[param.Key] = chkVMVal(param.Key, param.Value, vend.Inform[param.Key])
 			vend.SysctlParams[param.Key] = OptVMVal(param.Key, param.Value)
 		case INISectionFS:
 			vend.SysctlParams[param.Key] = OptFSVal(param.Key, param.Value)
 			continue
 		case INISectionBlock:
-			vend.SysctlParams[param.Key], vend.Inform[param.Key] = OptBlkVal(param.Key, param.Value, &blck, blckOK)
+			vend.SysctlParams[param.Key], vend.Inform[param.Key] = OptBlkVal(param.Key, param.Value, &vend.run.blck, blckOK)
 			vend.Inform[param.Key] = vend.chkDoubles(param.Key, vend.Inform[param.Key])
+			vend.Inform[param.Key] = chkBlkLayers(param.Key, vend.Inform[param.Key])
 			if system.IsSched.MatchString(param.Key) {
 				scheds = param.Value
 			}
 		case INISectionLimits:
 			vend.SysctlParams[param.Key] = OptLimitsVal(vend.SysctlParams[param.Key], param.Value)
+			vend.Inform[param.Key] = chkLimitsManager(vend.SysctlParams[param.Key], vend.Inform[param.Key])
+			vend.Inform[param.Key] = chkLimitsRuntime(vend.SysctlParams[param.Key], vend.Inform[param.Key])
 		case INISectionService:
 			vend.SysctlParams[param.Key] = OptServiceVal(param.Key, param.Value)
 		case INISectionLogin:
-			vend.SysctlParams[param.Key] = OptLoginVal(param.Value)
+			vend.SysctlParams[param.Key] = chkLoginVal(param.Key, OptLoginVal(param.Value))
+		case INISectionSystemd:
+			vend.SysctlParams[param.Key] = OptSystemdVal(param.Key, param.Value)
+		case INISectionCoredump:
+			vend.SysctlParams[param.Key] = OptCoredumpVal(param.Key, param.Value)
+			if param.Key == "full_core_size" {
+				// only checked, but not applied
+				continue
+			}
 		case INISectionMEM:
 			if vend.OverrideParams["VSZ_TMPFS_PERCENT"] == "untouched" || vend.OverrideParams["VSZ_TMPFS_PERCENT"] == "" {
 				vend.SysctlParams[param.Key] = OptMemVal(param.Key, vend.SysctlParams[param.Key], param.Value, ini.KeyValue["mem"]["VSZ_TMPFS_PERCENT"].Value)
@@ -232,13 +309,22 @@ func (vend INISettings) Optimise() (Note, error) {
 		case INISectionGrub:
 			vend.SysctlParams[param.Key] = OptGrubVal(param.Key, param.Value)
 			continue
+		case INISectionSwap:
+			vend.SysctlParams[param.Key] = OptSwapVal(param.Operator, param.Key, vend.SysctlParams[param.Key], param.Value)
+			continue
+		case INISectionTimesync:
+			vend.SysctlParams[param.Key] = OptTimesyncVal(param.Key, vend.SysctlParams[param.Key], param.Value)
+			continue
+		case INISectionMitigations:
+			vend.SysctlParams[param.Key] = OptMitigationsVal(param.Key, vend.SysctlParams[param.Key], param.Value)
+			continue
 		case INISectionReminder:
 			vend.SysctlParams[param.Key] = param.Value
 			continue
 		case INISectionVersion:
 			continue
 		case INISectionPagecache:
-			vend.SysctlParams[param.Key] = OptPagecacheVal(param.Key, param.Value, &pc)
+			vend.SysctlParams[param.Key] = OptPagecacheVal(param.Key, param.Value, &vend.run.pc)
 		default:
 			system.WarningLog("3rdPartyTuningOption %s: skip unknown section %s", vend.ConfFilePath, param.Section)
 			continue
@@ -261,6 +347,7 @@ func (vend INISettings) Optimise() (Note, error) {
 		// write vend data to runtime file, if in 'verify' to support
 		// 'refresh'
 		err = txtparser.StoreSectionInfo(vend, "vend", vend.ID, true)
+		storeVendRunState(vend.ID, vend.run)
 	}
 	if err != nil {
 		system.ErrorLog("Problems during storing of section information")
@@ -269,13 +356,32 @@ func (vend INISettings) Optimise() (Note, error) {
 	return vend, nil
 }
 
+// ApplyError describes the parameter, which caused an atomic apply to fail
+type ApplyError struct {
+	Section string
+	Key     string
+	Value   string
+	Err     error
+}
+
+// Error returns the error message of an atomic apply failure
+func (aerr *ApplyError) Error() string {
+	return fmt.Sprintf("setting parameter '%s' of section [%s] to '%s' failed - %v", aerr.Key, aerr.Section, aerr.Value, aerr.Err)
+}
+
 // Apply sets the new parameter values in the system or
 // revert the system to the former parameter values
 func (vend INISettings) Apply() error {
 	var err error
 	errs := make([]error, 0)
 	revertValues := false
+	atomic := false
 	pvendID := vend.ID
+	applied := make([]txtparser.INIEntry, 0)
+	if vend.run == nil {
+		// e.g. revert of a note read from the saved state file
+		vend.run = newNoteRunState()
+	}
 
 	if len(vend.ValuesToApply) == 0 {
 		// nothing to apply
@@ -284,8 +390,13 @@ func (vend INISettings) Apply() error {
 	if _, ok := vend.ValuesToApply["revert"]; ok {
 		revertValues = true
 	}
+	if _, ok := vend.ValuesToApply["atomic"]; ok && !revertValues {
+		// all-or-nothing, stop at the first failing parameter and
+		// roll back the parameters already changed by this apply
+		atomic = true
+	}
 
-	ini, err = txtparser.GetSectionInfo("sns", vend.ID, revertValues)
+	ini, err := txtparser.GetSectionInfo("sns", vend.ID, revertValues)
 	if err != nil {
 		// fallback, reading info from config file
 		ini, err = txtparser.ParseINIFile(vend.ConfFilePath, false)
@@ -305,10 +416,15 @@ func (vend INISettings) Apply() error {
 		// handle note 1805750
 		param.Key, param.Value = vend.handleID1805750(param.Key, param.Value)
 		switch param.Section {
-		case INISectionVersion, INISectionRpm, INISectionGrub, INISectionFS, INISectionReminder:
+		case INISectionVersion, INISectionRpm, INISectionGrub, INISectionFS, INISectionSwap, INISectionTimesync, INISectionMitigations, INISectionReminder:
 			// These parameters are only checked, but not applied.
 			// So nothing to do during apply and no need for revert
 			continue
+		case INISectionCoredump:
+			if param.Key == "full_core_size" {
+				// only checked, but not applied
+				continue
+			}
 		}
 
 		if _, ok := vend.ValuesToApply[param.Key]; !ok && !revertValues {
@@ -317,55 +433,102 @@ func (vend INISettings) Apply() error {
 
 		if revertValues && vend.SysctlParams[param.Key] != "PNA" {
 			// revert parameter value
-			pvendID, flstates = vend.setRevertParamValues(param.Key)
+			pvendID, vend.run.flstates = vend.setRevertParamValues(param.Key)
 		}
 
-		switch param.Section {
-		case INISectionSysctl:
-			// Apply sysctl parameters
-			// for the vm.dirty parameters take the counterpart
-			// parameters into account (only during revert)
-			// if vm.dirty_background_bytes is set to a value != 0,
-			// vm.dirty_background_ratio is set to 0 and vice versa
-			// if vm.dirty_bytes is set to a value != 0,
-			// vm.dirty_ratio is set to 0 and vice versa
-			key, val := vend.getCounterPart(param.Key, revertValues)
-			errs = append(errs, system.SetSysctlString(key, val))
-		case INISectionSys:
-			errs = append(errs, SetSysVal(param.Key, vend.SysctlParams[param.Key]))
-		case INISectionVM:
-			errs = append(errs, SetVMVal(param.Key, vend.SysctlParams[param.Key]))
-		case INISectionBlock:
-			errs = append(errs, SetBlkVal(param.Key, vend.SysctlParams[param.Key], &blck, revertValues))
-		case INISectionLimits:
-			errs = append(errs, SetLimitsVal(param.Key, pvendID, vend.SysctlParams[param.Key], revertValues))
-		case INISectionService:
-			errs = append(errs, SetServiceVal(param.Key, vend.SysctlParams[param.Key]))
-		case INISectionLogin:
-			errs = append(errs, SetLoginVal(param.Key, vend.SysctlParams[param.Key], revertValues))
-		case INISectionMEM:
-			errs = append(errs, SetMemVal(param.Key, vend.SysctlParams[param.Key]))
-		case INISectionCPU:
-			errs = append(errs, SetCPUVal(param.Key, vend.SysctlParams[param.Key], vend.ID, flstates, vend.OverrideParams[param.Key], revertValues))
-		case INISectionPagecache:
-			if revertValues {
-				switch param.Key {
-				case system.SysctlPagecacheLimitIgnoreDirty:
-					pc.VMPagecacheLimitIgnoreDirty, _ = strconv.Atoi(vend.SysctlParams[param.Key])
-				case "OVERRIDE_PAGECACHE_LIMIT_MB":
-					pc.VMPagecacheLimitMB, _ = strconv.ParseUint(vend.SysctlParams[param.Key], 10, 64)
-				}
+		perr := vend.setParamValue(param.Section, param.Key, pvendID, revertValues)
+		if atomic {
+			if perr != nil {
+				aerr := &ApplyError{Section: param.Section, Key: param.Key, Value: vend.SysctlParams[param.Key], Err: perr}
+				system.ErrorLog("%v", aerr)
+				// the failing parameter may be partly set
+				vend.rollbackParams(append(applied, param))
+				return aerr
 			}
-			errs = append(errs, SetPagecacheVal(param.Key, &pc))
-		default:
-			system.WarningLog("3rdPartyTuningOption %s: skip unknown section %s", vend.ConfFilePath, param.Section)
-			continue
+			applied = append(applied, param)
 		}
+		errs = append(errs, perr)
 	}
 	err = sap.PrintErrors(errs)
 	return err
 }
 
+// setParamValue sets the value of a single parameter of the given section
+func (vend INISettings) setParamValue(section, key, pvendID string, revertValues bool) error {
+	var err error
+	switch section {
+	case INISectionSysctl:
+		// Apply sysctl parameters
+		// for the vm.dirty parameters take the counterpart
+		// parameters into account (only during revert)
+		// if vm.dirty_background_bytes is set to a value != 0,
+		// vm.dirty_background_ratio is set to 0 and vice versa
+		// if vm.dirty_bytes is set to a value != 0,
+		// vm.dirty_ratio is set to 0 and vice versa
+		ckey, val := vend.getCounterPart(key, revertValues)
+		err = system.SetSysctlString(ckey, val)
+		if err == nil {
+			err = syncSysctlDropIn(key, ckey, val)
+		}
+	case INISectionSys:
+		err = SetSysVal(key, vend.SysctlParams[key])
+	case INISectionVM:
+		err = SetVMVal(key, vend.SysctlParams[key])
+	case INISectionBlock:
+		err = SetBlkVal(key, vend.SysctlParams[key], &vend.run.blck, revertValues)
+	case INISectionLimits:
+		err = SetLimitsVal(key, pvendID, vend.SysctlParams[key], revertValues)
+	case INISectionService:
+		err = SetServiceVal(key, vend.SysctlParams[key])
+	case INISectionLogin:
+		err = SetLoginVal(key, vend.SysctlParams[key], revertValues)
+	case INISectionSystemd:
+		err = SetSystemdVal(key, vend.SysctlParams[key], revertValues)
+	case INISectionCoredump:
+		err = SetCoredumpVal(key, pvendID, vend.SysctlParams[key], revertValues)
+	case INISectionMEM:
+		err = SetMemVal(key, vend.SysctlParams[key])
+	case INISectionCPU:
+		if key == "scaling_min_freq" || key == "scaling_max_freq" {
+			// write both frequency limits together in the order
+			// accepted by the kernel
+			err = system.SetCPUFreqLimits(vend.cpuFreqLimits(revertValues))
+			break
+		}
+		err = SetCPUVal(key, vend.SysctlParams[key], vend.ID, vend.run.flstates, vend.OverrideParams[key], revertValues)
+	case INISectionPagecache:
+		if revertValues {
+			switch key {
+			case system.SysctlPagecacheLimitIgnoreDirty:
+				vend.run.pc.VMPagecacheLimitIgnoreDirty, _ = strconv.Atoi(vend.SysctlParams[key])
+			case "OVERRIDE_PAGECACHE_LIMIT_MB":
+				vend.run.pc.VMPagecacheLimitMB, _ = strconv.ParseUint(vend.SysctlParams[key], 10, 64)
+			}
+		}
+		err = SetPagecacheVal(key, &vend.run.pc)
+	default:
+		system.WarningLog("3rdPartyTuningOption %s: skip unknown section %s", vend.ConfFilePath, section)
+	}
+	return err
+}
+
+// rollbackParams reverts the given, already applied parameters in reverse
+// order to the values they had before the note was applied.
+// The values are taken from the parameter state files, which also removes
+// the reference of the note from these files.
+func (vend INISettings) rollbackParams(applied []txtparser.INIEntry) {
+	for i := len(applied) - 1; i >= 0; i-- {
+		param := applied[i]
+		pvendID, fls := vend.setRevertParamValues(param.Key)
+		vend.run.flstates = fls
+		if err := vend.setParamValue(param.Section, param.Key, pvendID, true); err != nil {
+			system.ErrorLog("rollback of parameter '%s' of section [%s] failed - %v", param.Key, param.Section, err)
+			continue
+		}
+		system.NoticeLog("parameter '%s' of section [%s] rolled back to '%s'", param.Key, param.Section, vend.SysctlParams[param.Key])
+	}
+}
+
 // SetValuesToApply fills the data structure for applying the changes
 func (vend INISettings) SetValuesToApply(values []string) Note {
 	vend.ValuesToApply = make(map[string]string)
@@ -375,6 +538,23 @@ func (vend INISettings) SetValuesToApply(values []string) Note {
 	return vend
 }
 
+// cpuFreqLimits returns the values of 'scaling_min_freq' and
+// 'scaling_max_freq', which are part of the current apply or revert.
+// An empty value leaves the limit untouched
+func (vend INISettings) cpuFreqLimits(revert bool) (string, string) {
+	limits := []string{"", ""}
+	for i, key := range []string{"scaling_min_freq", "scaling_max_freq"} {
+		val, ok := vend.SysctlParams[key]
+		if !ok || val == "PNA" {
+			continue
+		}
+		if _, apply := vend.ValuesToApply[key]; apply || revert {
+			limits[i] = val
+		}
+	}
+	return limits[0], limits[1]
+}
+
 // getCounterPart gets the counterpart parameters of the vm.dirty parameters
 func (vend INISettings) getCounterPart(key string, revert bool) (string, string) {
 	// for the vm.dirty parameters take the counterpart
@@ -410,6 +590,14 @@ func (vend INISettings) setRevertParamValues(key string) (string, string) {
 	// revert parameter value
 	flstates := ""
 	pvalue, pvendID := RevertParameter(key, vend.ID)
+	if _, ok := vend.ValuesToApply["pristine-revert"]; ok && pvendID == "start" {
+		// no other note is changing the parameter any longer, so
+		// revert to the persistent pristine baseline instead of the
+		// start value of the current session, if available and valid
+		if pristine, ok := PristineRevertValue(key); ok {
+			pvalue = pristine
+		}
+	}
 	if pvendID == "" {
 		pvendID = vend.ID
 	}
@@ -418,11 +606,26 @@ func (vend INISettings) setRevertParamValues(key string) (string, string) {
 		vend.SysctlParams[key] = pvalue
 	}
 	if key == "force_latency" {
-		flstates, _ = RevertParameter("fl_states", vend.ID)
+		fnoteID := ""
+		flstates, fnoteID = RevertParameter("fl_states", vend.ID)
+		if _, ok := vend.ValuesToApply["pristine-revert"]; ok && fnoteID == "start" {
+			if pristine, ok := PristineRevertValue("fl_states"); ok {
+				flstates = pristine
+			}
+		}
 	}
 	return pvendID, flstates
 }
 
+// trackStartState creates the parameter saved state file with the start
+// value and remembers the created file, so that a failed atomic apply only
+// removes the files created by itself
+func (vend INISettings) trackStartState(key, start string) {
+	if CreateParameterStartValues(key, start) && vend.StartStates != nil {
+		vend.StartStates[key] = start
+	}
+}
+
 // createParamSavedStates creates the parameter saved state file
 func (vend INISettings) createParamSavedStates(key, flstates string) {
 	// Do not write parameter values to the saved state file during
@@ -432,6 +635,11 @@ func (vend INISettings) createParamSavedStates(key, flstates string) {
 	// the parameter file in these cases
 	if _, ok := vend.ValuesToApply["verify"]; !ok && vend.SysctlParams[key] != "PNA" {
 		start := vend.SysctlParams[key]
+		if dropInStart, ok := system.ValidSysctlDropInStartValue(key, start); ok && IsLastNoteOfParameter(key) {
+			// the saptune sysctl drop-in file survived a reboot,
+			// systemd-sysctl has already set the tuned value
+			start = dropInStart
+		}
 		if key == "UserTasksMax" {
 			if system.SystemctlIsStarting() {
 				start = system.GetBackupValue("/var/lib/saptune/working/.tmbackup")
@@ -440,9 +648,20 @@ func (vend INISettings) createParamSavedStates(key, flstates string) {
 				system.WriteBackupValue(start, "/var/lib/saptune/working/.tmbackup")
 			}
 		}
-		CreateParameterStartValues(key, start)
+		vend.trackStartState(key, start)
 		if key == "force_latency" {
-			CreateParameterStartValues("fl_states", flstates)
+			vend.trackStartState("fl_states", flstates)
+		}
+		if _, ok := vend.ValuesToApply["pristine-capture"]; ok {
+			// capture the persistent pristine baseline once
+			// If the parameter is already tuned by another applied
+			// Note (e.g. PRISTINE_BASELINE enabled later), the
+			// start value of the parameter state file is the value
+			// before the tuning
+			CreatePristineValue(key, pristineStartValue(key, start), vend.ID)
+			if key == "force_latency" {
+				CreatePristineValue("fl_states", pristineStartValue("fl_states", flstates), vend.ID)
+			}
 		}
 	}
 }
@@ -462,3 +462,11 @@ func TestPageCacheSettings(t *testing.T) {
 	}
 	cleanUp()
 }
+
+func TestApplyError(t *testing.T) {
+	aerr := &ApplyError{Section: "sysctl", Key: "vm.dirty_bytes", Value: "4711", Err: fmt.Errorf("permission denied")}
+	exp := "setting parameter 'vm.dirty_bytes' of section [sysctl] to '4711' failed - permission denied"
+	if aerr.Error() != exp {
+		t.Errorf("got: '%s', expected: '%s'\n", aerr.Error(), exp)
+	}
+}
@@ -195,6 +195,10 @@ func CompareNoteFields(actualNote, expectedNote Note) (allMatch bool, comparison
 	for i := 0; i < refActualNote.NumField(); i++ {
 		// Retrieve actualField value from actual and expected note
 		fieldName := reflect.TypeOf(actualNote).Field(i).Name
+		if !reflect.TypeOf(actualNote).Field(i).IsExported() {
+			// internal run state of the note, nothing to compare
+			continue
+		}
 		// Compare map value or actualField value
 		if refActualNote.Field(i).Type().Kind() == reflect.Map {
 			// Compare map values
@@ -230,7 +234,7 @@ func CompareNoteFields(actualNote, expectedNote Note) (allMatch bool, comparison
 					// if this should change in the future use
 					// !strings.Contains(key.String(), "grub")
 					// instead of !system.IsInternalGrub(key.String())
-					if actualValue.(string) != "all:none" && !system.IsInternalGrub(key.String()) && !(system.IsXFSOption.MatchString(key.String()) && actualValue.(string) == "NA") && actualValue.(string) != "PNA" && key.String() != "VSZ_TMPFS_PERCENT" {
+					if AffectsCompliance(key.String(), actualValue.(string)) {
 						allMatch = false
 					}
 				}
@@ -250,6 +254,14 @@ func CompareNoteFields(actualNote, expectedNote Note) (allMatch bool, comparison
 	return
 }
 
+// AffectsCompliance returns true, if a non matching parameter value should
+// influence the compliance result of a note.
+// Not supported parameters ("all:none", "PNA", "NA" for XFS options),
+// saptune internal grub parameters and VSZ_TMPFS_PERCENT are excluded.
+func AffectsCompliance(key, actualValue string) bool {
+	return actualValue != "all:none" && !system.IsInternalGrub(key) && !(system.IsXFSOption.MatchString(key) && actualValue == "NA") && actualValue != "PNA" && key != "VSZ_TMPFS_PERCENT"
+}
+
 // chkGrubCompliance grub special - check compliance of alternative settings
 // only if one of these alternatives are not compliant, modify the result of
 // the compare
@@ -303,6 +315,9 @@ func cmpMapValue(fieldName string, key reflect.Value, actVal, expVal interface{}
 	if strings.Split(key.String(), ":")[0] == "systemd" {
 		match = system.CmpServiceStates(actVal.(string), expVal.(string))
 	}
+	if system.IsManagerKey.MatchString(key.String()) {
+		match = system.CmpManagerValue(key.String(), actVal.(string), expVal.(string))
+	}
 	if expVal == "" {
 		// if the expected value is empty, the parameter value will
 		// be untouched
@@ -353,5 +368,7 @@ func GetVendInfo(initype, ID string) (Note, error) {
 	if err == nil && len(content) != 0 {
 		err = json.Unmarshal(content, &vendConf)
 	}
+	// the values read during Initialise are not part of the stored data
+	vendConf.run = vendRunState(ID)
 	return vendConf, err
 }
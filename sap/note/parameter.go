@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"strings"
 )
 
 // ParameterNoteEntry stores the parameter values set by a Note
@@ -24,7 +25,19 @@ type ParameterNotes struct {
 
 // GetPathToParameter returns path to the serialised parameter state file.
 func GetPathToParameter(param string) string {
-	return path.Join(system.SaptuneParameterStateDir, param)
+	return path.Join(system.SaptuneParameterStateDir, paramFileName(param))
+}
+
+// paramFileName returns the name of the state file of the parameter.
+// A '/' inside of a parameter name (e.g. the VLAN interface in the sysctl
+// key 'net.ipv4.conf.eth0/100.rp_filter') is escaped as '%2F'
+func paramFileName(param string) string {
+	return strings.Replace(strings.Replace(param, "%", "%25", -1), "/", "%2F", -1)
+}
+
+// paramFromFileName returns the parameter name of a state file name
+func paramFromFileName(name string) string {
+	return strings.Replace(strings.Replace(name, "%2F", "/", -1), "%25", "%", -1)
 }
 
 // ListParams lists all stored parameter states. Return parameter names
@@ -41,15 +54,16 @@ func ListParams() (ret []string, err error) {
 	}
 	ret = make([]string, 0, len(dirContent))
 	for _, pname := range dirContent {
-		ret = append(ret, pname.Name())
+		ret = append(ret, paramFromFileName(pname.Name()))
 	}
 	return
 }
 
 // CreateParameterStartValues creates the parameter state file and inserts
-// the start values.
-func CreateParameterStartValues(param, value string) {
+// the start values. Returns true, if the parameter state file was created.
+func CreateParameterStartValues(param, value string) bool {
 	pEntries := GetSavedParameterNotes(param)
+	created := false
 	if len(pEntries.AllNotes) == 0 {
 		system.DebugLog("Write parameter start value '%s' to file '%s'", value, GetPathToParameter(param))
 		// file does not exist, create start entry
@@ -61,8 +75,21 @@ func CreateParameterStartValues(param, value string) {
 		err := pEntries.StoreParameter(param, true)
 		if err != nil {
 			system.WarningLog("Failed to store start values for parameter file '%s' for parameter '%s'", GetPathToParameter(param), param)
+		} else {
+			created = true
 		}
 	}
+	return created
+}
+
+// parameterStartValue returns the start value of the parameter from the
+// parameter state file
+func parameterStartValue(key string) string {
+	pEntries := GetSavedParameterNotes(key)
+	if len(pEntries.AllNotes) == 0 {
+		return ""
+	}
+	return pEntries.AllNotes[0].Value
 }
 
 // AddParameterNoteValues adds note parameter values to the state file.
@@ -261,3 +288,23 @@ func IsLastNoteOfParameter(param string) bool {
 	}
 	return false
 }
+
+// RemoveNoteFromParameters removes the references of the given noteID from
+// all parameter state files without touching any system value.
+// Parameter state files containing only the 'start' entry are removed too,
+// if they were created during the failed apply ('startStates'), as they are
+// left overs of this apply.
+// Used to clean up after a failed atomic apply
+func RemoveNoteFromParameters(noteID string, startStates map[string]string) {
+	allParams, err := ListParams()
+	if err != nil {
+		return
+	}
+	for _, param := range allParams {
+		pEntries := GetSavedParameterNotes(param)
+		_, created := startStates[param]
+		if pEntries.PositionInParameterList(noteID) > 0 || (len(pEntries.AllNotes) == 1 && created) {
+			_, _ = RevertParameter(param, noteID)
+		}
+	}
+}
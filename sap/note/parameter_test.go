@@ -24,6 +24,28 @@ func TestGetPathToParameter(t *testing.T) {
 	}
 }
 
+func TestParameterFileName(t *testing.T) {
+	param := "net.ipv4.conf.eth0/100.rp_filter"
+	if val := GetPathToParameter(param); val != "/run/saptune/parameter/net.ipv4.conf.eth0%2F100.rp_filter" {
+		t.Errorf("parameter file name: %v.\n", val)
+	}
+	CreateParameterStartValues(param, "1")
+	defer CleanUpParamFile(param)
+	if val := GetSavedParameterNotes(param); len(val.AllNotes) == 0 || val.AllNotes[0].Value != "1" {
+		t.Errorf("wrong content in state file of '%s': '%+v'\n", param, val)
+	}
+	params, _ := ListParams()
+	found := false
+	for _, p := range params {
+		if p == param {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("'%s' not listed in '%+v'\n", param, params)
+	}
+}
+
 func TestGetSavedParameterNotes(t *testing.T) {
 	val := GetSavedParameterNotes("TEST_PARAMETER")
 	if len(val.AllNotes) > 0 {
@@ -163,6 +185,7 @@ func TestGetAllSavedParameters(t *testing.T) {
 	CleanUpParamFile("TEST_PARAMETER_1")
 	CleanUpParamFile("TEST_PARAMETER_2")
 	CleanUpParamFile("TEST_PARAMETER_3")
+	CleanUpParamFile("TEST_PARAMETER_4")
 }
 
 func TestStoreParameter(t *testing.T) {
@@ -247,3 +270,33 @@ func TestRevertParameter(t *testing.T) {
 	}
 	CleanUpParamFile("TEST_PARAMETER_1")
 }
+
+func TestRemoveNoteFromParameters(t *testing.T) {
+	CreateParameterStartValues("TEST_PARAMETER", "TestStartValue")
+	AddParameterNoteValues("TEST_PARAMETER", "AtomicValue", "atomicNote", "add")
+	CreateParameterStartValues("TEST_PARAMETER_2", "TestStartValue2")
+	AddParameterNoteValues("TEST_PARAMETER_2", "OtherValue", "otherNote", "add")
+	AddParameterNoteValues("TEST_PARAMETER_2", "AtomicValue2", "atomicNote", "add")
+	CreateParameterStartValues("TEST_PARAMETER_3", "TestStartValue3")
+	// start-only state file not created by the failed apply
+	CreateParameterStartValues("TEST_PARAMETER_4", "TestStartValue4")
+
+	RemoveNoteFromParameters("atomicNote", map[string]string{"TEST_PARAMETER": "TestStartValue", "TEST_PARAMETER_3": "TestStartValue3"})
+	if !IsLastNoteOfParameter("TEST_PARAMETER") {
+		t.Errorf("parameter state file 'TEST_PARAMETER' still exists\n")
+	}
+	if !IsLastNoteOfParameter("TEST_PARAMETER_3") {
+		t.Errorf("left over parameter state file 'TEST_PARAMETER_3' still exists\n")
+	}
+	if IsLastNoteOfParameter("TEST_PARAMETER_4") {
+		t.Errorf("parameter state file 'TEST_PARAMETER_4' not created by the apply removed\n")
+	}
+	val := GetSavedParameterNotes("TEST_PARAMETER_2")
+	if len(val.AllNotes) != 2 || val.IDInParameterList("atomicNote") || !val.IDInParameterList("otherNote") {
+		t.Errorf("wrong content in state file 'TEST_PARAMETER_2': '%+v'\n", val)
+	}
+	CleanUpParamFile("TEST_PARAMETER")
+	CleanUpParamFile("TEST_PARAMETER_2")
+	CleanUpParamFile("TEST_PARAMETER_3")
+	CleanUpParamFile("TEST_PARAMETER_4")
+}
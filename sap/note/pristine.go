@@ -0,0 +1,151 @@
+package note
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/SUSE/saptune/system"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// PristineEntry stores the value of a parameter found on the system before
+// saptune changed the parameter for the very first time.
+// Kernel and Hardware describe the system at the time the value was captured
+// and are used to validate the baseline before reverting to it.
+type PristineEntry struct {
+	Value    string
+	NoteID   string
+	Kernel   string
+	Hardware string
+	Captured string
+}
+
+// GetPathToPristine returns path to the persistent pristine baseline file
+// of the parameter
+func GetPathToPristine(param string) string {
+	return path.Join(system.SaptunePristineStateDir, paramFileName(param))
+}
+
+// pristineKernel returns the upstream version of the running kernel
+// without the distribution specific release part, so that kernel
+// maintenance updates do not invalidate the baseline
+var pristineKernel = func() string {
+	return strings.Split(system.KernelRelease(), "-")[0]
+}
+
+// pristineHardware returns a short identity of the hardware of the system
+// The number of possible cpus is used, as the number of online cpus changes
+// with cpu hotplug or SMT settings, which saptune may change itself
+var pristineHardware = func() string {
+	vendor, _ := system.GetHWIdentity("vendor")
+	model, _ := system.GetHWIdentity("model")
+	return fmt.Sprintf("%s %s, %d CPUs", vendor, model, system.PossibleCPUs())
+}
+
+// CreatePristineValue stores the given value as persistent pristine baseline
+// of the parameter. The baseline is captured only once, an already existing
+// baseline is never overwritten.
+func CreatePristineValue(param, value, noteID string) {
+	if _, err := os.Stat(GetPathToPristine(param)); err == nil {
+		return
+	}
+	pEntry := PristineEntry{
+		Value:    value,
+		NoteID:   noteID,
+		Kernel:   pristineKernel(),
+		Hardware: pristineHardware(),
+		Captured: time.Now().Format(time.RFC3339),
+	}
+	content, err := json.Marshal(pEntry)
+	if err == nil {
+		if err = os.MkdirAll(system.SaptunePristineStateDir, 0755); err == nil {
+			system.DebugLog("Write pristine value '%s' to file '%s'", value, GetPathToPristine(param))
+			err = os.WriteFile(GetPathToPristine(param), content, 0644)
+		}
+	}
+	if err != nil {
+		system.WarningLog("Failed to store pristine value for parameter '%s' to file '%s' - %v", param, GetPathToPristine(param), err)
+	}
+}
+
+// GetPristineValue reads the persistent pristine baseline of the parameter
+// Returns false, if no baseline is available
+func GetPristineValue(param string) (PristineEntry, bool) {
+	pEntry := PristineEntry{}
+	content, err := os.ReadFile(GetPathToPristine(param))
+	if err != nil || len(content) == 0 {
+		return pEntry, false
+	}
+	if err := json.Unmarshal(content, &pEntry); err != nil {
+		system.WarningLog("Failed to read pristine value for parameter '%s' from file '%s' - %v", param, GetPathToPristine(param), err)
+		return pEntry, false
+	}
+	return pEntry, true
+}
+
+// ChkPristineValue validates the pristine baseline of a parameter against
+// the current system. A baseline captured with a different kernel version
+// or on different hardware is no longer reliable.
+func ChkPristineValue(param string, pEntry PristineEntry) error {
+	if kernel := pristineKernel(); pEntry.Kernel != kernel {
+		return fmt.Errorf("pristine value of parameter '%s' was captured with kernel '%s', but kernel '%s' is running", param, pEntry.Kernel, kernel)
+	}
+	if hardware := pristineHardware(); pEntry.Hardware != hardware {
+		return fmt.Errorf("pristine value of parameter '%s' was captured on hardware '%s', but the current hardware is '%s'", param, pEntry.Hardware, hardware)
+	}
+	return nil
+}
+
+// PristineRevertValue returns the validated pristine baseline value of the
+// parameter, which should be used instead of the session start value during
+// a revert. Returns false, if no valid baseline is available. In this case
+// the session start value has to be used.
+func PristineRevertValue(param string) (string, bool) {
+	pEntry, ok := GetPristineValue(param)
+	if !ok {
+		system.InfoLog("no pristine value available for parameter '%s', using the start value of the current session", param)
+		return "", false
+	}
+	if err := ChkPristineValue(param, pEntry); err != nil {
+		system.WarningLog("%v. Using the start value of the current session.", err)
+		return "", false
+	}
+	return pEntry.Value, true
+}
+
+// pristineStartValue returns the start value of the parameter state file,
+// which is the value before any applied Note changed the parameter.
+// 'value' is returned, if no start value is available
+func pristineStartValue(param, value string) string {
+	if start := parameterStartValue(param); start != "" {
+		return start
+	}
+	return value
+}
+
+// CreatePristineFromStartValues captures the persistent pristine baseline
+// of all parameters, which are already tuned by applied Notes, from the
+// start values of the parameter state files.
+// Used, if PRISTINE_BASELINE is enabled while Notes are already applied
+func CreatePristineFromStartValues() {
+	params, err := ListParams()
+	if err != nil {
+		system.WarningLog("Failed to read the parameter state files - %v", err)
+		return
+	}
+	for _, param := range params {
+		pEntries := GetSavedParameterNotes(param)
+		if len(pEntries.AllNotes) < 2 {
+			// no applied Note changed the parameter
+			continue
+		}
+		CreatePristineValue(param, pEntries.AllNotes[0].Value, pEntries.AllNotes[1].NoteID)
+	}
+}
+
+// CleanUpPristine removes all persistent pristine baseline files
+func CleanUpPristine() {
+	os.RemoveAll(system.SaptunePristineStateDir)
+}
@@ -0,0 +1,106 @@
+package note
+
+import (
+	"github.com/SUSE/saptune/system"
+	"os"
+	"testing"
+)
+
+func TestPristineValue(t *testing.T) {
+	oldKernel := pristineKernel
+	oldHardware := pristineHardware
+	defer func() {
+		pristineKernel = oldKernel
+		pristineHardware = oldHardware
+	}()
+	pristineKernel = func() string { return "6.4.0" }
+	pristineHardware = func() string { return "TestVendor TestModel, 4 CPUs" }
+	param := "saptune.test.pristine"
+	defer os.Remove(GetPathToPristine(param))
+
+	if _, ok := GetPristineValue(param); ok {
+		t.Error("expected no pristine value")
+	}
+	CreatePristineValue(param, "10", "4711")
+	// the baseline is captured only once
+	CreatePristineValue(param, "20", "0815")
+	pEntry, ok := GetPristineValue(param)
+	if !ok {
+		t.Error("expected a pristine value")
+	}
+	if pEntry.Value != "10" || pEntry.NoteID != "4711" || pEntry.Kernel != "6.4.0" {
+		t.Errorf("unexpected pristine entry: '%+v'", pEntry)
+	}
+	if err := ChkPristineValue(param, pEntry); err != nil {
+		t.Error(err)
+	}
+	val, ok := PristineRevertValue(param)
+	if !ok || val != "10" {
+		t.Errorf("got: '%s', '%v', expected: '10', 'true'", val, ok)
+	}
+
+	// kernel change invalidates the baseline
+	pristineKernel = func() string { return "6.12.0" }
+	if err := ChkPristineValue(param, pEntry); err == nil {
+		t.Error("expected an error for a kernel change")
+	}
+	if _, ok := PristineRevertValue(param); ok {
+		t.Error("expected an invalid pristine value after a kernel change")
+	}
+	// hardware change invalidates the baseline
+	pristineKernel = func() string { return "6.4.0" }
+	pristineHardware = func() string { return "TestVendor TestModel, 8 CPUs" }
+	if err := ChkPristineValue(param, pEntry); err == nil {
+		t.Error("expected an error for a hardware change")
+	}
+	if GetPathToPristine(param) != system.SaptunePristineStateDir+"/"+param {
+		t.Errorf("wrong path '%s'", GetPathToPristine(param))
+	}
+}
+
+func TestPristineFromStartValues(t *testing.T) {
+	param := "saptune.test.pristine.start"
+	param2 := "saptune.test.pristine.start2"
+	defer func() {
+		for _, p := range []string{param, param2} {
+			os.Remove(GetPathToPristine(p))
+			CleanUpParamFile(p)
+		}
+	}()
+	// PRISTINE_BASELINE enabled while a Note is already applied
+	CreateParameterStartValues(param, "60")
+	AddParameterNoteValues(param, "10", "4711", "add")
+	CreatePristineFromStartValues()
+	if pEntry, ok := GetPristineValue(param); !ok || pEntry.Value != "60" || pEntry.NoteID != "4711" {
+		t.Errorf("unexpected pristine entry: '%+v', '%v'", pEntry, ok)
+	}
+
+	// a further Note is applied, the system value is already tuned
+	CreateParameterStartValues(param2, "60")
+	AddParameterNoteValues(param2, "10", "4711", "add")
+	vend := INISettings{ID: "0815", SysctlParams: map[string]string{param2: "10"}, ValuesToApply: map[string]string{"pristine-capture": "pristine-capture"}}
+	vend.createParamSavedStates(param2, "")
+	if pEntry, ok := GetPristineValue(param2); !ok || pEntry.Value != "60" {
+		t.Errorf("unexpected pristine entry: '%+v', '%v'", pEntry, ok)
+	}
+}
+
+func TestPristineRevertFlags(t *testing.T) {
+	param := "saptune.test.pristine.revert"
+	defer func() {
+		os.Remove(GetPathToPristine(param))
+		CleanUpParamFile(param)
+	}()
+	CreatePristineValue(param, "60", "4711")
+	for flag, exp := range map[string]string{"pristine-capture": "50", "pristine-revert": "60"} {
+		CleanUpParamFile(param)
+		CreateParameterStartValues(param, "50")
+		AddParameterNoteValues(param, "10", "4711", "add")
+		vend := INISettings{ID: "4711", SysctlParams: map[string]string{param: "10"}, ValuesToApply: map[string]string{"revert": "revert", flag: flag}}
+		vend.setRevertParamValues(param)
+		// only 'pristine-revert' reverts to the pristine baseline
+		if vend.SysctlParams[param] != exp {
+			t.Errorf("%s: expected '%s', actual '%s'", flag, exp, vend.SysctlParams[param])
+		}
+	}
+}
@@ -50,6 +50,17 @@ func GetBlkVal(key string, cur *param.BlockDeviceQueue) (string, string, error)
 		newMse := newMsect.(param.BlockDeviceMaxSectorsKB).MaxSectorsKB
 		retVal = strconv.Itoa(newMse[strings.TrimPrefix(key, "MAX_SECTORS_KB_")])
 		cur.BlockDeviceMaxSectorsKB = newMsect.(param.BlockDeviceMaxSectorsKB)
+	case system.IsBlkTunable.MatchString(key):
+		newTunables, err := cur.BlockDeviceTunables.Inspect()
+		if err != nil {
+			return "", info, err
+		}
+		name, bdev := system.GetBlockTunable(key)
+		retVal = newTunables.(param.BlockDeviceTunables).Tunables[name][bdev]
+		if retVal == "" {
+			retVal = "NA"
+		}
+		cur.BlockDeviceTunables = newTunables.(param.BlockDeviceTunables)
 	}
 	return retVal, info, nil
 }
@@ -108,6 +119,17 @@ func OptBlkVal(key, cfgval string, cur *param.BlockDeviceQueue, bOK map[string][
 		ival, sval, info = chkMaxHWsector(key, sval)
 		opt, _ := cur.BlockDeviceMaxSectorsKB.Optimise(ival)
 		cur.BlockDeviceMaxSectorsKB = opt.(param.BlockDeviceMaxSectorsKB)
+	case system.IsBlkTunable.MatchString(key):
+		// validate against the scheduler, which will be used for the
+		// block device after apply
+		name, bdev := system.GetBlockTunable(key)
+		sval = strings.TrimSpace(sval)
+		if ok, reason := param.IsValidBlockTunable(bdev, name, sval, cur.BlockDeviceSchedulers.SchedulerChoice[bdev]); !ok {
+			info = "blkinval:" + reason
+		} else {
+			opt, _ := cur.BlockDeviceTunables.Optimise(name + " " + bdev + " " + sval)
+			cur.BlockDeviceTunables = opt.(param.BlockDeviceTunables)
+		}
 	}
 	return sval, info
 }
@@ -118,13 +140,20 @@ func SetBlkVal(key, value string, cur *param.BlockDeviceQueue, revert bool) erro
 
 	switch {
 	case system.IsSched.MatchString(key):
+		bdev := strings.TrimPrefix(key, "IO_SCHEDULER_")
 		if revert {
-			cur.BlockDeviceSchedulers.SchedulerChoice[strings.TrimPrefix(key, "IO_SCHEDULER_")] = value
+			cur.BlockDeviceSchedulers.SchedulerChoice[bdev] = value
 		}
-		err = cur.BlockDeviceSchedulers.Apply(strings.TrimPrefix(key, "IO_SCHEDULER_"))
+		oldElev, _ := system.GetSysChoice(path.Join("block", bdev, "queue", "scheduler"))
+		err = cur.BlockDeviceSchedulers.Apply(bdev)
 		if err != nil {
 			return err
 		}
+		if newElev, _ := system.GetSysChoice(path.Join("block", bdev, "queue", "scheduler")); newElev != oldElev {
+			// the scheduler change has replaced the iosched
+			// directory, so set the scheduler tunables again
+			err = cur.BlockDeviceTunables.ApplySchedTunables(bdev)
+		}
 	case system.IsNrreq.MatchString(key):
 		if revert {
 			ival, _ := strconv.Atoi(value)
@@ -152,10 +181,32 @@ func SetBlkVal(key, value string, cur *param.BlockDeviceQueue, revert bool) erro
 		if err != nil {
 			return err
 		}
+	case system.IsBlkTunable.MatchString(key):
+		name, bdev := system.GetBlockTunable(key)
+		if revert {
+			cur.BlockDeviceTunables.SetTunable(name, bdev, value)
+		}
+		err = cur.BlockDeviceTunables.Apply(name + " " + bdev)
 	}
 	return err
 }
 
+// chkBlkLayers checks, if the current value of a block device parameter
+// differs between the layers of a block device stack (multipath, LVM,
+// MD RAID). The differing devices are added to the info
+func chkBlkLayers(key, info string) string {
+	diffs := system.BlockStackDiffs(key)
+	if len(diffs) == 0 {
+		return info
+	}
+	system.InfoLog("value of '%s' differs between the layers of the block device stack: %s", key, strings.Join(diffs, ", "))
+	inf := "layers:" + strings.Join(diffs, ", ")
+	if info != "" {
+		return info + "§" + inf
+	}
+	return inf
+}
+
 // chkMaxHWsector checks, if the given value for max_sector_kb exceeds
 // max_hw_sector_kb
 func chkMaxHWsector(key, val string) (int, string, string) {
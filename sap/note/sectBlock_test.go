@@ -3,6 +3,7 @@ package note
 import (
 	"github.com/SUSE/saptune/sap/param"
 	"github.com/SUSE/saptune/system"
+	"strings"
 	"testing"
 )
 
@@ -142,3 +143,34 @@ func TestChkMaxHWsector(t *testing.T) {
 		t.Errorf("expected info as 'limited', but got '%s' - '%+v' - '%+v'\n", info, ival, sval)
 	}
 }
+
+func TestBlkTunableVal(t *testing.T) {
+	setUp(t)
+	blckOK := make(map[string][]string)
+	tblck := resetToFactoryBlockDevices()
+	val, _, err := GetBlkVal("RQ_AFFINITY_"+tstDisk, &tblck)
+	if err != nil || val == "" {
+		t.Errorf("unexpected value '%s' - %v", val, err)
+	}
+	val, info := OptBlkVal("RQ_AFFINITY_"+tstDisk, "2", &tblck, blckOK)
+	if val != "2" || info != "" {
+		t.Errorf("unexpected value '%s', info '%s'", val, info)
+	}
+	if tblck.BlockDeviceTunables.Tunables["RQ_AFFINITY"][tstDisk] != "2" {
+		t.Errorf("optimised value not stored: '%+v'", tblck.BlockDeviceTunables)
+	}
+	val, info = OptBlkVal("RQ_AFFINITY_"+tstDisk, "5", &tblck, blckOK)
+	if val != "5" || !strings.HasPrefix(info, "blkinval:") {
+		t.Errorf("unexpected value '%s', info '%s'", val, info)
+	}
+	// scheduler tunable not matching the expected scheduler
+	tblck.BlockDeviceSchedulers.SchedulerChoice[tstDisk] = "none"
+	val, info = OptBlkVal("SLICE_IDLE_"+tstDisk, "0", &tblck, blckOK)
+	if val != "0" || info != "blkinval:needs scheduler 'bfq', used scheduler 'none'" {
+		t.Errorf("unexpected value '%s', info '%s'", val, info)
+	}
+	// revert with a not available value does not change the system
+	if err := SetBlkVal("SLICE_IDLE_"+tstDisk, "NA", &tblck, true); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,13 +4,22 @@ import (
 	"fmt"
 	"github.com/SUSE/saptune/system"
 	"strings"
+	"sync"
 )
 
 // section [cpu]
 
+// cpuValMutex serialises the reading of the cpu values, as the cpu functions
+// of the system package use package level state (C-state latency table and
+// the counters of the once printed messages) and notes are initialised
+// concurrently during the verification
+var cpuValMutex sync.Mutex
+
 // GetCPUVal initialise the cpu performance structure with the current
 // system settings
 func GetCPUVal(key string) (string, string, string) {
+	cpuValMutex.Lock()
+	defer cpuValMutex.Unlock()
 	var val string
 	cpuStateDiffer := false
 	flsVal := ""
@@ -22,8 +31,25 @@ func GetCPUVal(key string) (string, string, string) {
 			info = "hasDiffs"
 		}
 	case "energy_perf_bias":
-		// cpupower -c all info  -b
+		// cat /sys/devices/system/cpu/cpu*/power/energy_perf_bias
 		val = system.GetPerfBias()
+	case "energy_performance_preference":
+		// cat /sys/devices/system/cpu/cpu*/cpufreq/energy_performance_preference
+		val = system.CPUValString(system.GetEPP())
+	case "scaling_min_freq", "scaling_max_freq":
+		// cat /sys/devices/system/cpu/cpu*/cpufreq/scaling_m[in|ax]_freq
+		val = system.CPUValString(system.GetCPUFreq(key))
+	case "turbo":
+		// cat /sys/devices/system/cpu/intel_pstate/no_turbo
+		// or /sys/devices/system/cpu/cpufreq/boost
+		val = system.GetTurbo()
+	case "min_perf_pct":
+		// cat /sys/devices/system/cpu/intel_pstate/min_perf_pct
+		val = system.GetMinPerfPct()
+	case "smt":
+		// cat /sys/devices/system/cpu/smt/control (x86_64) or
+		// online threads per core (ppc64le, like 'ppc64_cpu --smt')
+		val = system.GetSMT()
 	case "governor":
 		// cpupower -c all frequency-info -p
 		//or better
@@ -74,12 +100,35 @@ func OptCPUVal(key, actval, cfgval string) string {
 			fields := strings.Split(entry, ":")
 			rval = rval + fmt.Sprintf("%s:%s ", fields[0], val)
 		}
-	case "governor":
+	case "governor", "energy_performance_preference":
 		val = sval
 		for _, entry := range strings.Fields(actval) {
 			fields := strings.Split(entry, ":")
 			rval = rval + fmt.Sprintf("%s:%s ", fields[0], val)
 		}
+	case "scaling_min_freq", "scaling_max_freq":
+		// absolute values or percentage of cpuinfo_max_freq, converted
+		// to kHz for each cpu
+		rval = system.CPUValString(system.CPUFreqExpected(key, cfgval))
+	case "turbo":
+		switch sval {
+		case "on", "1":
+			rval = "on"
+		case "off", "0":
+			rval = "off"
+		default:
+			system.WarningLog("wrong selection for turbo. Now set to 'on'")
+			rval = "on"
+		}
+	case "min_perf_pct":
+		rval = strings.TrimSuffix(strings.TrimSpace(cfgval), "%")
+	case "smt":
+		// keep invalid values, so that verify can report them. They
+		// will be skipped during apply
+		if valid, choices := system.IsValidSMT(sval); !valid {
+			system.WarningLog("wrong selection for smt, valid values are: %s", choices)
+		}
+		rval = system.PPCSMTValue(sval)
 	}
 	return strings.TrimSpace(rval)
 }
@@ -97,14 +146,24 @@ func SetCPUVal(key, value, noteID, savedStates, oval string, revert bool) error
 				// as we set and handle 2 different sort of values
 				// the 'force_latency' value and the related
 				// cpu state values
-				_, flstates, _ = system.GetFLInfo()
-				AddParameterNoteValues("fl_states", flstates, noteID, "add")
+				_, states, _ := system.GetFLInfo()
+				AddParameterNoteValues("fl_states", states, noteID, "add")
 			}
 		}
 	case "energy_perf_bias":
 		err = system.SetPerfBias(value)
 	case "governor":
 		err = system.SetGovernor(value)
+	case "energy_performance_preference":
+		err = system.SetEPP(value)
+	case "scaling_min_freq", "scaling_max_freq":
+		err = system.SetCPUFreq(key, value)
+	case "turbo":
+		err = system.SetTurbo(value)
+	case "min_perf_pct":
+		err = system.SetMinPerfPct(value)
+	case "smt":
+		err = system.SetSMT(value, revert)
 	}
 
 	return err
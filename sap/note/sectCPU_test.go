@@ -1,6 +1,7 @@
 package note
 
 import (
+	"runtime"
 	"testing"
 )
 
@@ -17,6 +18,10 @@ func TestGetCPUVal(t *testing.T) {
 	if val != "all:none" && val != "" {
 		t.Logf("governor supported: '%s'\n", val)
 	}
+	val, _, _ = GetCPUVal("energy_performance_preference")
+	if val != "all:none" {
+		t.Logf("energy_performance_preference supported: '%s'\n", val)
+	}
 }
 
 func TestOptCPUVal(t *testing.T) {
@@ -65,6 +70,40 @@ func TestOptCPUVal(t *testing.T) {
 	if val != "cpu0:performance cpu1:performance cpu2:performance" {
 		t.Error(val)
 	}
+	val = OptCPUVal("energy_performance_preference", "all:balance_performance", "Performance")
+	if val != "all:performance" {
+		t.Error(val)
+	}
+	val = OptCPUVal("energy_performance_preference", "cpu0:power cpu1:balance_power", "balance_performance")
+	if val != "cpu0:balance_performance cpu1:balance_performance" {
+		t.Error(val)
+	}
+	val = OptCPUVal("turbo", "on", "Off")
+	if val != "off" {
+		t.Error(val)
+	}
+	val = OptCPUVal("turbo", "all:none", "1")
+	if val != "on" {
+		t.Error(val)
+	}
+	val = OptCPUVal("turbo", "off", "unknown")
+	if val != "on" {
+		t.Error(val)
+	}
+	val = OptCPUVal("min_perf_pct", "20", "50%")
+	if val != "50" {
+		t.Error(val)
+	}
+	if runtime.GOARCH != "ppc64le" {
+		val = OptCPUVal("smt", "on", "OFF")
+		if val != "off" {
+			t.Error(val)
+		}
+		val = OptCPUVal("smt", "on", "8")
+		if val != "8" {
+			t.Error(val)
+		}
+	}
 	/* future feature
 	val = OptCPUVal("governor", "cpu0:powersave cpu1:performance cpu2:powersave", "cpu0:performance cpu1:powersave cpu2:performance")
 	if val != "cpu0:performance cpu1:powersave cpu2:performance" {
@@ -0,0 +1,176 @@
+package note
+
+import (
+	"fmt"
+	"github.com/SUSE/saptune/system"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// section [coredump]
+// Manipulate kernel.core_pattern, the systemd-coredump configuration and
+// the core file size limit and check, if a full core could be written
+
+// coredumpDropIn returns the name of the saptune drop-in file of a
+// coredump.conf key
+func coredumpDropIn(key string) string {
+	return fmt.Sprintf("saptune-%s.conf", key)
+}
+
+// coreLimitEntry converts the value of 'core_limit' ('domain type value')
+// into a limits entry ('domain type core value')
+func coreLimitEntry(value string) []string {
+	lim := strings.Fields(value)
+	if len(lim) < 2 {
+		return nil
+	}
+	entry := []string{lim[0], lim[1], "core"}
+	return append(entry, lim[2:]...)
+}
+
+// GetCoredumpVal initialise the core dump structure with the current
+// system settings.
+// For 'full_core_size' the configured value is returned, if a full core of
+// this size could be written, otherwise the reason why not.
+// 'coreLimit' is the value of 'core_limit' of the Note definition file
+func GetCoredumpVal(key, cfgval, coreLimit string) (string, string) {
+	info := ""
+	switch key {
+	case "core_pattern":
+		val, err := system.GetSysctlString("kernel.core_pattern")
+		if err != nil {
+			return "NA", info
+		}
+		// the arguments of a pipe handler may be separated by
+		// several blanks
+		return strings.Join(strings.Fields(val), " "), info
+	case "core_limit":
+		lim := coreLimitEntry(cfgval)
+		if lim == nil {
+			system.WarningLog("wrong format of 'core_limit' entry '%s', use '<domain> <type> <value>'", cfgval)
+			return "NA", info
+		}
+		limit, _, err := GetLimitsVal(strings.Join(lim[:3], " "))
+		if err != nil {
+			return "NA", info
+		}
+		cur := strings.Fields(limit)
+		// remove item 'core'
+		return strings.Join(append(cur[:2], cur[3:]...), " "), info
+	case "full_core_size":
+		size, err := system.CoreSizeToBytes(cfgval)
+		if err != nil {
+			system.WarningLog("%v", err)
+			return "NA", info
+		}
+		verdict := system.CoredumpVerdict(size, coreLimitBytes(coreLimit))
+		if verdict == "" {
+			return cfgval, info
+		}
+		system.WarningLog("a full core of size '%s' can not be written - %s", cfgval, verdict)
+		return verdict, info
+	}
+	if !system.IsCoredumpKey(key) {
+		system.WarningLog("'%s' is not a supported key of the [coredump] section, skipping", key)
+		return "NA", info
+	}
+	return system.GetCoredumpValue(key), info
+}
+
+// OptCoredumpVal returns the value from the configuration file.
+// Invalid values are kept, so that verify can report them. They will be
+// skipped during apply
+func OptCoredumpVal(key, cfgval string) string {
+	val := strings.TrimSpace(cfgval)
+	switch key {
+	case "core_pattern", "core_limit":
+		// the parser separates multiple values by tabs
+		return strings.Join(strings.Fields(val), " ")
+	case "full_core_size":
+		return val
+	}
+	if system.IsCoredumpKey(key) && !system.IsValidCoredumpValue(key, val) {
+		system.WarningLog("wrong value '%s' for the coredump.conf key '%s'", cfgval, key)
+	}
+	return val
+}
+
+// SetCoredumpVal applies the settings to the system
+// The coredump.conf keys are written to the saptune drop-in file of the key
+// in /etc/systemd/coredump.conf.d. systemd-coredump reads its configuration
+// for each core, so no reload is needed. During revert of the last Note
+// using the key the drop-in file is removed
+func SetCoredumpVal(key, noteID, value string, revert bool) error {
+	switch key {
+	case "full_core_size":
+		// only checked, not applied
+		return nil
+	case "core_pattern":
+		if value == "" || value == "NA" {
+			return nil
+		}
+		return system.SetSysctlString("kernel.core_pattern", value)
+	case "core_limit":
+		lim := coreLimitEntry(value)
+		if lim == nil {
+			return nil
+		}
+		if len(lim) < 4 {
+			lim = append(lim, "NA")
+		}
+		return SetLimitsVal(key, noteID, strings.Join(lim, " "), revert)
+	}
+	if !system.IsCoredumpKey(key) {
+		return nil
+	}
+	dropIn := path.Join(system.CoredumpConfDir, coredumpDropIn(key))
+	if revert && IsLastNoteOfParameter(key) {
+		// revert - remove coredump drop-in file
+		if err := os.Remove(dropIn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if value == "" || value == "NA" {
+		return nil
+	}
+	if !system.IsValidCoredumpValue(key, value) {
+		system.WarningLog("wrong value '%s' for the coredump.conf key '%s', skipping", value, key)
+		return nil
+	}
+	// revert with value from another former applied note
+	// or
+	// apply - Prepare coredump drop-in file
+	if err := os.MkdirAll(system.CoredumpConfDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dropIn, []byte(fmt.Sprintf("[Coredump]\n%s=%s\n", key, value)), 0644)
+}
+
+// coreLimitBytes returns the current soft core file size limit of the
+// 'core_limit' entry in bytes. math.MaxInt64 means 'unlimited', -1 means
+// 'unknown' or 'not configured'
+func coreLimitBytes(coreLimit string) int64 {
+	if coreLimit == "" {
+		return -1
+	}
+	cur, _ := GetCoredumpVal("core_limit", coreLimit, "")
+	lim := strings.Fields(cur)
+	// dom=[0], type=[1], value=[2]
+	if len(lim) < 3 || lim[1] == "hard" {
+		return -1
+	}
+	switch lim[2] {
+	case "unlimited", "infinity", "-1":
+		return math.MaxInt64
+	}
+	kb, err := strconv.ParseInt(lim[2], 10, 64)
+	if err != nil {
+		return -1
+	}
+	// limits.conf uses KB for core
+	return kb * 1024
+}
@@ -0,0 +1,84 @@
+package note
+
+import (
+	"github.com/SUSE/saptune/system"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestGetCoredumpVal(t *testing.T) {
+	if val, _ := GetCoredumpVal("UnknownKey", "1", ""); val != "NA" {
+		t.Error(val)
+	}
+	if val, _ := GetCoredumpVal("core_limit", "@sapsys", ""); val != "NA" {
+		t.Error(val)
+	}
+	if val, _ := GetCoredumpVal("core_limit", "@sdba soft unlimited", ""); val != "@sdba soft NA" {
+		t.Error(val)
+	}
+	if val, _ := GetCoredumpVal("full_core_size", "many", ""); val != "NA" {
+		t.Error(val)
+	}
+	val, _ := GetCoredumpVal("core_pattern", "", "")
+	if val == "" {
+		t.Error(val)
+	}
+	t.Logf("core_pattern: '%s'\n", val)
+}
+
+func TestOptCoredumpVal(t *testing.T) {
+	if val := OptCoredumpVal("core_limit", " @sapsys  -  unlimited "); val != "@sapsys - unlimited" {
+		t.Error(val)
+	}
+	if val := OptCoredumpVal("core_pattern", " |/usr/lib/systemd/systemd-coredump\t%P\t%u "); val != "|/usr/lib/systemd/systemd-coredump %P %u" {
+		t.Error(val)
+	}
+	// invalid values are kept for verify
+	if val := OptCoredumpVal("ProcessSizeMax", "many"); val != "many" {
+		t.Error(val)
+	}
+	if name := coredumpDropIn("MaxUse"); name != "saptune-MaxUse.conf" {
+		t.Error(name)
+	}
+	if lim := coreLimitEntry("@sapsys - unlimited"); len(lim) != 4 || lim[2] != "core" {
+		t.Error(lim)
+	}
+	if lim := coreLimitBytes(""); lim != -1 {
+		t.Error(lim)
+	}
+}
+
+func TestSetCoredumpVal(t *testing.T) {
+	oldConfDir := system.CoredumpConfDir
+	defer func() { system.CoredumpConfDir = oldConfDir }()
+	system.CoredumpConfDir = "/tmp/saptune_test_coredump/coredump.conf.d"
+	defer os.RemoveAll(path.Dir(system.CoredumpConfDir))
+	dropIn := path.Join(system.CoredumpConfDir, "saptune-ProcessSizeMax.conf")
+
+	// invalid values, unknown keys and check-only keys are skipped
+	if err := SetCoredumpVal("ProcessSizeMax", "", "many", false); err != nil {
+		t.Error(err)
+	}
+	if err := SetCoredumpVal("UnknownKey", "", "1", false); err != nil {
+		t.Error(err)
+	}
+	if err := SetCoredumpVal("full_core_size", "", "100%", false); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(dropIn); err == nil {
+		t.Errorf("drop-in file '%s' written for invalid value", dropIn)
+	}
+	if err := SetCoredumpVal("ProcessSizeMax", "", "512G", false); err != nil {
+		t.Error(err)
+	}
+	if !system.CheckForPattern(dropIn, "ProcessSizeMax=512G") {
+		t.Errorf("wrong content of file '%s'", dropIn)
+	}
+	if err := SetCoredumpVal("ProcessSizeMax", "", "32G", true); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(dropIn); err == nil {
+		t.Errorf("drop-in file '%s' not removed", dropIn)
+	}
+}
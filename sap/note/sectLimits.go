@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/SUSE/saptune/system"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -101,3 +102,48 @@ func getLimitInfo(val string) string {
 	}
 	return info
 }
+
+// chkLimitsRuntime checks the effective limits of the running processes of
+// the limit domain, if enabled by LIMITS_RUNTIME_CHECK, as the limits of
+// the drop-in files are not applied to processes started by systemd
+// (e.g. sapinit or SAP<SID>_<nr>.service).
+// The effective values per process group are added to the info
+func chkLimitsRuntime(limit, info string) string {
+	if !system.LimitsRuntimeCheck() {
+		return info
+	}
+	// dom=[0], type=[1], item=[2], value=[3]
+	lim := strings.Fields(limit)
+	if len(lim) < 4 || lim[3] == "NA" || lim[3] == "" {
+		return info
+	}
+	procLimits, err := system.ProcessLimits(lim[0], lim[1], lim[2])
+	if err != nil {
+		system.InfoLog("runtime check of limit '%s' skipped - %v", limit, err)
+		return info
+	}
+	if len(procLimits) == 0 {
+		return info
+	}
+	expected := lim[3]
+	if expected == "infinity" || expected == "-1" {
+		expected = "unlimited"
+	}
+	names := []string{}
+	for name := range procLimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	effective := []string{}
+	for _, name := range names {
+		if procLimits[name] != expected {
+			system.WarningLog("limit '%s' is not effective for the running processes '%s', effective limit is '%s'", limit, name, procLimits[name])
+		}
+		effective = append(effective, name+"="+procLimits[name])
+	}
+	inf := "runtime:" + strings.Join(effective, ", ")
+	if info != "" {
+		return info + "§" + inf
+	}
+	return inf
+}
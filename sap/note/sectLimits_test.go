@@ -1,6 +1,7 @@
 package note
 
 import (
+	"github.com/SUSE/saptune/system"
 	"testing"
 )
 
@@ -27,3 +28,17 @@ func TestOptLimitsVal(t *testing.T) {
 }
 
 //SetLimitsVal apply and revert
+
+func TestChkLimitsRuntime(t *testing.T) {
+	if info := chkLimitsRuntime("@sapsys soft nofile 1048576", "limit_exceeded"); info != "limit_exceeded" {
+		t.Error(info)
+	}
+	system.SetLimitsRuntimeCheck("yes")
+	defer system.SetLimitsRuntimeCheck("no")
+	if info := chkLimitsRuntime("unknownadm soft nofile 1048576", "limit_exceeded"); info != "limit_exceeded" {
+		t.Error(info)
+	}
+	if info := chkLimitsRuntime("@sapsys soft nofile NA", ""); info != "" {
+		t.Error(info)
+	}
+}
@@ -11,6 +11,48 @@ import (
 
 // section [login]
 
+// logindBoolKeys maps the boolean logind.conf keys, which are validated
+// explicitly, to their compiled-in systemd-logind default. The default is
+// used, if the key is not set in the logind configuration.
+// 'yes' destroys the shared memory segments of SAP HANA (RemoveIPC) or kills
+// SAP processes started from a ssh session (KillUserProcesses), when the
+// user logs out
+var logindBoolKeys = map[string]string{
+	"RemoveIPC":         "yes",
+	"KillUserProcesses": "no",
+}
+
+// logindKeys are the valid keys of the [Login] section of logind.conf
+var logindKeys = []string{
+	"NAutoVTs", "ReserveVT", "KillUserProcesses", "KillOnlyUsers",
+	"KillExcludeUsers", "IdleAction", "IdleActionSec", "InhibitDelayMaxSec",
+	"UserStopDelaySec", "HandlePowerKey", "HandlePowerKeyLongPress",
+	"HandleRebootKey", "HandleRebootKeyLongPress", "HandleSuspendKey",
+	"HandleSuspendKeyLongPress", "HandleHibernateKey",
+	"HandleHibernateKeyLongPress", "HandleLidSwitch",
+	"HandleLidSwitchExternalPower", "HandleLidSwitchDocked",
+	"PowerKeyIgnoreInhibited", "SuspendKeyIgnoreInhibited",
+	"HibernateKeyIgnoreInhibited", "LidSwitchIgnoreInhibited",
+	"RebootKeyIgnoreInhibited", "HoldoffTimeoutSec", "RuntimeDirectorySize",
+	"RuntimeDirectoryInodesMax", "InhibitorsMax", "SessionsMax", "RemoveIPC",
+	"StopIdleSessionSec",
+}
+
+// isLogindKey checks, if key is a valid logind.conf key
+func isLogindKey(key string) bool {
+	for _, lkey := range logindKeys {
+		if lkey == key {
+			return true
+		}
+	}
+	return false
+}
+
+// logindDropIn returns the name of the saptune drop-in file of a logind key
+func logindDropIn(key string) string {
+	return fmt.Sprintf("saptune-%s.conf", key)
+}
+
 // GetLoginVal initialise the systemd login structure with the current
 // system settings
 func GetLoginVal(key string) (string, error) {
@@ -29,6 +71,24 @@ func GetLoginVal(key string) (string, error) {
 		} else {
 			val = "NA"
 		}
+	default:
+		if !isLogindKey(key) {
+			return val, nil
+		}
+		// effective value of the merged logind configuration
+		found := false
+		val, found = system.GetLogindConfValue(key)
+		if !found {
+			// compiled-in default of systemd-logind
+			if def, ok := logindBoolKeys[key]; ok {
+				val = def
+			} else {
+				val = "NA"
+			}
+		}
+		if _, ok := logindBoolKeys[key]; ok {
+			val = normLogindBool(val)
+		}
 	}
 	return val, nil
 }
@@ -38,6 +98,45 @@ func OptLoginVal(cfgval string) string {
 	return strings.ToLower(cfgval)
 }
 
+// chkLoginVal validates the boolean logind keys RemoveIPC and
+// KillUserProcesses and returns the normalized value ('yes' or 'no').
+// Invalid values are kept, so that verify can report them. They will be
+// skipped during apply
+func chkLoginVal(key, value string) string {
+	if _, ok := logindBoolKeys[key]; !ok {
+		return value
+	}
+	nval := normLogindBool(value)
+	switch nval {
+	case "yes":
+		system.WarningLog("%s=yes is not recommended for SAP systems. %s", key, logindBoolHint(key))
+	case "no":
+	default:
+		system.WarningLog("wrong value '%s' for %s, valid values are 'yes' and 'no'", value, key)
+	}
+	return nval
+}
+
+// normLogindBool maps the boolean values of systemd to 'yes' or 'no'
+func normLogindBool(value string) string {
+	switch strings.ToLower(value) {
+	case "yes", "true", "on", "1", "y", "t":
+		return "yes"
+	case "no", "false", "off", "0", "n", "f":
+		return "no"
+	}
+	return value
+}
+
+// logindBoolHint returns the reason, why the boolean logind key should be
+// set to 'no'
+func logindBoolHint(key string) string {
+	if key == "RemoveIPC" {
+		return "The shared memory segments of SAP HANA will be removed, if the <sid>adm user logs out."
+	}
+	return "SAP processes started from a login session will be killed, if the user logs out."
+}
+
 // SetLoginVal applies the settings to the system
 func SetLoginVal(key, value string, revert bool) error {
 	switch key {
@@ -108,6 +207,44 @@ func SetLoginVal(key, value string, revert bool) error {
 					"This opens up entire system to fork-bomb style attacks.")
 			}
 		}
+	default:
+		if isLogindKey(key) {
+			return setLogindKey(key, value, revert)
+		}
 	}
 	return nil
 }
+
+// setLogindKey writes the logind key to the saptune drop-in file of the key
+// in LogindConfDir. During revert of the last Note using the key the
+// drop-in file is removed, which restores the former logind configuration
+func setLogindKey(key, value string, revert bool) error {
+	dropIn := path.Join(LogindConfDir, logindDropIn(key))
+	system.DebugLog("setLogindKey - key is '%s', value is '%s', revert is '%v'\n", key, value, revert)
+	if revert && IsLastNoteOfParameter(key) {
+		// revert - remove logind drop-in file
+		if err := os.Remove(dropIn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// reload-or-try-restart systemd-logind.service
+		return system.SystemctlReloadTryRestart("systemd-logind.service")
+	}
+	if value == "" || value == "NA" {
+		return nil
+	}
+	if _, ok := logindBoolKeys[key]; ok && value != "yes" && value != "no" {
+		system.WarningLog("wrong value '%s' for %s, skipping", value, key)
+		return nil
+	}
+	// revert with value from another former applied note
+	// or
+	// apply - Prepare logind drop-in file
+	if err := os.MkdirAll(LogindConfDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dropIn, []byte(fmt.Sprintf("[Login]\n%s=%s\n", key, value)), 0644); err != nil {
+		return err
+	}
+	// reload-or-try-restart systemd-logind.service
+	return system.SystemctlReloadTryRestart("systemd-logind.service")
+}
@@ -3,6 +3,7 @@ package note
 import (
 	"github.com/SUSE/saptune/system"
 	"os"
+	"path"
 	"testing"
 	"time"
 )
@@ -27,6 +28,47 @@ func TestGetLoginVal(t *testing.T) {
 	}
 }
 
+func TestGetLoginValLogindKeys(t *testing.T) {
+	for _, key := range []string{"RemoveIPC", "KillUserProcesses"} {
+		val, err := GetLoginVal(key)
+		if err != nil || (val != "yes" && val != "no") {
+			t.Errorf("%s: '%s' - %v", key, val, err)
+		}
+	}
+	val, err := GetLoginVal("InhibitorsMax")
+	if val == "" || err != nil {
+		t.Error(val)
+	}
+	val, err = GetLoginVal("not-a-key")
+	if val != "" || err != nil {
+		t.Error(val)
+	}
+}
+
+func TestChkLoginVal(t *testing.T) {
+	for val, exp := range map[string]string{"no": "no", "false": "no", "0": "no", "yes": "yes", "on": "yes", "maybe": "maybe"} {
+		if nval := chkLoginVal("RemoveIPC", val); nval != exp {
+			t.Errorf("RemoveIPC: expected '%s', actual '%s'", exp, nval)
+		}
+		if nval := chkLoginVal("KillUserProcesses", val); nval != exp {
+			t.Errorf("KillUserProcesses: expected '%s', actual '%s'", exp, nval)
+		}
+	}
+	if val := chkLoginVal("HandleLidSwitch", "0"); val != "0" {
+		t.Error(val)
+	}
+	if name := logindDropIn("RemoveIPC"); name != "saptune-RemoveIPC.conf" {
+		t.Error(name)
+	}
+	// invalid values are skipped
+	if err := SetLoginVal("RemoveIPC", "maybe", false); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(path.Join(LogindConfDir, "saptune-RemoveIPC.conf")); err == nil {
+		t.Error("drop-in file written for invalid value")
+	}
+}
+
 func TestOptLoginVal(t *testing.T) {
 	val := OptLoginVal("unknown")
 	if val != "unknown" {
@@ -0,0 +1,69 @@
+package note
+
+import (
+	"github.com/SUSE/saptune/system"
+	"github.com/SUSE/saptune/txtparser"
+	"strings"
+)
+
+// section [mitigations]
+// only checked, but not applied
+
+// GetMitigationsVal initialise the mitigations structure with the current
+// state of the CPU vulnerability reported by the kernel
+func GetMitigationsVal(key string) string {
+	state, err := system.GetCPUVulnerabilityState(mitigationsKey(key))
+	if err != nil {
+		system.InfoLog("CPU vulnerability '%s' not available - %v", mitigationsKey(key), err)
+		return "NA"
+	}
+	return state
+}
+
+// unexpectedVulnerabilities returns the keys ('mitigations:<name>') of the
+// CPU vulnerabilities known by the running kernel, which have no expectation
+// in the [mitigations] section of the Note
+func unexpectedVulnerabilities(expected map[string]txtparser.INIEntry) []string {
+	keys := []string{}
+	for _, vuln := range system.GetCPUVulnerabilities() {
+		key := "mitigations:" + vuln
+		if _, ok := expected[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// OptMitigationsVal returns the expected state of the CPU vulnerability.
+// If the current state matches one of the states of the configuration
+// file, the current state is returned, otherwise the value of the
+// configuration file
+func OptMitigationsVal(key, actval, cfgval string) string {
+	// the parser separates multiple words by tabs
+	exp := []string{}
+	for _, state := range strings.Split(cfgval, "|") {
+		if state = strings.Join(strings.Fields(state), " "); state != "" {
+			exp = append(exp, state)
+		}
+	}
+	cfgval = strings.Join(exp, "|")
+	if actval == "NA" || cfgval == "" {
+		return cfgval
+	}
+	if system.MatchesMitigationState(actval, cfgval) {
+		return actval
+	}
+	system.WarningLog("state '%s' of CPU vulnerability '%s' does not match the expected state '%s'", actval, mitigationsKey(key), cfgval)
+	return cfgval
+}
+
+// SetMitigationsVal nothing to do, only checking for 'verify'
+func SetMitigationsVal(value string) error {
+	// nothing to do, only checking for 'verify'
+	return nil
+}
+
+// mitigationsKey returns the key without the section prefix 'mitigations:'
+func mitigationsKey(key string) string {
+	return strings.TrimPrefix(key, "mitigations:")
+}
@@ -0,0 +1,74 @@
+package note
+
+import (
+	"github.com/SUSE/saptune/system"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestGetMitigationsVal(t *testing.T) {
+	if val := GetMitigationsVal("mitigations:not_existing_vulnerability"); val != "NA" {
+		t.Error(val)
+	}
+	if val := GetMitigationsVal("mitigations:../cpu0"); val != "NA" {
+		t.Error(val)
+	}
+}
+
+func TestOptMitigationsVal(t *testing.T) {
+	tests := []struct {
+		actval, cfgval, exp string
+	}{
+		{"Mitigation: PTI", "Mitigation", "Mitigation: PTI"},
+		{"Not affected", "Mitigation|Not\taffected", "Not affected"},
+		{"Vulnerable: SMT vulnerable", "Mitigation | Not\taffected", "Mitigation|Not affected"},
+		{"NA", "Not\taffected", "Not affected"},
+		{"Vulnerable", "", ""},
+	}
+	for _, tst := range tests {
+		if val := OptMitigationsVal("mitigations:mds", tst.actval, tst.cfgval); val != tst.exp {
+			t.Errorf("'%s' (actual '%s') - expected: '%s', got: '%s'", tst.cfgval, tst.actval, tst.exp, val)
+		}
+	}
+	if err := SetMitigationsVal("Mitigation"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnexpectedVulnerabilities(t *testing.T) {
+	vulns := system.GetCPUVulnerabilities()
+	if len(vulns) < 2 {
+		t.Skip("CPU vulnerabilities not available")
+	}
+	cleanUp()
+	defer cleanUp()
+	iniPath := path.Join(t.TempDir(), "4711mitigations")
+	if err := os.WriteFile(iniPath, []byte("[mitigations]\n"+vulns[0]+" = Mitigation|Not affected|Vulnerable\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ini := INISettings{ConfFilePath: iniPath, ID: "4711mitigations"}
+	initialised, err := ini.SetValuesToApply([]string{"verify"}).Initialise()
+	if err != nil {
+		t.Fatal(err)
+	}
+	initINI := initialised.(INISettings)
+	if inf := initINI.Inform["mitigations:"+vulns[0]]; inf != "" {
+		t.Errorf("vulnerability '%s' with expectation reported as unexpected: '%s'", vulns[0], inf)
+	}
+	for _, vuln := range vulns[1:] {
+		key := "mitigations:" + vuln
+		if initINI.Inform[key] != "unexpected" || initINI.SysctlParams[key] != GetMitigationsVal(key) {
+			t.Errorf("vulnerability '%s' not reported: '%s', '%s'", vuln, initINI.SysctlParams[key], initINI.Inform[key])
+		}
+	}
+	// the current state is the expected one
+	optimised, err := initINI.Optimise()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "mitigations:" + vulns[1]
+	if val := optimised.(INISettings).SysctlParams[key]; val != initINI.SysctlParams[key] {
+		t.Errorf("expected: '%s', got: '%s'", initINI.SysctlParams[key], val)
+	}
+}
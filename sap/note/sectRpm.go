@@ -2,15 +2,38 @@ package note
 
 import (
 	"github.com/SUSE/saptune/system"
+	"github.com/SUSE/saptune/txtparser"
 	"strings"
 )
 
 // section [rpm]
 
+// RpmPackages returns the names of all packages listed in the rpm section
+// of the note definition
+func RpmPackages(ini *txtparser.INIFile) []string {
+	rpms := []string{}
+	for _, param := range ini.AllValues {
+		if param.Section != INISectionRpm {
+			continue
+		}
+		if fields := strings.Split(param.Key, ":"); len(fields) > 1 {
+			rpms = append(rpms, fields[1])
+		}
+	}
+	return rpms
+}
+
 // GetRpmVal initialise the rpm structure with the current system settings
+// The epoch is part of the version, if the installed package has an epoch.
+// To check a special package of a multilib installation use 'name.arch'
+// as package name (e.g. 'glibc.i686')
 func GetRpmVal(key string) string {
 	keyFields := strings.Split(key, ":")
-	instvers := system.GetRpmVers(keyFields[1])
+	if len(keyFields) < 2 {
+		system.WarningLog("wrong format of rpm entry '%s', use 'rpm:<package>'", key)
+		return ""
+	}
+	instvers := system.GetRpmEpochVers(keyFields[1])
 	return instvers
 }
 
@@ -1,6 +1,7 @@
 package note
 
 import (
+	"github.com/SUSE/saptune/txtparser"
 	"testing"
 )
 
@@ -24,3 +25,16 @@ func TestSetRpmVal(t *testing.T) {
 		t.Error(val)
 	}
 }
+
+func TestRpmKeyWithoutPackage(t *testing.T) {
+	if val := GetRpmVal("glibc"); val != "" {
+		t.Errorf("expected empty value, got '%s'", val)
+	}
+	ini := &txtparser.INIFile{AllValues: []txtparser.INIEntry{
+		{Section: INISectionRpm, Key: "glibc"},
+		{Section: INISectionRpm, Key: "rpm:kernel-default"},
+	}}
+	if rpms := RpmPackages(ini); len(rpms) != 1 || rpms[0] != "kernel-default" {
+		t.Errorf("unexpected packages '%+v'", rpms)
+	}
+}
@@ -3,11 +3,33 @@ package note
 import (
 	"fmt"
 	"github.com/SUSE/saptune/system"
+	"github.com/SUSE/saptune/txtparser"
 	"strings"
 )
 
 // section [service]
 
+// ServiceUnits returns the names of the systemd units of all services
+// available on the system, which are listed in the service section of the
+// note definition
+func ServiceUnits(ini *txtparser.INIFile) []string {
+	units := []string{}
+	for _, param := range ini.AllValues {
+		if param.Section != INISectionService {
+			continue
+		}
+		serviceKey := param.Key
+		keyFields := strings.Split(param.Key, ":")
+		if len(keyFields) == 2 {
+			serviceKey = keyFields[1]
+		}
+		if service, ok := system.LookupServiceName(serviceKey); ok {
+			units = append(units, service)
+		}
+	}
+	return units
+}
+
 // GetServiceVal initialise the systemd service structure with the current
 // system settings
 func GetServiceVal(key string) string {
@@ -0,0 +1,135 @@
+package note
+
+import (
+	"github.com/SUSE/saptune/system"
+	"github.com/SUSE/saptune/txtparser"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// section [swap]
+// only checked, but not applied
+
+// GetSwapVal initialise the swap structure with the current system settings
+func GetSwapVal(key string) string {
+	devs, err := system.GetSwapDevices()
+	if err != nil {
+		system.WarningLog("failed to read the swap devices - %v", err)
+		return "NA"
+	}
+	switch swapKey(key) {
+	case "size":
+		return strconv.FormatUint(system.GetSwapSizeMB(devs), 10)
+	case "type":
+		if len(devs) == 0 {
+			return "none"
+		}
+		return strings.Join(system.GetSwapTypes(devs), ",")
+	case "priority":
+		if len(devs) == 0 {
+			return "none"
+		}
+		prios := []string{}
+		for _, prio := range system.GetSwapPriorities(devs) {
+			prios = append(prios, strconv.Itoa(prio))
+		}
+		return strings.Join(prios, ",")
+	}
+	system.WarningLog("'%s' is not a supported key of the [swap] section, skipping", key)
+	return "NA"
+}
+
+// OptSwapVal returns the expected value of the swap setting.
+// If the current value fulfills the rule of the configuration file, the
+// current value is returned, otherwise the value required by the rule
+func OptSwapVal(operator txtparser.Operator, key, actval, cfgval string) string {
+	cfgval = strings.Join(strings.Fields(cfgval), " ")
+	if actval == "NA" || cfgval == "" {
+		return cfgval
+	}
+	switch swapKey(key) {
+	case "size":
+		memMB := system.GetMainMemSizeMB()
+		req, err := system.SwapSizeRequired(cfgval, memMB)
+		if err != nil {
+			system.WarningLog("%v", err)
+			return cfgval
+		}
+		act, _ := strconv.ParseInt(actval, 10, 64)
+		if cmpOperator(operator, act, int64(req)) {
+			return actval
+		}
+		system.WarningLog("swap size of %sMB does not fulfill the rule '%s %s' for %dMB main memory (%dMB)", actval, operator, cfgval, memMB, req)
+		return strconv.FormatUint(req, 10)
+	case "type":
+		types := strings.FieldsFunc(cfgval, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+		for _, typ := range types {
+			if !system.IsValidSwapType(typ) {
+				system.WarningLog("wrong swap device type '%s', valid types are 'partition', 'file', 'zram' and 'none'", typ)
+			}
+		}
+		for _, act := range strings.Split(actval, ",") {
+			if !system.IsValidSwapType(act) || !containsStr(types, act) {
+				return strings.Join(types, ",")
+			}
+		}
+		return actval
+	case "priority":
+		exp, err := strconv.ParseInt(cfgval, 10, 64)
+		if err != nil {
+			system.WarningLog("wrong swap priority '%s'", cfgval)
+			return cfgval
+		}
+		if actval == "none" {
+			return cfgval
+		}
+		for _, prio := range strings.Split(actval, ",") {
+			act, _ := strconv.ParseInt(prio, 10, 64)
+			if !cmpOperator(operator, act, exp) {
+				return cfgval
+			}
+		}
+		return actval
+	}
+	return cfgval
+}
+
+// SetSwapVal nothing to do, only checking for 'verify'
+func SetSwapVal(value string) error {
+	// nothing to do, only checking for 'verify'
+	return nil
+}
+
+// swapKey returns the key without the section prefix 'swap:'
+func swapKey(key string) string {
+	return strings.TrimPrefix(key, "swap:")
+}
+
+// cmpOperator checks, if the actual value fulfills the expected value
+// regarding the operator
+func cmpOperator(operator txtparser.Operator, act, exp int64) bool {
+	switch operator {
+	case txtparser.OperatorLessThan:
+		return act < exp
+	case txtparser.OperatorLessThanEqual:
+		return act <= exp
+	case txtparser.OperatorMoreThan:
+		return act > exp
+	case txtparser.OperatorMoreThanEqual:
+		return act >= exp
+	}
+	return act == exp
+}
+
+// containsStr checks, if the list contains the string
+func containsStr(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
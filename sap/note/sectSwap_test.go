@@ -0,0 +1,49 @@
+package note
+
+import (
+	"github.com/SUSE/saptune/txtparser"
+	"testing"
+)
+
+func TestGetSwapVal(t *testing.T) {
+	if val := GetSwapVal("swap:unknown"); val != "NA" {
+		t.Error(val)
+	}
+	for _, key := range []string{"swap:size", "swap:type", "swap:priority"} {
+		val := GetSwapVal(key)
+		if val == "" || val == "NA" {
+			t.Errorf("%s: '%s'", key, val)
+		}
+		t.Logf("%s: '%s'\n", key, val)
+	}
+}
+
+func TestOptSwapVal(t *testing.T) {
+	tests := []struct {
+		operator                 txtparser.Operator
+		key, actval, cfgval, exp string
+	}{
+		{">=", "swap:size", "4096", "*:2G", "4096"},
+		{">=", "swap:size", "1024", "*:2G", "2048"},
+		{"<", "swap:size", "4096", "2G", "2048"},
+		{"=", "swap:size", "2048", "2G", "2048"},
+		{"=", "swap:size", "NA", "2G", "2G"},
+		{"=", "swap:size", "2048", "many", "many"},
+		{"=", "swap:type", "partition", "partition,\tzram", "partition"},
+		{"=", "swap:type", "file,partition", "partition,zram", "partition,zram"},
+		{"=", "swap:type", "none", "partition", "partition"},
+		{"=", "swap:type", "none", "none", "none"},
+		{">=", "swap:priority", "-2,10", "-2", "-2,10"},
+		{">", "swap:priority", "-2,10", "-2", "-2"},
+		{"=", "swap:priority", "none", "10", "10"},
+		{"=", "swap:priority", "10", "high", "high"},
+	}
+	for _, tst := range tests {
+		if val := OptSwapVal(tst.operator, tst.key, tst.actval, tst.cfgval); val != tst.exp {
+			t.Errorf("%s %s %s (actual %s) - expected: '%s', got: '%s'", tst.key, tst.operator, tst.cfgval, tst.actval, tst.exp, val)
+		}
+	}
+	if err := SetSwapVal("2048"); err != nil {
+		t.Error(err)
+	}
+}
@@ -40,6 +40,11 @@ func SetSysVal(key, value string) error {
 	if len(keyFields) > 1 {
 		syskey = keyFields[1]
 	}
+	if _, _, ok := system.ModuleParameter(syskey); ok {
+		// read-only module parameters may only get active after
+		// the next load of the module
+		return system.SetModuleParameter(key, syskey, value)
+	}
 	err := system.SetSysString(syskey, value)
 	return err
 }
@@ -58,3 +58,56 @@ func OptSysctlVal(operator txtparser.Operator, key, actval, cfgval string) strin
 
 	return strings.TrimSpace(allFieldsS)
 }
+
+// syncSysctlDropIn keeps the saptune sysctl drop-in file in sync with the
+// sysctl values applied by saptune.
+// If no applied Note is changing the parameter any longer (no parameter
+// state file, e.g. after the revert of the last Note), the parameter is
+// removed from the drop-in file, otherwise the applied value is written.
+// 'ckey' is the parameter really set, which differs from 'key' during the
+// revert of the vm.dirty parameters (see getCounterPart)
+func syncSysctlDropIn(key, ckey, value string) error {
+	if system.SysctlDropIn() == "" {
+		return nil
+	}
+	if IsLastNoteOfParameter(key) || value == "" || value == "PNA" {
+		return system.RemoveSysctlDropInValues(key, ckey)
+	}
+	if ckey != key {
+		if err := system.RemoveSysctlDropInValues(key); err != nil {
+			return err
+		}
+	}
+	return system.SetSysctlDropInValue(ckey, value, parameterStartValue(key))
+}
+
+// WriteSysctlDropIn writes the applied values of the sysctl parameters of
+// the given applied Notes to the saptune sysctl drop-in file.
+// Used, if the drop-in file is enabled while Notes are already applied
+func WriteSysctlDropIn(noteIDs []string) error {
+	for _, noteID := range noteIDs {
+		ini, err := txtparser.GetSectionInfo("rosi", noteID, false)
+		if err != nil {
+			continue
+		}
+		for _, param := range ini.AllValues {
+			if param.Section != INISectionSysctl {
+				continue
+			}
+			// the last entry of the parameter state file is the
+			// applied value
+			pEntries := GetSavedParameterNotes(param.Key)
+			if len(pEntries.AllNotes) < 2 {
+				continue
+			}
+			value := pEntries.AllNotes[len(pEntries.AllNotes)-1].Value
+			if value == "" || value == "PNA" {
+				continue
+			}
+			if err := system.SetSysctlDropInValue(param.Key, value, pEntries.AllNotes[0].Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package note
 
 import (
+	"github.com/SUSE/saptune/system"
 	"github.com/SUSE/saptune/txtparser"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -68,3 +71,113 @@ func TestOptSysctlVal(t *testing.T) {
 		t.Error(val)
 	}
 }
+
+func TestSyncSysctlDropIn(t *testing.T) {
+	key := "TEST_SYSCTL_DROPIN"
+	cpKey := "TEST_SYSCTL_DROPIN_COUNTERPART"
+	oldLocation := system.SysctlDropInLocation()
+	defer func() {
+		system.SetSysctlDropIn(oldLocation)
+		system.CleanUpSysctlDropIn()
+		CleanUpParamFile(key)
+		_, _ = txtparser.GetSectionInfo("sns", "4711dropin", true)
+	}()
+	// disabled, nothing to do
+	system.SetSysctlDropIn("no")
+	if err := syncSysctlDropIn(key, key, "10"); err != nil {
+		t.Error(err)
+	}
+	system.SetSysctlDropIn("run")
+	dropIn := system.SysctlDropIn()
+	if _, err := os.Stat(dropIn); !os.IsNotExist(err) {
+		t.Errorf("drop-in file '%s' written, but disabled", dropIn)
+	}
+
+	// no parameter state file, no applied note changes the parameter
+	if err := syncSysctlDropIn(key, key, "10"); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(dropIn); !os.IsNotExist(err) {
+		t.Errorf("drop-in file '%s' written, but parameter not applied", dropIn)
+	}
+
+	// apply
+	CreateParameterStartValues(key, "60")
+	AddParameterNoteValues(key, "10", "4711dropin", "add")
+	if err := syncSysctlDropIn(key, key, "10"); err != nil {
+		t.Error(err)
+	}
+	content, _ := os.ReadFile(dropIn)
+	if !strings.Contains(string(content), "\n"+key+" = 10\n") {
+		t.Errorf("missing value in '%s'", string(content))
+	}
+	// revert of a vm.dirty parameter, counterpart is set
+	if err := syncSysctlDropIn(key, cpKey, "5"); err != nil {
+		t.Error(err)
+	}
+	content, _ = os.ReadFile(dropIn)
+	if strings.Contains(string(content), "\n"+key+" = ") || !strings.Contains(string(content), "\n"+cpKey+" = 5\n") {
+		t.Errorf("wrong content '%s'", string(content))
+	}
+
+	// enable drop-in file with already applied notes
+	system.CleanUpSysctlDropIn()
+	ini := txtparser.ParseINI("[sysctl]\n" + key + " = 10\n")
+	if err := txtparser.StoreSectionInfo(ini, "section", "4711dropin", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSysctlDropIn([]string{"4711dropin", "not_applied"}); err != nil {
+		t.Error(err)
+	}
+	content, _ = os.ReadFile(dropIn)
+	if !strings.Contains(string(content), "\n"+key+" = 10\n") {
+		t.Errorf("missing value in '%s'", string(content))
+	}
+
+	// revert of the last note
+	_, _ = RevertParameter(key, "4711dropin")
+	if err := syncSysctlDropIn(key, key, "60"); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(dropIn); !os.IsNotExist(err) {
+		t.Errorf("drop-in file '%s' not removed", dropIn)
+	}
+
+	// drop-in file survived a reboot, systemd-sysctl has already set
+	// the tuned value, the recorded start value is used
+	if err := system.SetSysctlDropInValue(key, "10", "60"); err != nil {
+		t.Error(err)
+	}
+	vend := INISettings{ID: "4711dropin", SysctlParams: map[string]string{key: "10"}, ValuesToApply: map[string]string{}}
+	vend.createParamSavedStates(key, "")
+	if start := parameterStartValue(key); start != "60" {
+		t.Errorf("expected start value '60', got '%s'", start)
+	}
+	// stale key of a note, which is no longer applied, is not written
+	// during the rebuild of the drop-in file
+	staleKey := "TEST_SYSCTL_DROPIN_STALE"
+	if err := system.SetSysctlDropInValue(staleKey, "20", "40"); err != nil {
+		t.Error(err)
+	}
+	AddParameterNoteValues(key, "10", "4711dropin", "add")
+	if err := system.RemoveSysctlDropIn(); err != nil {
+		t.Error(err)
+	}
+	if err := WriteSysctlDropIn([]string{"4711dropin"}); err != nil {
+		t.Error(err)
+	}
+	content, _ = os.ReadFile(dropIn)
+	if strings.Contains(string(content), staleKey) || !strings.Contains(string(content), "# start: "+key+" = 60\n"+key+" = 10\n") {
+		t.Errorf("wrong content '%s'", string(content))
+	}
+	_, _ = RevertParameter(key, "4711dropin")
+	CleanUpParamFile(key)
+
+	// the value of the drop-in file was not set during boot, the
+	// recorded start value is stale, the system value is used
+	vend.SysctlParams[key] = "30"
+	vend.createParamSavedStates(key, "")
+	if start := parameterStartValue(key); start != "30" {
+		t.Errorf("expected start value '30', got '%s'", start)
+	}
+}
@@ -0,0 +1,119 @@
+package note
+
+import (
+	"fmt"
+	"github.com/SUSE/saptune/system"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// section [systemd]
+// Manipulate the systemd manager defaults of system.conf
+
+// systemdDropIn returns the name of the saptune drop-in file of a systemd
+// manager default
+func systemdDropIn(key string) string {
+	return fmt.Sprintf("saptune-%s.conf", key)
+}
+
+// GetSystemdVal initialise the systemd manager structure with the current
+// system settings
+func GetSystemdVal(key string) string {
+	if !system.IsManagerKey.MatchString(key) {
+		system.WarningLog("'%s' is not a supported systemd manager default, skipping", key)
+		return "NA"
+	}
+	return system.GetManagerValue(key)
+}
+
+// OptSystemdVal returns the value from the configuration file.
+// Invalid values are kept, so that verify can report them. They will be
+// skipped during apply
+func OptSystemdVal(key, cfgval string) string {
+	val := strings.Join(strings.Fields(cfgval), " ")
+	if !system.IsValidManagerValue(key, val) {
+		system.WarningLog("wrong value '%s' for systemd manager default '%s'", cfgval, key)
+	}
+	return val
+}
+
+// SetSystemdVal applies the settings to the system
+// The value is written to the saptune drop-in file of the key in
+// /etc/systemd/system.conf.d. During revert of the last Note using the key
+// the drop-in file is removed, which restores the former manager default
+func SetSystemdVal(key, value string, revert bool) error {
+	if !system.IsManagerKey.MatchString(key) {
+		return nil
+	}
+	dropIn := path.Join(system.SystemdConfDir, systemdDropIn(key))
+	if revert && IsLastNoteOfParameter(key) {
+		// revert - remove systemd drop-in file
+		if err := os.Remove(dropIn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return system.SystemctlDaemonReload()
+	}
+	if value == "" || value == "NA" {
+		return nil
+	}
+	if !system.IsValidManagerValue(key, value) {
+		system.WarningLog("wrong value '%s' for systemd manager default '%s', skipping", value, key)
+		return nil
+	}
+	// revert with value from another former applied note
+	// or
+	// apply - Prepare systemd drop-in file
+	if err := os.MkdirAll(system.SystemdConfDir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dropIn, []byte(fmt.Sprintf("[Manager]\n%s=%s\n", key, value)), 0644); err != nil {
+		return err
+	}
+	// the manager defaults are re-read during daemon-reload and
+	// used for units started afterwards
+	return system.SystemctlDaemonReload()
+}
+
+// chkLimitsManager checks, if the value of a [limits] entry is undermined
+// for services by a lower systemd manager default, as the limits of
+// limits.conf only apply to login sessions. If so, the manager default is
+// added to the info
+func chkLimitsManager(limit, info string) string {
+	// dom=[0], type=[1], item=[2], value=[3]
+	lim := strings.Fields(limit)
+	if len(lim) < 4 || lim[3] == "NA" || lim[3] == "" {
+		return info
+	}
+	soft, hard, err := system.ManagerLimit(lim[2])
+	if err != nil {
+		system.DebugLog("chkLimitsManager - %v", err)
+		return info
+	}
+	val := int64(math.MaxInt64)
+	if lim[3] != "unlimited" && lim[3] != "infinity" && lim[3] != "-1" {
+		ival, err := strconv.ParseInt(lim[3], 10, 64)
+		if err != nil {
+			return info
+		}
+		val = ival
+	}
+	// '-' sets soft and hard limit
+	mgr := hard
+	if lim[1] == "soft" || lim[1] == "-" {
+		mgr = soft
+	}
+	if val <= mgr {
+		return info
+	}
+	key := "DefaultLimit" + strings.ToUpper(lim[2])
+	mgrVal := system.GetManagerValue(key)
+	system.WarningLog("limit '%s' is undermined for services by the lower systemd manager default %s=%s", limit, key, mgrVal)
+	inf := "manager:" + key + "=" + mgrVal
+	if info != "" {
+		return info + "§" + inf
+	}
+	return inf
+}
@@ -0,0 +1,69 @@
+package note
+
+import (
+	"github.com/SUSE/saptune/system"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestGetSystemdVal(t *testing.T) {
+	if val := GetSystemdVal("UnknownKey"); val != "NA" {
+		t.Error(val)
+	}
+	val := GetSystemdVal("DefaultTasksMax")
+	if val == "" {
+		t.Error(val)
+	}
+	t.Logf("DefaultTasksMax: '%s'\n", val)
+}
+
+func TestOptSystemdVal(t *testing.T) {
+	if val := OptSystemdVal("DefaultTimeoutStopSec", " 1min  30s "); val != "1min 30s" {
+		t.Error(val)
+	}
+	// invalid values are kept for verify
+	if val := OptSystemdVal("DefaultLimitNOFILE", "many"); val != "many" {
+		t.Error(val)
+	}
+	if name := systemdDropIn("DefaultTasksMax"); name != "saptune-DefaultTasksMax.conf" {
+		t.Error(name)
+	}
+}
+
+func TestSetSystemdVal(t *testing.T) {
+	oldConfDir := system.SystemdConfDir
+	defer func() { system.SystemdConfDir = oldConfDir }()
+	system.SystemdConfDir = "/tmp/saptune_test_systemd/system.conf.d"
+	defer os.RemoveAll(path.Dir(system.SystemdConfDir))
+	dropIn := path.Join(system.SystemdConfDir, "saptune-DefaultLimitNOFILE.conf")
+
+	// invalid values and unknown keys are skipped
+	if err := SetSystemdVal("DefaultLimitNOFILE", "many", false); err != nil {
+		t.Error(err)
+	}
+	if err := SetSystemdVal("UnknownKey", "1", false); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(dropIn); err == nil {
+		t.Errorf("drop-in file '%s' written for invalid value", dropIn)
+	}
+	// daemon-reload may fail without a running systemd
+	_ = SetSystemdVal("DefaultLimitNOFILE", "1048576", false)
+	if !system.CheckForPattern(dropIn, "DefaultLimitNOFILE=1048576") {
+		t.Errorf("wrong content of file '%s'", dropIn)
+	}
+	_ = SetSystemdVal("DefaultLimitNOFILE", "1024:524288", true)
+	if _, err := os.Stat(dropIn); err == nil {
+		t.Errorf("drop-in file '%s' not removed", dropIn)
+	}
+}
+
+func TestChkLimitsManager(t *testing.T) {
+	if info := chkLimitsManager("@sapsys soft nofile NA", "limit_exceeded"); info != "limit_exceeded" {
+		t.Error(info)
+	}
+	if info := chkLimitsManager("@sapsys soft", ""); info != "" {
+		t.Error(info)
+	}
+}
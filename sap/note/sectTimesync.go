@@ -0,0 +1,93 @@
+package note
+
+import (
+	"github.com/SUSE/saptune/system"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// section [timesync]
+// only checked, but not applied
+
+// GetTimesyncVal initialise the time synchronisation structure with the
+// current system settings
+func GetTimesyncVal(key string) string {
+	switch timesyncKey(key) {
+	case "daemon":
+		return system.ActiveTimeDaemon()
+	case "synchronized":
+		return system.TimeSynchronized()
+	case "max_offset":
+		offset, err := system.TimeOffsetMS()
+		if err != nil {
+			system.InfoLog("%v", err)
+			return "NA"
+		}
+		return strconv.FormatFloat(offset, 'f', 3, 64) + "ms"
+	case "min_sources":
+		sources, err := system.TimeSources()
+		if err != nil {
+			system.InfoLog("%v", err)
+			return "NA"
+		}
+		return strconv.Itoa(sources)
+	}
+	system.WarningLog("'%s' is not a supported key of the [timesync] section, skipping", key)
+	return "NA"
+}
+
+// OptTimesyncVal returns the expected value of the time synchronisation
+// setting.
+// If the current value fulfills the value of the configuration file, the
+// current value is returned, otherwise the value of the configuration file
+func OptTimesyncVal(key, actval, cfgval string) string {
+	cfgval = strings.Join(strings.Fields(cfgval), " ")
+	if actval == "NA" || cfgval == "" {
+		return cfgval
+	}
+	switch timesyncKey(key) {
+	case "daemon":
+		daemons := strings.FieldsFunc(cfgval, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+		if actval != "none" && (containsStr(daemons, "any") || containsStr(daemons, actval)) {
+			return actval
+		}
+		return strings.Join(daemons, ",")
+	case "max_offset":
+		max, err := system.TimeOffsetToMS(cfgval)
+		if err != nil {
+			system.WarningLog("%v", err)
+			return cfgval
+		}
+		offset, err := system.TimeOffsetToMS(actval)
+		if err == nil && offset <= max {
+			return actval
+		}
+		system.WarningLog("offset '%s' of the system clock exceeds the threshold '%s'", actval, cfgval)
+		return cfgval
+	case "min_sources":
+		min, err := strconv.Atoi(cfgval)
+		if err != nil {
+			system.WarningLog("wrong number of time sources '%s'", cfgval)
+			return cfgval
+		}
+		if sources, _ := strconv.Atoi(actval); sources >= min {
+			return actval
+		}
+		return cfgval
+	}
+	return cfgval
+}
+
+// SetTimesyncVal nothing to do, only checking for 'verify'
+func SetTimesyncVal(value string) error {
+	// nothing to do, only checking for 'verify'
+	return nil
+}
+
+// timesyncKey returns the key without the section prefix 'timesync:'
+func timesyncKey(key string) string {
+	return strings.TrimPrefix(key, "timesync:")
+}
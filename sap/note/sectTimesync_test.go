@@ -0,0 +1,45 @@
+package note
+
+import (
+	"testing"
+)
+
+func TestGetTimesyncVal(t *testing.T) {
+	if val := GetTimesyncVal("timesync:unknown"); val != "NA" {
+		t.Error(val)
+	}
+	for _, key := range []string{"timesync:daemon", "timesync:synchronized", "timesync:max_offset", "timesync:min_sources"} {
+		val := GetTimesyncVal(key)
+		if val == "" {
+			t.Errorf("%s: '%s'", key, val)
+		}
+		t.Logf("%s: '%s'\n", key, val)
+	}
+}
+
+func TestOptTimesyncVal(t *testing.T) {
+	tests := []struct {
+		key, actval, cfgval, exp string
+	}{
+		{"timesync:daemon", "chronyd", "chronyd, systemd-timesyncd", "chronyd"},
+		{"timesync:daemon", "ntpd", "chronyd,\tsystemd-timesyncd", "chronyd,systemd-timesyncd"},
+		{"timesync:daemon", "ntpd", "any", "ntpd"},
+		{"timesync:daemon", "none", "any", "any"},
+		{"timesync:synchronized", "no", "yes", "yes"},
+		{"timesync:max_offset", "0.123ms", "100ms", "0.123ms"},
+		{"timesync:max_offset", "150.000ms", "0.1s", "0.1s"},
+		{"timesync:max_offset", "NA", "100ms", "100ms"},
+		{"timesync:max_offset", "0.123ms", "1min", "1min"},
+		{"timesync:min_sources", "3", "2", "3"},
+		{"timesync:min_sources", "1", "2", "2"},
+		{"timesync:min_sources", "3", "many", "many"},
+	}
+	for _, tst := range tests {
+		if val := OptTimesyncVal(tst.key, tst.actval, tst.cfgval); val != tst.exp {
+			t.Errorf("%s %s (actual %s) - expected: '%s', got: '%s'", tst.key, tst.cfgval, tst.actval, tst.exp, val)
+		}
+	}
+	if err := SetTimesyncVal("yes"); err != nil {
+		t.Error(err)
+	}
+}
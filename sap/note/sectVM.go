@@ -1,7 +1,9 @@
 package note
 
 import (
+	"fmt"
 	"github.com/SUSE/saptune/system"
+	"os"
 	"strconv"
 	"strings"
 )
@@ -9,6 +11,27 @@ import (
 // section [vm]
 // Manipulate /sys/kernel/mm switches.
 
+// vmKnob describes an additional key of the [vm] section
+// If choice is set, the /sys/ file offers the valid values like
+// 'always defer [madvise] never', otherwise the file contains a number
+// between min and max (max < 0 - no upper limit)
+type vmKnob struct {
+	choice bool
+	min    int
+	max    int
+}
+
+// vmKnobs contains the additional keys of the [vm] section. The related
+// /sys/ keys are defined in system.SysVMKeys
+var vmKnobs = map[string]vmKnob{
+	"THP_DEFRAG":                   {choice: true},
+	"THP_KHUGEPAGED_DEFRAG":        {min: 0, max: 1},
+	"THP_KHUGEPAGED_MAX_PTES_NONE": {min: 0, max: -1},
+	"THP_USE_ZERO_PAGE":            {min: 0, max: 1},
+	"KSM_PAGES_TO_SCAN":            {min: 0, max: -1},
+	"KSM_SLEEP_MILLISECS":          {min: 0, max: -1},
+}
+
 // GetVMVal initialise the memory management structure with the current
 // system settings
 func GetVMVal(key string) (string, string) {
@@ -20,6 +43,14 @@ func GetVMVal(key string) (string, string) {
 	case "KSM":
 		ksmval, _ := system.GetSysInt(system.SysKSMRun)
 		val = strconv.Itoa(ksmval)
+	default:
+		if knob, ok := vmKnobs[key]; ok {
+			if knob.choice {
+				val, _ = system.GetSysChoice(system.SysVMKeys[key])
+			} else {
+				val, _ = system.GetSysString(system.SysVMKeys[key])
+			}
+		}
 	}
 	return val, info
 }
@@ -39,6 +70,11 @@ func OptVMVal(key, cfgval string) string {
 			system.WarningLog("wrong selection for KSM. Now set to default value '0'")
 			val = "0"
 		}
+	default:
+		// the additional keys keep the expected value, even if it is
+		// not supported by the kernel, so that verify can report it
+		// (see chkVMVal)
+		val = strings.TrimSpace(val)
 	}
 	return val
 }
@@ -52,6 +88,82 @@ func SetVMVal(key, value string) error {
 	case "KSM":
 		ksmval, _ := strconv.Atoi(value)
 		err = system.SetSysInt(system.SysKSMRun, ksmval)
+	default:
+		if _, ok := vmKnobs[key]; !ok {
+			return err
+		}
+		if valid, choices := isValidVMVal(key, value); !valid && value != "PNA" {
+			system.WarningLog("value '%s' for '%s' is not supported by the kernel (valid values: %s), skipping.", value, key, choices)
+			return err
+		}
+		err = system.SetSysString(system.SysVMKeys[key], value)
 	}
 	return err
 }
+
+// chkVMVal checks, if the expected value of an additional [vm] key is
+// offered by the kernel. If not, the valid values are added to the info
+func chkVMVal(key, value, info string) string {
+	if _, ok := vmKnobs[key]; !ok {
+		return info
+	}
+	valid, choices := isValidVMVal(key, strings.ToLower(strings.TrimSpace(value)))
+	if valid || choices == "" {
+		return info
+	}
+	system.WarningLog("value '%s' for '%s' is not supported by the kernel, valid values are: %s", value, key, choices)
+	inf := "choices:" + choices
+	if info != "" {
+		return info + "§" + inf
+	}
+	return inf
+}
+
+// isValidVMVal checks, if the value of an additional [vm] key is offered by
+// the kernel and returns the valid values as string.
+// If the /sys/ key is not available, no valid values are returned.
+func isValidVMVal(key, value string) (bool, string) {
+	knob := vmKnobs[key]
+	sysKey := system.SysVMKeys[key]
+	if knob.choice {
+		choices, err := system.GetSysChoices(sysKey)
+		if err != nil {
+			return false, ""
+		}
+		for _, choice := range choices {
+			if choice == value {
+				return true, ""
+			}
+		}
+		return false, strings.Join(choices, " ")
+	}
+	if _, err := os.Stat("/sys/" + strings.Replace(sysKey, ".", "/", -1)); err != nil {
+		return false, ""
+	}
+	max := knob.max
+	if key == "THP_KHUGEPAGED_MAX_PTES_NONE" {
+		max = maxPtesNone()
+	}
+	valid := fmt.Sprintf(">= %d", knob.min)
+	if max == knob.min+1 {
+		valid = fmt.Sprintf("%d %d", knob.min, max)
+	} else if max >= 0 {
+		valid = fmt.Sprintf("%d - %d", knob.min, max)
+	}
+	ival, err := strconv.Atoi(value)
+	if err != nil || ival < knob.min || (max >= 0 && ival > max) {
+		return false, valid
+	}
+	return true, ""
+}
+
+// maxPtesNone returns the maximal value of khugepaged/max_ptes_none, which
+// is the number of pages of a transparent huge page - 1
+// (hpage_pmd_size / page size - 1)
+func maxPtesNone() int {
+	pmdSize, err := system.GetSysInt("kernel/mm/transparent_hugepage/hpage_pmd_size")
+	if err != nil || pmdSize <= 0 {
+		return -1
+	}
+	return pmdSize/os.Getpagesize() - 1
+}
@@ -1,6 +1,8 @@
 package note
 
 import (
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -80,3 +82,47 @@ func TestSetVMVal(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestVMKnobs(t *testing.T) {
+	if _, err := os.Stat("/sys/kernel/mm/transparent_hugepage/defrag"); err != nil {
+		t.Skip("transparent huge pages not supported by the kernel")
+	}
+	val, _ := GetVMVal("THP_DEFRAG")
+	if valid, choices := isValidVMVal("THP_DEFRAG", val); !valid {
+		t.Errorf("current value '%s' is not valid, valid values: '%s'", val, choices)
+	}
+	valid, choices := isValidVMVal("THP_DEFRAG", "hugo")
+	if valid || !strings.Contains(choices, "never") {
+		t.Errorf("'hugo' should not be valid, valid values: '%s'", choices)
+	}
+	if info := chkVMVal("THP_DEFRAG", "Hugo", ""); info != "choices:"+choices {
+		t.Errorf("unexpected info '%s'", info)
+	}
+	if info := chkVMVal("THP_DEFRAG", "never", "[sys] 'x'"); info != "[sys] 'x'" {
+		t.Errorf("unexpected info '%s'", info)
+	}
+	if val := OptVMVal("THP_DEFRAG", "Defer+Madvise "); val != "defer+madvise" {
+		t.Error(val)
+	}
+
+	valid, choices = isValidVMVal("THP_USE_ZERO_PAGE", "2")
+	if valid || choices != "0 1" {
+		t.Errorf("'2' should not be valid, valid values: '%s'", choices)
+	}
+	if valid, _ := isValidVMVal("THP_KHUGEPAGED_MAX_PTES_NONE", "0"); !valid {
+		t.Error("'0' should be valid for max_ptes_none")
+	}
+	if valid, choices := isValidVMVal("THP_KHUGEPAGED_MAX_PTES_NONE", "100000"); valid || !strings.HasPrefix(choices, "0 - ") {
+		t.Errorf("'100000' should not be valid, valid values: '%s'", choices)
+	}
+	if valid, choices := isValidVMVal("KSM_SLEEP_MILLISECS", "-5"); valid || choices != ">= 0" {
+		t.Errorf("'-5' should not be valid, valid values: '%s'", choices)
+	}
+	// invalid values are not set
+	if err := SetVMVal("THP_DEFRAG", "hugo"); err != nil {
+		t.Error(err)
+	}
+	if info := chkVMVal("UNKNOWN_PARAMETER", "hugo", ""); info != "" {
+		t.Errorf("unexpected info '%s'", info)
+	}
+}
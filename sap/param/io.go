@@ -6,19 +6,43 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // BlockDeviceQueue is the data structure for block devices
-// for schedulers, IO nr_request, read_ahead_kb and max_sectors_kb changes
+// for schedulers, IO nr_request, read_ahead_kb, max_sectors_kb and the
+// additional queue and I/O scheduler tunables changes
 type BlockDeviceQueue struct {
 	BlockDeviceSchedulers
 	BlockDeviceNrRequests
 	BlockDeviceReadAheadKB
 	BlockDeviceMaxSectorsKB
+	BlockDeviceTunables
 }
 
 var blkDev *system.BlockDev
 
+// blkDevMutex protects the lazy initialisation of 'blkDev', as notes are
+// initialised concurrently during the verification
+var blkDevMutex sync.Mutex
+
+// blockDevInventory returns the block device inventory, which is shared by
+// all block device parameter types (BlockDeviceSchedulers,
+// BlockDeviceNrRequests, BlockDeviceReadAheadKB, BlockDeviceMaxSectorsKB,
+// BlockDeviceTunables).
+// The inventory is read only once per saptune call and is only used for the
+// list of block devices and their available schedulers. The queue attributes
+// change during the saptune call (e.g. by apply), so they are read from the
+// system by system.BlockQueueValue
+func blockDevInventory() *system.BlockDev {
+	blkDevMutex.Lock()
+	defer blkDevMutex.Unlock()
+	if blkDev == nil || (len(blkDev.AllBlockDevs) == 0 && len(blkDev.BlockAttributes) == 0) {
+		blkDev, _ = system.GetBlockDeviceInfo()
+	}
+	return blkDev
+}
+
 // BlockDeviceSchedulers changes IO elevators on all IO devices
 type BlockDeviceSchedulers struct {
 	SchedulerChoice map[string]string
@@ -30,12 +54,9 @@ func (ioe BlockDeviceSchedulers) Inspect() (Parameter, error) {
 		// inspect needs to run only once per saptune call
 		return ioe, nil
 	}
-	if blkDev == nil || (len(blkDev.AllBlockDevs) == 0 && len(blkDev.BlockAttributes) == 0) {
-		blkDev, _ = system.GetBlockDeviceInfo()
-	}
 	newIOE := BlockDeviceSchedulers{SchedulerChoice: make(map[string]string)}
-	for _, entry := range blkDev.AllBlockDevs {
-		elev := blkDev.BlockAttributes[entry]["IO_SCHEDULER"]
+	for _, entry := range blockDevInventory().AllBlockDevs {
+		elev := system.BlockQueueValue(entry, "scheduler")
 		if elev != "" {
 			newIOE.SchedulerChoice[entry] = elev
 		} else {
@@ -99,12 +120,9 @@ func (ior BlockDeviceNrRequests) Inspect() (Parameter, error) {
 		// inspect needs to run only once per saptune call
 		return ior, nil
 	}
-	if blkDev == nil || (len(blkDev.AllBlockDevs) == 0 && len(blkDev.BlockAttributes) == 0) {
-		blkDev, _ = system.GetBlockDeviceInfo()
-	}
 	newIOR := BlockDeviceNrRequests{NrRequests: make(map[string]int)}
-	for _, entry := range blkDev.AllBlockDevs {
-		nrreq := blkDev.BlockAttributes[entry]["NRREQ"]
+	for _, entry := range blockDevInventory().AllBlockDevs {
+		nrreq := system.BlockQueueValue(entry, "nr_requests")
 		if nrreq != "" {
 			ival, _ := strconv.Atoi(nrreq)
 			if ival >= 0 {
@@ -169,12 +187,9 @@ func (rakb BlockDeviceReadAheadKB) Inspect() (Parameter, error) {
 		// inspect needs to run only once per saptune call
 		return rakb, nil
 	}
-	if blkDev == nil || (len(blkDev.AllBlockDevs) == 0 && len(blkDev.BlockAttributes) == 0) {
-		blkDev, _ = system.GetBlockDeviceInfo()
-	}
 	newRAKB := BlockDeviceReadAheadKB{ReadAheadKB: make(map[string]int)}
-	for _, entry := range blkDev.AllBlockDevs {
-		readahead := blkDev.BlockAttributes[entry]["READ_AHEAD_KB"]
+	for _, entry := range blockDevInventory().AllBlockDevs {
+		readahead := system.BlockQueueValue(entry, "read_ahead_kb")
 		if readahead != "" {
 			ival, _ := strconv.Atoi(readahead)
 			if ival >= 0 {
@@ -239,12 +254,9 @@ func (mskb BlockDeviceMaxSectorsKB) Inspect() (Parameter, error) {
 		// inspect needs to run only once per saptune call
 		return mskb, nil
 	}
-	if blkDev == nil || (len(blkDev.AllBlockDevs) == 0 && len(blkDev.BlockAttributes) == 0) {
-		blkDev, _ = system.GetBlockDeviceInfo()
-	}
 	newMSKB := BlockDeviceMaxSectorsKB{MaxSectorsKB: make(map[string]int)}
-	for _, entry := range blkDev.AllBlockDevs {
-		maxsector := blkDev.BlockAttributes[entry]["MAX_SECTORS_KB"]
+	for _, entry := range blockDevInventory().AllBlockDevs {
+		maxsector := system.BlockQueueValue(entry, "max_sectors_kb")
 		if maxsector != "" {
 			ival, _ := strconv.Atoi(maxsector)
 			if ival >= 0 {
@@ -287,6 +299,111 @@ func (mskb BlockDeviceMaxSectorsKB) Apply(blkdev interface{}) error {
 	return nil
 }
 
+// BlockDeviceTunables changes the additional queue and I/O scheduler
+// tunables (see system.BlockTunables) on all block devices
+type BlockDeviceTunables struct {
+	// tunable name -> block device -> value
+	Tunables map[string]map[string]string
+}
+
+// Inspect retrieves the current tunable values from the system
+// The tunables of an I/O scheduler, which is not active for a block device,
+// are reported as 'NA'
+func (iot BlockDeviceTunables) Inspect() (Parameter, error) {
+	if len(iot.Tunables) != 0 {
+		// inspect needs to run only once per saptune call
+		return iot, nil
+	}
+	blkDev := blockDevInventory()
+	newIOT := BlockDeviceTunables{Tunables: make(map[string]map[string]string)}
+	for _, name := range system.BlockTunableNames() {
+		newIOT.Tunables[name] = make(map[string]string)
+		for _, entry := range blkDev.AllBlockDevs {
+			// read live, the iosched tunables change with the
+			// scheduler
+			val := system.BlockQueueValue(entry, system.BlockTunables[name].File)
+			if val == "" {
+				val = "NA"
+			}
+			newIOT.Tunables[name][entry] = val
+		}
+	}
+	return newIOT, nil
+}
+
+// Optimise gets the expected tunable value from the configuration
+// parameter format is '<tunable> <block device> <value>'
+func (iot BlockDeviceTunables) Optimise(newTunable interface{}) (Parameter, error) {
+	fields := strings.Fields(newTunable.(string))
+	if len(fields) == 3 {
+		iot.SetTunable(fields[0], fields[1], fields[2])
+	}
+	return iot, nil
+}
+
+// SetTunable stores the value of a tunable of a block device, which should
+// be applied
+func (iot *BlockDeviceTunables) SetTunable(name, bdev, value string) {
+	if iot.Tunables == nil {
+		iot.Tunables = make(map[string]map[string]string)
+	}
+	if iot.Tunables[name] == nil {
+		iot.Tunables[name] = make(map[string]string)
+	}
+	iot.Tunables[name][bdev] = value
+}
+
+// Apply sets the new tunable value in the system
+// parameter format is '<tunable> <block device>'
+// A tunable of an I/O scheduler is only set, if the scheduler is active for
+// the block device. A failed write is returned, so that an atomic apply
+// rolls back the changed parameters.
+func (iot BlockDeviceTunables) Apply(tunable interface{}) error {
+	fields := strings.Fields(tunable.(string))
+	if len(fields) != 2 {
+		return nil
+	}
+	name := fields[0]
+	bdev := fields[1]
+	value := iot.Tunables[name][bdev]
+	if value == "" || value == "NA" || value == "PNA" {
+		return nil
+	}
+	file := system.BlockTunables[name].File
+	if sched := system.BlockTunables[name].Sched; sched != "" {
+		elev, _ := system.GetSysChoice(path.Join("block", bdev, "queue", "scheduler"))
+		if elev != sched {
+			system.InfoLog("skipping device '%s', '%s' is a tunable of the scheduler '%s', but the active scheduler is '%s'", bdev, file, sched, elev)
+			return nil
+		}
+	}
+	if err := system.SetSysString(system.BlockTunableKey(name, bdev), value); err != nil {
+		system.WarningLog("skipping device '%s', not valid for setting '%s' to '%v'", bdev, file, value)
+		return err
+	}
+	return nil
+}
+
+// ApplySchedTunables sets the tunables of the active I/O scheduler of a
+// block device again. Needed after a scheduler change, as the kernel
+// replaces the queue/iosched directory of the block device with the
+// default values of the new scheduler. Returns the first failed write
+func (iot BlockDeviceTunables) ApplySchedTunables(bdev string) error {
+	var err error
+	elev, _ := system.GetSysChoice(path.Join("block", bdev, "queue", "scheduler"))
+	for _, name := range system.BlockTunableNames() {
+		if system.BlockTunables[name].Sched != elev {
+			continue
+		}
+		if _, ok := iot.Tunables[name][bdev]; ok {
+			if aerr := iot.Apply(name + " " + bdev); aerr != nil && err == nil {
+				err = aerr
+			}
+		}
+	}
+	return err
+}
+
 // IsValidScheduler checks, if the scheduler value is supported by the system.
 // only used during optimize
 // During initialize, the scheduler is read from the system, so no check needed.
@@ -295,9 +412,7 @@ func (mskb BlockDeviceMaxSectorsKB) Apply(blkdev interface{}) error {
 // And a scheduler can only change during a system reboot
 // (single-queued -> multi-queued)
 func IsValidScheduler(blockdev, scheduler string) bool {
-	if blkDev == nil || (len(blkDev.AllBlockDevs) == 0 && len(blkDev.BlockAttributes) == 0) {
-		blkDev, _ = system.GetBlockDeviceInfo()
-	}
+	blkDev := blockDevInventory()
 	val := blkDev.BlockAttributes[blockdev]["VALID_SCHEDS"]
 	actsched := fmt.Sprintf("[%s]", scheduler)
 	if val != "" {
@@ -339,3 +454,35 @@ func checkIfBlockIsValid(blockdev string, testString string, file string) bool {
 	}
 	return false
 }
+
+// IsValidBlockTunable checks, if the value of an additional queue or I/O
+// scheduler tunable is supported for the block device. sched is the I/O
+// scheduler, which will be used for the block device. If empty, the current
+// scheduler of the block device is used.
+// Returns the reason, if the value is not valid
+func IsValidBlockTunable(blockdev, name, value, sched string) (bool, string) {
+	tunable, ok := system.BlockTunables[name]
+	if !ok {
+		return false, "unknown tunable"
+	}
+	ival, err := strconv.Atoi(value)
+	if err != nil || ival < tunable.Min || (tunable.Max != 0 && ival > tunable.Max) {
+		valid := fmt.Sprintf(">= %d", tunable.Min)
+		if tunable.Max != 0 {
+			valid = fmt.Sprintf("%d - %d", tunable.Min, tunable.Max)
+		}
+		system.InfoLog("'%s' is not a valid value for '%s' of device '%s' (valid: %s), skipping.", value, tunable.File, blockdev, valid)
+		return false, fmt.Sprintf("invalid value, valid: %s", valid)
+	}
+	if tunable.Sched == "" {
+		return true, ""
+	}
+	if sched == "" || sched == "NA" {
+		sched = system.BlockQueueValue(blockdev, "scheduler")
+	}
+	if sched != tunable.Sched {
+		system.InfoLog("'%s' is a tunable of the scheduler '%s', but device '%s' uses the scheduler '%s', skipping.", tunable.File, tunable.Sched, blockdev, sched)
+		return false, fmt.Sprintf("needs scheduler '%s', used scheduler '%s'", tunable.Sched, sched)
+	}
+	return true, ""
+}
@@ -492,3 +492,86 @@ func TestIsValidforReadAheadKB(t *testing.T) {
 }
 
 // Apply für beide
+
+func TestBlockDeviceTunables(t *testing.T) {
+	inspected, err := BlockDeviceTunables{}.Inspect()
+	if err != nil {
+		t.Error(err, inspected)
+	}
+	t.Logf("inspected - '%+v'\n", inspected)
+	for _, name := range system.BlockTunableNames() {
+		if _, ok := inspected.(BlockDeviceTunables).Tunables[name]; !ok {
+			t.Errorf("missing tunable '%s' in '%+v'", name, inspected)
+		}
+	}
+
+	optimised, err := inspected.Optimise("READ_EXPIRE hugo 250")
+	if err != nil {
+		t.Error(err)
+	}
+	if val := optimised.(BlockDeviceTunables).Tunables["READ_EXPIRE"]["hugo"]; val != "250" {
+		t.Errorf("expected '250', got '%s'", val)
+	}
+	// wrong syntax is ignored
+	optimised, _ = optimised.Optimise("READ_EXPIRE egon")
+	if _, ok := optimised.(BlockDeviceTunables).Tunables["READ_EXPIRE"]["egon"]; ok {
+		t.Error("unexpected value for block device 'egon'")
+	}
+
+	// tunables of not available devices are skipped
+	if err := optimised.Apply("READ_EXPIRE hugo"); err != nil {
+		t.Error(err)
+	}
+
+	tunables := BlockDeviceTunables{}
+	tunables.SetTunable("NOMERGES", "sdz", "NA")
+	if err := tunables.Apply("NOMERGES sdz"); err != nil {
+		t.Error(err)
+	}
+	if err := tunables.ApplySchedTunables("sdz"); err != nil {
+		t.Error(err)
+	}
+
+	// a failed write is returned (needed for the rollback of an
+	// atomic apply) and the current value is read from the system
+	for _, bdev := range blockDevInventory().AllBlockDevs {
+		nomerges := system.BlockQueueValue(bdev, "nomerges")
+		if nomerges == "" {
+			continue
+		}
+		tunables.SetTunable("NOMERGES", bdev, "invalid")
+		if err := tunables.Apply("NOMERGES " + bdev); err == nil {
+			t.Errorf("expected an error for an invalid value of device '%s'", bdev)
+		}
+		live, _ := BlockDeviceTunables{}.Inspect()
+		if val := live.(BlockDeviceTunables).Tunables["NOMERGES"][bdev]; val != nomerges {
+			t.Errorf("expected '%s', got '%s'", nomerges, val)
+		}
+		break
+	}
+}
+
+func TestIsValidBlockTunable(t *testing.T) {
+	tests := []struct {
+		name, value, sched string
+		valid              bool
+	}{
+		{"RQ_AFFINITY", "2", "", true},
+		{"RQ_AFFINITY", "3", "", false},
+		{"NOMERGES", "-1", "", false},
+		{"ADD_RANDOM", "0", "", true},
+		{"ADD_RANDOM", "yes", "", false},
+		{"WBT_LAT_USEC", "-1", "", true},
+		{"WBT_LAT_USEC", "75000", "", true},
+		{"READ_EXPIRE", "250", "mq-deadline", true},
+		{"FIFO_BATCH", "16", "bfq", false},
+		{"SLICE_IDLE", "0", "bfq", true},
+		{"SLICE_IDLE", "0", "none", false},
+		{"HUGO", "0", "", false},
+	}
+	for _, tst := range tests {
+		if valid, reason := IsValidBlockTunable("sdz", tst.name, tst.value, tst.sched); valid != tst.valid {
+			t.Errorf("'%s=%s' (scheduler '%s'): expected '%v', got '%v' (%s)", tst.name, tst.value, tst.sched, tst.valid, valid, reason)
+		}
+	}
+}
@@ -50,13 +50,13 @@ func GetFlagVal(flag string) string {
 // returns a map of Flags (set/not set or value) and a slice containing the
 // remaining arguments
 // possible Flags - force, dryrun, help, version, show-non-compliant, format,
-// colorscheme, non-compliance-check
-// on command line - --force, --dry-run or --dryrun, --help, --version, --color-scheme, --format
+// colorscheme, non-compliance-check, atomic, to-pristine
+// on command line - --force, --dry-run or --dryrun, --help, --version, --color-scheme, --format, --atomic, --to-pristine
 // Some Flags (like 'format') can have a value (--format json or --format csv)
 func ParseCliArgs() ([]string, map[string]string) {
 	stArgs := []string{}
 	// supported flags
-	stFlags := map[string]string{"force": "false", "dryrun": "false", "help": "false", "version": "false", "show-non-compliant": "false", "format": "", "colorscheme": "", "non-compliance-check": "false", "notSupported": "", "force-color": "false", "fun": "false"}
+	stFlags := map[string]string{"force": "false", "dryrun": "false", "help": "false", "version": "false", "show-non-compliant": "false", "format": "", "colorscheme": "", "non-compliance-check": "false", "notSupported": "", "force-color": "false", "fun": "false", "atomic": "false", "to-pristine": "false"}
 	skip := false
 	for i, arg := range os.Args {
 		if skip {
@@ -129,6 +129,10 @@ func handleSimpleFlags(arg string, flags map[string]string) {
 		flags["force-color"] = "true"
 	case "--fun", "-fun":
 		flags["fun"] = "true"
+	case "--atomic", "-atomic":
+		flags["atomic"] = "true"
+	case "--to-pristine", "-to-pristine":
+		flags["to-pristine"] = "true"
 	default:
 		setUnsupportedFlag(arg, flags)
 	}
@@ -325,12 +329,12 @@ func chkCmdOpts(cmdLinePos map[string]int) bool {
 	ret := true
 	// check minimum of arguments for command options
 	// saptune realm cmd
-	if len(saptArgs) < 3 && (IsFlagSet("force") || IsFlagSet("dryrun") || IsFlagSet("colorscheme") || IsFlagSet("show-non-compliant")) {
+	if len(saptArgs) < 3 && (IsFlagSet("force") || IsFlagSet("dryrun") || IsFlagSet("colorscheme") || IsFlagSet("show-non-compliant") || IsFlagSet("atomic") || IsFlagSet("to-pristine")) {
 		// too few arguments for the active flags
-		DebugLog("chkCmdOpts failed - too few arguments for flags 'force' or 'dryrun' or 'colorscheme' or 'show-non-compliant'")
+		DebugLog("chkCmdOpts failed - too few arguments for flags 'force' or 'dryrun' or 'colorscheme' or 'show-non-compliant' or 'atomic' or 'to-pristine'")
 		return false
 	}
-	if len(os.Args) < cmdLinePos["cmdOpt"]+1 || (!IsFlagSet("force") && !IsFlagSet("dryrun") && !IsFlagSet("colorscheme") && !IsFlagSet("show-non-compliant") && !IsFlagSet("non-compliance-check")) {
+	if len(os.Args) < cmdLinePos["cmdOpt"]+1 || (!IsFlagSet("force") && !IsFlagSet("dryrun") && !IsFlagSet("colorscheme") && !IsFlagSet("show-non-compliant") && !IsFlagSet("non-compliance-check") && !IsFlagSet("atomic") && !IsFlagSet("to-pristine")) {
 		// no command options set or too few options
 		// and/or non of the flags set, which need further checks
 		// so let the 'old' default checks (in main and/or actions) set
@@ -350,6 +354,12 @@ func chkCmdOpts(cmdLinePos map[string]int) bool {
 		"chkVerifySyntax",
 		// saptune (service) status  [--non-compliance-check]
 		"chkServiceStatusSyntax",
+		// saptune note apply [--atomic] NOTEID
+		// saptune solution apply [--atomic] SOLUTIONNAME
+		"chkAtomicFlag",
+		// saptune note revert [--to-pristine] NOTEID
+		// saptune revert all [--to-pristine]
+		"chkPristineFlag",
 	}
 
 	for _, flag := range flagToCheck {
@@ -413,6 +423,18 @@ func checkFlag(cmdLinePos map[string]int, flagValue string) bool {
 		isWrongPosition := stArgs[cmdLinePos["cmdOpt"]] != "--dry-run"
 		result = runChecks("chkDryrunFlag", "dry-run", "dryrun", notInRealm, isWrongPosition)
 
+	case "chkAtomicFlag":
+		// Checks the syntax of 'saptune note apply' and 'saptune solution apply' regarding the 'atomic' flag
+		notInRealm := syntaxCheckNotRealm([][]string{{"note", "apply"}, {"solution", "apply"}})
+		isWrongPosition := stArgs[cmdLinePos["cmdOpt"]] != "--atomic"
+		result = runChecks("chkAtomicFlag", "atomic", "atomic", notInRealm, isWrongPosition)
+
+	case "chkPristineFlag":
+		// Checks the syntax of 'saptune note revert' and 'saptune revert all' regarding the 'to-pristine' flag
+		notInRealm := syntaxCheckNotRealm([][]string{{"note", "revert"}, {"revert", "all"}})
+		isWrongPosition := stArgs[cmdLinePos["cmdOpt"]] != "--to-pristine"
+		result = runChecks("chkPristineFlag", "to-pristine", "to-pristine", notInRealm, isWrongPosition)
+
 	case "chkVerifySyntax":
 		result = chkVerifySyntax(stArgs, cmdLinePos, result)
 	}
@@ -73,7 +73,7 @@ func TestCliArg(t *testing.T) {
 }
 
 func TestCliFlags(t *testing.T) {
-	os.Args = []string{"saptune", "note", "list", "--format", "json", "--force", "--dryrun", "--help", "--version", "--colorscheme", "full-green-zebra", "--show-non-compliant", "--non-compliance-check", "--wrongflag", "--unknownflag=none", "--force-color", "--fun"}
+	os.Args = []string{"saptune", "note", "list", "--format", "json", "--force", "--dryrun", "--help", "--version", "--colorscheme", "full-green-zebra", "--show-non-compliant", "--non-compliance-check", "--wrongflag", "--unknownflag=none", "--force-color", "--fun", "--atomic", "--to-pristine"}
 	// parse command line, to get the test parameters
 	saptArgs, saptFlags = ParseCliArgs()
 
@@ -110,6 +110,12 @@ func TestCliFlags(t *testing.T) {
 	if !IsFlagSet("fun") {
 		t.Errorf("Test failed, expected 'fun' flag as 'true', but got 'false'")
 	}
+	if !IsFlagSet("atomic") {
+		t.Errorf("Test failed, expected 'atomic' flag as 'true', but got 'false'")
+	}
+	if !IsFlagSet("to-pristine") {
+		t.Errorf("Test failed, expected 'to-pristine' flag as 'true', but got 'false'")
+	}
 
 	expected := "json"
 	actual := GetFlagVal("format")
@@ -295,6 +301,58 @@ func TestChkCliSyntax(t *testing.T) {
 		t.Errorf("Test failed, expected wrong syntax, but got 'good'")
 	}
 
+	// saptune note apply [--atomic] NOTEID
+	// {"saptune", "note", "apply", "--atomic", "1234"} -> ok
+	os.Args = []string{"saptune", "note", "apply", "--atomic", "1234"}
+	saptArgs, saptFlags = ParseCliArgs()
+	if !ChkCliSyntax() {
+		t.Errorf("Test failed, expected good syntax, but got 'wrong'")
+	}
+
+	// {"saptune", "solution", "apply", "--atomic", "HANA"} -> ok
+	os.Args = []string{"saptune", "solution", "apply", "--atomic", "HANA"}
+	saptArgs, saptFlags = ParseCliArgs()
+	if !ChkCliSyntax() {
+		t.Errorf("Test failed, expected good syntax, but got 'wrong'")
+	}
+
+	// {"saptune", "note", "apply", "1234", "--atomic"} -> wrong
+	os.Args = []string{"saptune", "note", "apply", "1234", "--atomic"}
+	saptArgs, saptFlags = ParseCliArgs()
+	if ChkCliSyntax() {
+		t.Errorf("Test failed, expected wrong syntax, but got 'good'")
+	}
+
+	// {"saptune", "note", "revert", "--atomic", "1234"} -> wrong
+	os.Args = []string{"saptune", "note", "revert", "--atomic", "1234"}
+	saptArgs, saptFlags = ParseCliArgs()
+	if ChkCliSyntax() {
+		t.Errorf("Test failed, expected wrong syntax, but got 'good'")
+	}
+
+	// saptune note revert [--to-pristine] NOTEID
+	// {"saptune", "note", "revert", "--to-pristine", "1234"} -> ok
+	os.Args = []string{"saptune", "note", "revert", "--to-pristine", "1234"}
+	saptArgs, saptFlags = ParseCliArgs()
+	if !ChkCliSyntax() {
+		t.Errorf("Test failed, expected good syntax, but got 'wrong'")
+	}
+
+	// saptune revert all [--to-pristine]
+	// {"saptune", "revert", "all", "--to-pristine"} -> ok
+	os.Args = []string{"saptune", "revert", "all", "--to-pristine"}
+	saptArgs, saptFlags = ParseCliArgs()
+	if !ChkCliSyntax() {
+		t.Errorf("Test failed, expected good syntax, but got 'wrong'")
+	}
+
+	// {"saptune", "note", "apply", "--to-pristine", "1234"} -> wrong
+	os.Args = []string{"saptune", "note", "apply", "--to-pristine", "1234"}
+	saptArgs, saptFlags = ParseCliArgs()
+	if ChkCliSyntax() {
+		t.Errorf("Test failed, expected wrong syntax, but got 'good'")
+	}
+
 	// saptune staging release [--force|--dry-run] [NOTE...|SOLUTION...|all]
 	// {"saptune", "staging", "list", "--force"} -> wrong
 	os.Args = []string{"saptune", "staging", "list", "--force"}
@@ -1,12 +1,19 @@
 package system
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
 // BlockDev contains all key-value pairs of current available
@@ -28,6 +35,98 @@ var IsRahead = regexp.MustCompile(`^READ_AHEAD_KB_\w+\-?\d*$`)
 // IsMsect matches block device max_sectors_kb tag
 var IsMsect = regexp.MustCompile(`^MAX_SECTORS_KB_\w+\-?\d*$`)
 
+// IsBlkTunable matches the block device tags of the additional queue and
+// I/O scheduler tunables
+var IsBlkTunable = regexp.MustCompile(`^(RQ_AFFINITY|NOMERGES|WBT_LAT_USEC|ADD_RANDOM|READ_EXPIRE|FIFO_BATCH|SLICE_IDLE)_\w+\-?\d*$`)
+
+// BlockTunable describes an additional queue or I/O scheduler tunable of
+// a block device
+type BlockTunable struct {
+	File  string // file in /sys/block/<dev>/queue
+	Sched string // I/O scheduler providing the tunable in queue/iosched
+	Min   int    // minimal valid value
+	Max   int    // maximal valid value, 0 for no upper limit
+}
+
+// BlockTunables contains the additional queue and I/O scheduler tunables
+// supported in the [block] section
+var BlockTunables = map[string]BlockTunable{
+	"RQ_AFFINITY":  {File: "rq_affinity", Min: 0, Max: 2},
+	"NOMERGES":     {File: "nomerges", Min: 0, Max: 2},
+	"WBT_LAT_USEC": {File: "wbt_lat_usec", Min: -1},
+	"ADD_RANDOM":   {File: "add_random", Min: 0, Max: 1},
+	"READ_EXPIRE":  {File: "iosched/read_expire", Sched: "mq-deadline", Min: 0},
+	"FIFO_BATCH":   {File: "iosched/fifo_batch", Sched: "mq-deadline", Min: 0},
+	"SLICE_IDLE":   {File: "iosched/slice_idle", Sched: "bfq", Min: 0},
+}
+
+// BlockTunableNames returns the sorted names of the additional queue and
+// I/O scheduler tunables
+func BlockTunableNames() []string {
+	names := make([]string, 0, len(BlockTunables))
+	for name := range BlockTunables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetBlockTunable returns the name of the tunable and the block device of a
+// block device tag of an additional queue or I/O scheduler tunable
+// (e.g. READ_EXPIRE_sda)
+func GetBlockTunable(key string) (string, string) {
+	if !IsBlkTunable.MatchString(key) {
+		return "", ""
+	}
+	for name := range BlockTunables {
+		if strings.HasPrefix(key, name+"_") {
+			return name, strings.TrimPrefix(key, name+"_")
+		}
+	}
+	return "", ""
+}
+
+// BlockTunableKey returns the sys key (without the leading /sys/) of the
+// tunable of a block device
+func BlockTunableKey(name, bdev string) string {
+	return path.Join("block", bdev, "queue", BlockTunables[name].File)
+}
+
+// BlockKeyDevice returns the block device of a block device tag
+// (e.g. IO_SCHEDULER_sda or READ_EXPIRE_sda) or an empty string, if the key
+// is not a block device tag
+func BlockKeyDevice(key string) string {
+	if _, bdev := GetBlockTunable(key); bdev != "" {
+		return bdev
+	}
+	for prefix, isKey := range map[string]*regexp.Regexp{"IO_SCHEDULER_": IsSched, "NRREQ_": IsNrreq, "READ_AHEAD_KB_": IsRahead, "MAX_SECTORS_KB_": IsMsect} {
+		if isKey.MatchString(key) {
+			return strings.TrimPrefix(key, prefix)
+		}
+	}
+	return ""
+}
+
+// blockDevChangedDir contains a marker file for each block device, which
+// was added, changed or removed after the block device information was
+// collected. The marker files are created by the saptune udev rule.
+var blockDevChangedDir = "/run/saptune/blockdev.changed"
+
+// sysBlockDir is the sysfs directory of the block devices
+var sysBlockDir = "/sys/block"
+
+// diskByIDDir contains the persistent links to the block devices
+var diskByIDDir = "/dev/disk/by-id"
+
+// udevDataDir contains the udev database, which records the by-id links of
+// the block devices too. Used, if /dev/disk/by-id is not available, e.g.
+// in the private /dev of saptune.service (PrivateDevices=true)
+var udevDataDir = "/run/udev/data"
+
+// blockDevExcludes contains the block devices excluded from all block
+// device settings (BLOCK_DEVICE_EXCLUDE)
+var blockDevExcludes = []string{}
+
 var isVD = regexp.MustCompile(`^x?vd\w+$`)
 
 // devices like /dev/nvme0n1 are the NVME storage namespaces: the devices you
@@ -78,153 +177,693 @@ func BlockDeviceIsDisk(dev string) bool {
 // GetBlockDeviceInfo reads content of stored block device information.
 // content stored in SaptuneSectionDir (/run/saptune/sections)
 // as blockdev.run
+// The stored information is used for the list of block devices and their
+// static attributes, the queue attributes are read from the system
 // Return the content as BlockDev
 func GetBlockDeviceInfo() (*BlockDev, error) {
+	bdevConf, err := getStoredBlockDeviceInfo()
+	if err == nil {
+		refreshBlockQueueAttributes(bdevConf)
+	}
+	return bdevConf, err
+}
+
+// getStoredBlockDeviceInfo reads the stored block device information
+// without the current queue attributes. The block devices listed in
+// BLOCK_DEVICE_EXCLUDE are removed during the read, so a change of the
+// setting is effective without a new collection of the information
+func getStoredBlockDeviceInfo() (*BlockDev, error) {
 	bdevFileName := fmt.Sprintf("%s/blockdev.run", SaptuneSectionDir)
 	bdevConf := &BlockDev{
 		AllBlockDevs:    make([]string, 0, 64),
 		BlockAttributes: make(map[string]map[string]string),
 	}
 
-	content, err := os.ReadFile(bdevFileName)
+	// each caller gets its own copy of the (cached) information
+	content, err := cachedFact("blockdev", bdevFileName, func() (string, error) {
+		return readBlockDeviceInfo(bdevFileName)
+	})
 	if err == nil && len(content) != 0 {
-		err = json.Unmarshal(content, &bdevConf)
+		err = json.Unmarshal([]byte(content), &bdevConf)
+	}
+	if err == nil {
+		excludeBlockDevices(bdevConf)
 	}
 	return bdevConf, err
 }
 
+// readBlockDeviceInfo reads the stored block device information and
+// refreshes the block devices changed since the last collection.
+// Returns the information in json format
+func readBlockDeviceInfo(bdevFileName string) (string, error) {
+	content, err := os.ReadFile(bdevFileName)
+	if err != nil || len(content) == 0 {
+		return "", err
+	}
+	bdevConf := &BlockDev{
+		AllBlockDevs:    make([]string, 0, 64),
+		BlockAttributes: make(map[string]map[string]string),
+	}
+	if err = json.Unmarshal(content, &bdevConf); err != nil {
+		return "", err
+	}
+	// handle block devices changed since the last collection
+	if !RefreshBlockDeviceInfo(bdevConf) {
+		return string(content), nil
+	}
+	content, err = json.Marshal(bdevConf)
+	return string(content), err
+}
+
 // getValidBlockDevices reads all block devices from /sys/block
-// and select the block devices, which are 'real disks' or a multipath
-// device (/sys/block/*/dm/uuid starts with 'mpath-')
+// and select the block devices, which are 'real disks' or a layer of a
+// block device stack - a multipath device (/sys/block/*/dm/uuid starts
+// with 'mpath-'), a LVM logical volume (dm/uuid starts with 'LVM-') or
+// a MD RAID device (/sys/block/*/md exists).
+// Which layers of a stack are used by a Note section is decided later by
+// SelectBlockDeviceLayer
 func getValidBlockDevices() (valDevs []string) {
-	var isMpath = regexp.MustCompile(`^mpath-\w+`)
-	var isLVM = regexp.MustCompile(`^LVM-\w+`)
-	candidates := []string{}
-	excludedevs := []string{}
-
 	// List /sys/block and inspect the needed info of each one
-	_, sysDevs := ListDir("/sys/block", "the available block devices of the system")
+	_, sysDevs := ListDir(sysBlockDir, "the available block devices of the system")
 	for _, bdev := range sysDevs {
-		dmUUID := fmt.Sprintf("/sys/block/%s/dm/uuid", bdev)
-		if _, err := os.Stat(dmUUID); err == nil {
-			cont, _ := os.ReadFile(dmUUID)
-			if isMpath.MatchString(string(cont)) {
-				candidates = append(candidates, bdev)
-				_, slaves := ListDir(fmt.Sprintf("/sys/block/%s/slaves", bdev), "dm slaves")
-				excludedevs = append(excludedevs, slaves...)
-			} else {
-				// skip not applicable devices
-				if isLVM.MatchString(string(cont)) {
-					InfoLog("skipping device '%s' (LVM), not applicable", bdev)
-				} else {
-					InfoLog("skipping device '%s', not applicable", bdev)
+		if blockDevStackType(bdev) != "" {
+			valDevs = append(valDevs, bdev)
+			continue
+		}
+		if _, err := os.Stat(path.Join(sysBlockDir, bdev, "dm", "uuid")); err == nil {
+			// skip not applicable device mapper targets (e.g. crypt)
+			InfoLog("skipping device '%s', not applicable", bdev)
+			continue
+		}
+		if !BlockDeviceIsDisk(bdev) {
+			// skip not applicable devices
+			InfoLog("skipping device '%s', not applicable", bdev)
+			continue
+		}
+		valDevs = append(valDevs, bdev)
+	}
+	return valDevs
+}
+
+// blockDevStackType returns the type of a stacked block device - 'mpath'
+// for multipath devices, 'lvm' for LVM logical volumes and 'md' for MD RAID
+// devices. For all other block devices an empty string is returned
+func blockDevStackType(bdev string) string {
+	uuid := readBlockAttr(bdev, "dm/uuid")
+	switch {
+	case strings.HasPrefix(uuid, "mpath-"):
+		return "mpath"
+	case strings.HasPrefix(uuid, "LVM-"):
+		return "lvm"
+	}
+	if _, err := os.Stat(path.Join(sysBlockDir, bdev, "md")); err == nil {
+		return "md"
+	}
+	return ""
+}
+
+// blockDevSlaves returns the block devices below a stacked block device
+// (/sys/block/<bdev>/slaves). Partitions are mapped to their disk
+func blockDevSlaves(bdev string) []string {
+	slaveDir := path.Join(sysBlockDir, bdev, "slaves")
+	_, links := ListDir(slaveDir, "")
+	slaves := []string{}
+	for _, link := range links {
+		slaves = appendUniqueDev(slaves, blockDevDisk(path.Join(slaveDir, link)))
+	}
+	return slaves
+}
+
+// blockDevHolders returns the block devices stacked on top of a block
+// device or on top of one of its partitions (/sys/block/<bdev>/holders and
+// /sys/block/<bdev>/<partition>/holders)
+func blockDevHolders(bdev string) []string {
+	_, holders := ListDir(path.Join(sysBlockDir, bdev, "holders"), "")
+	parts, _ := ListDir(path.Join(sysBlockDir, bdev), "")
+	for _, part := range parts {
+		if _, err := os.Stat(path.Join(sysBlockDir, bdev, part, "partition")); err != nil {
+			continue
+		}
+		_, partHolders := ListDir(path.Join(sysBlockDir, bdev, part, "holders"), "")
+		holders = append(holders, partHolders...)
+	}
+	uniqHolders := []string{}
+	for _, holder := range holders {
+		uniqHolders = appendUniqueDev(uniqHolders, holder)
+	}
+	return uniqHolders
+}
+
+// blockDevDisk returns the name of the disk of a sysfs block device link.
+// For a partition the name of the disk containing the partition is returned
+func blockDevDisk(link string) string {
+	target, err := filepath.EvalSymlinks(link)
+	if err != nil {
+		return path.Base(link)
+	}
+	if _, err := os.Stat(path.Join(target, "partition")); err == nil {
+		return path.Base(path.Dir(target))
+	}
+	return path.Base(target)
+}
+
+// appendUniqueDev appends a block device to the list, if not yet available
+func appendUniqueDev(bdevs []string, bdev string) []string {
+	for _, dev := range bdevs {
+		if dev == bdev {
+			return bdevs
+		}
+	}
+	return append(bdevs, bdev)
+}
+
+// SelectBlockDeviceLayer selects the block devices of the given list, which
+// belong to the requested layer of a block device stack
+//
+//	top    - the top most devices of the stacks and all not stacked disks
+//	leaves - the disks (or paths) at the bottom of the stacks and all
+//	         not stacked disks
+//	both   - all layers of the stacks
+//
+// Without a layer ("") the multipath devices instead of their paths and the
+// disks below LVM and MD RAID devices are selected, as LVM and MD devices do
+// not have an own request queue
+func SelectBlockDeviceLayer(bdevs []string, layer string) []string {
+	if layer == "both" {
+		return bdevs
+	}
+	bdevConf, err := getStoredBlockDeviceInfo()
+	if err != nil {
+		return bdevs
+	}
+	selected := []string{}
+	for _, bdev := range bdevs {
+		attrs, ok := bdevConf.BlockAttributes[bdev]
+		if !ok {
+			selected = append(selected, bdev)
+			continue
+		}
+		holders := knownBlockDevs(bdevConf, attrs["HOLDERS"])
+		slaves := knownBlockDevs(bdevConf, attrs["SLAVES"])
+		use := false
+		switch layer {
+		case "top":
+			use = len(holders) == 0
+		case "leaves":
+			use = len(slaves) == 0
+		default:
+			switch attrs["STACK"] {
+			case "mpath":
+				use = true
+			case "":
+				use = true
+				for _, holder := range holders {
+					if bdevConf.BlockAttributes[holder]["STACK"] == "mpath" {
+						use = false
+					}
 				}
 			}
+		}
+		if use {
+			selected = append(selected, bdev)
 		} else {
-			if !BlockDeviceIsDisk(bdev) {
-				// skip not applicable devices
-				InfoLog("skipping device '%s', not applicable", bdev)
+			DebugLog("skipping device '%s', not part of block device stack layer '%s'", bdev, layer)
+		}
+	}
+	return selected
+}
+
+// knownBlockDevs returns the block devices of the space separated list,
+// which are part of the block device information
+func knownBlockDevs(bdevConf *BlockDev, list string) []string {
+	known := []string{}
+	for _, bdev := range strings.Fields(list) {
+		if _, ok := bdevConf.BlockAttributes[bdev]; ok {
+			known = append(known, bdev)
+		}
+	}
+	return known
+}
+
+// blockStackRelatives returns all block devices above (holders) and below
+// (slaves) the given block device in its block device stack
+func blockStackRelatives(bdevConf *BlockDev, bdev string) []string {
+	relatives := []string{}
+	seen := map[string]bool{bdev: true}
+	for _, rel := range []string{"HOLDERS", "SLAVES"} {
+		todo := knownBlockDevs(bdevConf, bdevConf.BlockAttributes[bdev][rel])
+		for len(todo) > 0 {
+			dev := todo[0]
+			todo = todo[1:]
+			if seen[dev] {
 				continue
 			}
-			candidates = append(candidates, bdev)
+			seen[dev] = true
+			relatives = append(relatives, dev)
+			todo = append(todo, knownBlockDevs(bdevConf, bdevConf.BlockAttributes[dev][rel])...)
 		}
 	}
-	if len(excludedevs) == 0 {
-		return candidates
+	return relatives
+}
+
+// BlockStackDiffs returns the block devices of the block device stack of the
+// device related to the given block parameter key (e.g. READ_AHEAD_KB_dm-0)
+// together with their value, if the value differs from the value of the
+// device itself. The tunables of the I/O schedulers are not compared. The
+// scheduler and nr_requests are only compared between multipath devices
+// and disks, as LVM and MD devices have no own request queue
+func BlockStackDiffs(key string) []string {
+	diffs := []string{}
+	var attr string
+	bdev := BlockKeyDevice(key)
+	if bdev == "" {
+		return diffs
 	}
-	for _, bdev := range candidates {
-		exclude := false
-		for _, edev := range excludedevs {
-			if bdev == edev {
-				// skip not applicable devices
-				InfoLog("skipping device '%s', not applicable for dm slaves", bdev)
-				exclude = true
-				break
+	if name, _ := GetBlockTunable(key); name != "" {
+		if BlockTunables[name].Sched != "" {
+			// depends on the active scheduler of each layer
+			return diffs
+		}
+		attr = BlockTunables[name].File
+	} else {
+		attr = map[string]string{"IO_SCHEDULER": "scheduler", "NRREQ": "nr_requests", "READ_AHEAD_KB": "read_ahead_kb", "MAX_SECTORS_KB": "max_sectors_kb"}[strings.TrimSuffix(key, "_"+bdev)]
+	}
+	bdevConf, err := getStoredBlockDeviceInfo()
+	if err != nil {
+		return diffs
+	}
+	reqQueueOnly := attr == "scheduler" || attr == "nr_requests"
+	if reqQueueOnly && !hasRequestQueue(bdevConf, bdev) {
+		return diffs
+	}
+	value := BlockQueueValue(bdev, attr)
+	if value == "" {
+		return diffs
+	}
+	for _, rel := range blockStackRelatives(bdevConf, bdev) {
+		if reqQueueOnly && !hasRequestQueue(bdevConf, rel) {
+			continue
+		}
+		if relVal := BlockQueueValue(rel, attr); relVal != "" && relVal != value {
+			diffs = append(diffs, rel+"="+relVal)
+		}
+	}
+	return diffs
+}
+
+// hasRequestQueue returns true, if the block device is a multipath device
+// or a disk
+func hasRequestQueue(bdevConf *BlockDev, bdev string) bool {
+	stack := bdevConf.BlockAttributes[bdev]["STACK"]
+	return stack == "" || stack == "mpath"
+}
+
+// BlockQueueValue returns the current value of a queue attribute of a
+// block device (relative to /sys/block/<bdev>/queue) read live from sysfs.
+// For the scheduler the active scheduler is returned.
+// If the attribute is not available, an empty string is returned
+func BlockQueueValue(bdev, attr string) string {
+	val := readBlockAttr(bdev, path.Join("queue", attr))
+	if attr == "scheduler" {
+		for _, choice := range strings.Fields(val) {
+			if len(choice) > 2 && choice[0] == '[' && choice[len(choice)-1] == ']' {
+				return choice[1 : len(choice)-1]
 			}
 		}
-		if !exclude {
-			valDevs = append(valDevs, bdev)
+	}
+	return val
+}
+
+// BlockQueueAttributes returns the queue attributes of a block device read
+// live from sysfs - the active scheduler (IO_SCHEDULER), the available
+// schedulers (VALID_SCHEDS), NRREQ, READ_AHEAD_KB, MAX_SECTORS_KB, the
+// additional queue and I/O scheduler tunables and NR_TAGS.
+// The tunables of the I/O scheduler are only available for the active
+// scheduler
+func BlockQueueAttributes(bdev string) map[string]string {
+	attrs := map[string]string{
+		"IO_SCHEDULER":   BlockQueueValue(bdev, "scheduler"),
+		"VALID_SCHEDS":   readBlockAttr(bdev, "queue/scheduler"),
+		"NRREQ":          BlockQueueValue(bdev, "nr_requests"),
+		"READ_AHEAD_KB":  BlockQueueValue(bdev, "read_ahead_kb"),
+		"MAX_SECTORS_KB": BlockQueueValue(bdev, "max_sectors_kb"),
+		"NR_TAGS":        readBlockAttr(bdev, "mq/0/nr_tags"),
+	}
+	for _, name := range BlockTunableNames() {
+		attrs[name] = BlockQueueValue(bdev, BlockTunables[name].File)
+	}
+	return attrs
+}
+
+// refreshBlockQueueAttributes replaces the queue attributes of the stored
+// block device information by the current values of the system, as the
+// queue attributes change without an udev event (e.g. by saptune itself or
+// by an external write to sysfs). Devices not available in sysfs are
+// left unchanged
+func refreshBlockQueueAttributes(bdevConf *BlockDev) {
+	for _, bdev := range bdevConf.AllBlockDevs {
+		if _, err := os.Stat(path.Join(sysBlockDir, bdev, "queue")); err != nil {
+			continue
+		}
+		if bdevConf.BlockAttributes[bdev] == nil {
+			bdevConf.BlockAttributes[bdev] = make(map[string]string)
+		}
+		for attr, val := range BlockQueueAttributes(bdev) {
+			bdevConf.BlockAttributes[bdev][attr] = val
 		}
 	}
-	return valDevs
 }
 
 // CollectBlockDeviceInfo collects all needed information about
 // block devices from /sys/block
 // write info to /var/lib/saptune/sections/block.run
+// The attributes of the block devices are read concurrently, as this is
+// time consuming on systems with hundreds of devices
 func CollectBlockDeviceInfo() []string {
 	bdevConf := BlockDev{
 		AllBlockDevs:    make([]string, 0, 64),
 		BlockAttributes: make(map[string]map[string]string),
 	}
+	// a full collection covers all pending udev change events
+	changed := readBlockDevChanges()
+	bdevConf.AllBlockDevs = getValidBlockDevices()
+	bdevConf.BlockAttributes = collectBlockDevices(bdevConf.AllBlockDevs)
+
+	// the excluded block devices are stored too and are removed during
+	// the read (see getStoredBlockDeviceInfo)
+	err := storeBlockDeviceInfo(bdevConf)
+	if err != nil {
+		ErrorLog("could not store block device information - err: %v", err)
+	}
+	clearBlockDevChanges(changed)
+	excludeBlockDevices(&bdevConf)
+	return bdevConf.AllBlockDevs
+}
+
+// collectBlockDevices reads the attributes of the given block devices
+// concurrently in one pass
+func collectBlockDevices(bdevs []string) map[string]map[string]string {
+	byID := blockDevByIDLinks()
+	attrs := make([]map[string]string, len(bdevs))
+	var wg sync.WaitGroup
+	// reading sysfs is not cpu bound, so use more workers than cpus
+	limit := make(chan bool, 2*runtime.NumCPU())
+	for i, bdev := range bdevs {
+		wg.Add(1)
+		limit <- true
+		go func(i int, bdev string) {
+			defer wg.Done()
+			attrs[i] = collectBlockDevice(bdev, byID)
+			<-limit
+		}(i, bdev)
+	}
+	wg.Wait()
+	blockAttributes := make(map[string]map[string]string)
+	for i, bdev := range bdevs {
+		blockAttributes[bdev] = attrs[i]
+	}
+	return blockAttributes
+}
 
-	for _, bdev := range getValidBlockDevices() {
-		// add new block device
-		blockMap := make(map[string]string)
+// collectBlockDevice reads the attributes of a single block device
+// byID contains the /dev/disk/by-id links of all block devices
+func collectBlockDevice(bdev string, byID map[string][]string) map[string]string {
+	// queue attributes, refreshed on each read of the stored block
+	// device information (see GetBlockDeviceInfo)
+	blockMap := BlockQueueAttributes(bdev)
 
-		// Remember, GetSysChoice does not accept the leading /sys/
-		elev, _ := GetSysChoice(path.Join("block", bdev, "queue", "scheduler"))
-		if elev == "" || elev == "NA" || elev == "PNA" {
-			elev, _ = GetSysString(path.Join("block", bdev, "queue", "scheduler"))
+	// VENDOR, MODEL e.g. for FUJITSU udev replacement
+	vendor := ""
+	model := ""
+	// virtio block devices do not have useful values.
+	if !isVD.MatchString(bdev) {
+		vendFile := path.Join("block", bdev, "device", "vendor")
+		if _, err := os.Stat(path.Join("/sys", vendFile)); err == nil {
+			vendor, _ = GetSysString(vendFile)
+		} else {
+			InfoLog("missing vendor information for block device '%s', file '%s' does not exist.", bdev, vendFile)
 		}
-		blockMap["IO_SCHEDULER"] = elev
-		val, err := os.ReadFile(path.Join("/sys/block/", bdev, "/queue/scheduler"))
-		sched := ""
-		if err == nil {
-			sched = string(val)
+		modelFile := path.Join("block", bdev, "device", "model")
+		if _, err := os.Stat(path.Join("/sys", modelFile)); err == nil {
+			model, _ = GetSysString(modelFile)
+		} else {
+			InfoLog("missing model information for block device '%s', file '%s' does not exist.", bdev, modelFile)
 		}
-		blockMap["VALID_SCHEDS"] = sched
+	}
+	blockMap["VENDOR"] = vendor
+	blockMap["MODEL"] = model
 
-		// Remember, GetSysString does not accept the leading /sys/
-		nrreq, _ := GetSysString(path.Join("block", bdev, "queue", "nr_requests"))
-		blockMap["NRREQ"] = nrreq
+	// stable identifiers, as kernel names can change between boots
+	wwn := readBlockAttr(bdev, "device/wwid", "wwid")
+	if wwn == "" {
+		// multipath devices
+		if uuid := readBlockAttr(bdev, "dm/uuid"); strings.HasPrefix(uuid, "mpath-") {
+			wwn = strings.TrimPrefix(uuid, "mpath-")
+		}
+	}
+	blockMap["WWN"] = wwn
+	serial := readBlockAttr(bdev, "device/serial", "serial")
+	if serial == "" {
+		serial = readVPDSerial(bdev)
+	}
+	blockMap["SERIAL"] = serial
+	blockMap["BYID"] = strings.Join(byID[bdev], " ")
+
+	// layer of a block device stack (multipath, LVM, MD RAID)
+	blockMap["STACK"] = blockDevStackType(bdev)
+	blockMap["SLAVES"] = strings.Join(blockDevSlaves(bdev), " ")
+	blockMap["HOLDERS"] = strings.Join(blockDevHolders(bdev), " ")
+	// ... more to come
+	return blockMap
+}
 
-		readahead, _ := GetSysString(path.Join("block", bdev, "queue", "read_ahead_kb"))
-		blockMap["READ_AHEAD_KB"] = readahead
+// readBlockAttr returns the content of the first available and non-empty
+// attribute file of the block device (relative to /sys/block/<bdev>)
+func readBlockAttr(bdev string, files ...string) string {
+	for _, file := range files {
+		val, err := os.ReadFile(path.Join(sysBlockDir, bdev, file))
+		if err != nil {
+			continue
+		}
+		if attr := strings.TrimSpace(string(bytes.Trim(val, "\x00"))); attr != "" {
+			return attr
+		}
+	}
+	return ""
+}
 
-		maxsectkb, _ := GetSysString(path.Join("block", bdev, "queue", "max_sectors_kb"))
-		blockMap["MAX_SECTORS_KB"] = maxsectkb
+// readVPDSerial returns the unit serial number of a SCSI device from the
+// vital product data page 0x80 (4 bytes header followed by the serial)
+func readVPDSerial(bdev string) string {
+	val, err := os.ReadFile(path.Join(sysBlockDir, bdev, "device", "vpd_pg80"))
+	if err != nil || len(val) <= 4 {
+		return ""
+	}
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, string(val[4:])))
+}
 
-		nrTagsFile := path.Join("block", bdev, "mq", "0", "nr_tags")
-		nrtags := ""
-		if _, err := os.Stat(path.Join("/sys", nrTagsFile)); err == nil {
-			nrtags, _ = GetSysString(nrTagsFile)
+// blockDevByIDLinks returns the names of the /dev/disk/by-id links of
+// all block devices, sorted by name
+// If /dev/disk/by-id is not available, the links are read from the udev
+// database
+func blockDevByIDLinks() map[string][]string {
+	links := make(map[string][]string)
+	_, names := ListDir(diskByIDDir, "")
+	for _, name := range names {
+		target, err := os.Readlink(path.Join(diskByIDDir, name))
+		if err != nil {
+			continue
 		}
-		blockMap["NR_TAGS"] = nrtags
+		bdev := path.Base(target)
+		links[bdev] = append(links[bdev], name)
+	}
+	if len(links) == 0 {
+		links = udevByIDLinks()
+	}
+	for bdev := range links {
+		sort.Strings(links[bdev])
+	}
+	return links
+}
 
-		// VENDOR, MODEL e.g. for FUJITSU udev replacement
-		vendor := ""
-		model := ""
-		// virtio block devices do not have useful values.
-		if !isVD.MatchString(bdev) {
-			vendFile := path.Join("block", bdev, "device", "vendor")
-			if _, err := os.Stat(path.Join("/sys", vendFile)); err == nil {
-				vendor, _ = GetSysString(vendFile)
-			} else {
-				InfoLog("missing vendor information for block device '%s', file '%s' does not exist.", bdev, vendFile)
-			}
-			modelFile := path.Join("block", bdev, "device", "model")
-			if _, err := os.Stat(path.Join("/sys", modelFile)); err == nil {
-				model, _ = GetSysString(modelFile)
-			} else {
-				InfoLog("missing model information for block device '%s', file '%s' does not exist.", bdev, modelFile)
+// udevByIDLinks returns the names of the by-id links of all block devices
+// from the udev database (lines 'S:disk/by-id/<name>' of the file
+// b<major>:<minor>)
+func udevByIDLinks() map[string][]string {
+	links := make(map[string][]string)
+	entries, err := os.ReadDir(sysBlockDir)
+	if err != nil {
+		return links
+	}
+	for _, entry := range entries {
+		bdev := entry.Name()
+		devNum := readBlockAttr(bdev, "dev")
+		if devNum == "" {
+			continue
+		}
+		content, err := os.ReadFile(path.Join(udevDataDir, "b"+devNum))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.HasPrefix(line, "S:disk/by-id/") {
+				links[bdev] = append(links[bdev], strings.TrimPrefix(line, "S:disk/by-id/"))
 			}
 		}
-		blockMap["VENDOR"] = vendor
-		blockMap["MODEL"] = model
-		// ... more to come
+	}
+	return links
+}
 
-		// end of sys/block loop
-		// save block info
-		bdevConf.BlockAttributes[bdev] = blockMap
-		bdevConf.AllBlockDevs = append(bdevConf.AllBlockDevs, bdev)
+// BlockDeviceStableID returns the stable identifier of a block device
+// from the block device attributes. In the order WWN, first
+// /dev/disk/by-id link, serial number. Returns an empty string, if no
+// stable identifier is available
+func BlockDeviceStableID(attrs map[string]string) string {
+	if attrs["WWN"] != "" {
+		return attrs["WWN"]
+	}
+	if byID := strings.Fields(attrs["BYID"]); len(byID) != 0 {
+		return byID[0]
 	}
+	return attrs["SERIAL"]
+}
 
-	err := storeBlockDeviceInfo(bdevConf)
+// BlockDeviceStableIDs returns the stable identifiers of all block devices
+// stored in the block device information
+func BlockDeviceStableIDs() map[string]string {
+	ids := make(map[string]string)
+	bdevConf, err := getStoredBlockDeviceInfo()
 	if err != nil {
+		return ids
+	}
+	for _, bdev := range bdevConf.AllBlockDevs {
+		if id := BlockDeviceStableID(bdevConf.BlockAttributes[bdev]); id != "" {
+			ids[bdev] = id
+		}
+	}
+	return ids
+}
+
+// SetBlockDeviceExcludes sets the block devices, which should be excluded
+// from all block device settings (BLOCK_DEVICE_EXCLUDE of the saptune
+// configuration). Entries can be separated by blanks or commas.
+func SetBlockDeviceExcludes(excludes string) {
+	blockDevExcludes = strings.FieldsFunc(excludes, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// isExcludedBlockDevice checks, if a block device is listed in
+// BLOCK_DEVICE_EXCLUDE. Block devices can be listed by kernel name
+// (with or without /dev/), WWN, serial number or /dev/disk/by-id link
+// (with or without /dev/disk/by-id/)
+func isExcludedBlockDevice(bdev string, attrs map[string]string) bool {
+	for _, excl := range blockDevExcludes {
+		excl = strings.TrimPrefix(strings.TrimPrefix(excl, diskByIDDir+"/"), "/dev/")
+		if excl == bdev || (attrs["WWN"] != "" && excl == attrs["WWN"]) || (attrs["SERIAL"] != "" && excl == attrs["SERIAL"]) {
+			return true
+		}
+		for _, link := range strings.Fields(attrs["BYID"]) {
+			if excl == link {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// excludeBlockDevices removes the block devices listed in
+// BLOCK_DEVICE_EXCLUDE from the read block device information
+func excludeBlockDevices(bdevConf *BlockDev) {
+	if len(blockDevExcludes) == 0 {
+		return
+	}
+	valDevs := []string{}
+	for _, bdev := range bdevConf.AllBlockDevs {
+		if isExcludedBlockDevice(bdev, bdevConf.BlockAttributes[bdev]) {
+			InfoLog("skipping device '%s', excluded by BLOCK_DEVICE_EXCLUDE", bdev)
+			delete(bdevConf.BlockAttributes, bdev)
+			continue
+		}
+		valDevs = append(valDevs, bdev)
+	}
+	bdevConf.AllBlockDevs = valDevs
+}
+
+// RefreshBlockDeviceInfo updates the stored block device information for
+// the block devices reported as changed by the saptune udev rule
+// (see blockDevChangedDir). Only the attributes of the changed devices are
+// read again, removed devices are dropped from the information and new
+// devices are added.
+// Returns true, if the block device information was changed.
+func RefreshBlockDeviceInfo(bdevConf *BlockDev) bool {
+	changed := readBlockDevChanges()
+	if len(changed) == 0 {
+		return false
+	}
+	DebugLog("RefreshBlockDeviceInfo - block devices changed since last collection: '%v'", changed)
+	refresh := make(map[string]bool)
+	for bdev := range changed {
+		refresh[bdev] = true
+	}
+	valDevs := getValidBlockDevices()
+	newDevs := []string{}
+	for _, bdev := range valDevs {
+		if _, known := bdevConf.BlockAttributes[bdev]; !known || refresh[bdev] {
+			newDevs = append(newDevs, bdev)
+		}
+	}
+	newAttrs := collectBlockDevices(newDevs)
+	blockAttributes := make(map[string]map[string]string)
+	for _, bdev := range valDevs {
+		if attrs, ok := newAttrs[bdev]; ok {
+			blockAttributes[bdev] = attrs
+		} else {
+			blockAttributes[bdev] = bdevConf.BlockAttributes[bdev]
+		}
+	}
+	bdevConf.AllBlockDevs = valDevs
+	bdevConf.BlockAttributes = blockAttributes
+	if err := storeBlockDeviceInfo(*bdevConf); err != nil {
 		ErrorLog("could not store block device information - err: %v", err)
+		return true
+	}
+	clearBlockDevChanges(changed)
+	return true
+}
+
+// readBlockDevChanges returns the change markers of the block devices
+// together with their modification time
+func readBlockDevChanges() map[string]time.Time {
+	markers := make(map[string]time.Time)
+	entries, err := os.ReadDir(blockDevChangedDir)
+	if err != nil {
+		return markers
+	}
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			markers[entry.Name()] = info.ModTime()
+		}
+	}
+	return markers
+}
+
+// clearBlockDevChanges removes the change markers, which were read before
+// the collection of the block device information started and are handled
+// now. A marker touched again by the udev rule during the collection is
+// kept for the next refresh
+func clearBlockDevChanges(markers map[string]time.Time) {
+	for bdev, mtime := range markers {
+		marker := path.Join(blockDevChangedDir, bdev)
+		if info, err := os.Stat(marker); err == nil && info.ModTime().Equal(mtime) {
+			_ = os.Remove(marker)
+		}
 	}
-	return bdevConf.AllBlockDevs
 }
 
 // storeBlockDeviceInfo stores block device information to file blockdev.run
@@ -233,6 +872,7 @@ func CollectBlockDeviceInfo() []string {
 func storeBlockDeviceInfo(obj BlockDev) error {
 	overwriteExisting := true
 	bdevFileName := fmt.Sprintf("%s/blockdev.run", SaptuneSectionDir)
+	forgetFact("blockdev", bdevFileName)
 
 	content, err := json.Marshal(obj)
 	if err != nil {
@@ -254,7 +894,7 @@ func GetAvailBlockInfo(info, tag string) []string {
 	ret := []string{}
 	inf := ""
 	if blkDevConf == nil || (len(blkDevConf.AllBlockDevs) == 0 && len(blkDevConf.BlockAttributes) == 0) {
-		blkDevConf, _ = GetBlockDeviceInfo()
+		blkDevConf, _ = getStoredBlockDeviceInfo()
 	}
 	for _, entry := range blkDevConf.AllBlockDevs {
 		if info == "pat" {
@@ -265,9 +905,17 @@ func GetAvailBlockInfo(info, tag string) []string {
 		if inf == "" {
 			continue
 		}
-		match, _ := regexp.MatchString(tag, inf)
-		if match {
-			ret = append(ret, entry)
+		infos := []string{inf}
+		if info == "BYID" {
+			// blank separated list of by-id links, match each
+			// link on its own
+			infos = strings.Fields(inf)
+		}
+		for _, i := range infos {
+			if match, _ := regexp.MatchString(tag, i); match {
+				ret = append(ret, entry)
+				break
+			}
 		}
 	}
 	return ret
@@ -5,7 +5,9 @@ import (
 	"path"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestBlockDeviceIsDisk(t *testing.T) {
@@ -133,3 +135,413 @@ func TestGetAvailBlockInfo(t *testing.T) {
 	bdevFile := path.Join(SaptuneSectionDir, "/blockdev.run")
 	_ = os.Remove(bdevFile)
 }
+
+func TestRefreshBlockDeviceInfo(t *testing.T) {
+	oldChangedDir := blockDevChangedDir
+	defer func() { blockDevChangedDir = oldChangedDir }()
+	blockDevChangedDir = t.TempDir()
+
+	valDevs := getValidBlockDevices()
+	bdevConf := &BlockDev{
+		AllBlockDevs:    []string{"saptunefake"},
+		BlockAttributes: map[string]map[string]string{"saptunefake": {"IO_SCHEDULER": "none"}},
+	}
+	// no changes reported
+	if RefreshBlockDeviceInfo(bdevConf) {
+		t.Error("block device information should not be refreshed")
+	}
+	if len(bdevConf.AllBlockDevs) != 1 {
+		t.Errorf("block devices changed without reported changes: '%+v'", bdevConf.AllBlockDevs)
+	}
+
+	// removed device reported by udev
+	if err := os.WriteFile(path.Join(blockDevChangedDir, "saptunefake"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !RefreshBlockDeviceInfo(bdevConf) {
+		t.Error("block device information should be refreshed")
+	}
+	if _, ok := bdevConf.BlockAttributes["saptunefake"]; ok {
+		t.Error("removed block device 'saptunefake' still available")
+	}
+	if !reflect.DeepEqual(bdevConf.AllBlockDevs, valDevs) {
+		t.Errorf("wrong block devices after refresh: '%+v', expected '%+v'", bdevConf.AllBlockDevs, valDevs)
+	}
+	for _, bdev := range valDevs {
+		if _, ok := bdevConf.BlockAttributes[bdev]; !ok {
+			t.Errorf("missing attributes for block device '%s'", bdev)
+		}
+	}
+	if _, err := os.Stat(path.Join(blockDevChangedDir, "saptunefake")); !os.IsNotExist(err) {
+		t.Error("change marker of block device 'saptunefake' not removed")
+	}
+	_ = os.Remove(path.Join(SaptuneSectionDir, "/blockdev.run"))
+}
+
+func TestClearBlockDevChanges(t *testing.T) {
+	oldChangedDir := blockDevChangedDir
+	defer func() { blockDevChangedDir = oldChangedDir }()
+	blockDevChangedDir = t.TempDir()
+	for _, bdev := range []string{"sda", "sdb"} {
+		if err := os.WriteFile(path.Join(blockDevChangedDir, bdev), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	markers := readBlockDevChanges()
+	if len(markers) != 2 {
+		t.Fatalf("wrong change markers: '%+v'", markers)
+	}
+	// udev touches 'sdb' again and adds 'sdc' during the collection
+	later := markers["sdb"].Add(time.Second)
+	if err := os.Chtimes(path.Join(blockDevChangedDir, "sdb"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(blockDevChangedDir, "sdc"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	clearBlockDevChanges(markers)
+	if _, err := os.Stat(path.Join(blockDevChangedDir, "sda")); !os.IsNotExist(err) {
+		t.Error("handled change marker of block device 'sda' not removed")
+	}
+	for _, bdev := range []string{"sdb", "sdc"} {
+		if _, err := os.Stat(path.Join(blockDevChangedDir, bdev)); err != nil {
+			t.Errorf("change marker of block device '%s' created during the collection removed", bdev)
+		}
+	}
+}
+
+func TestCollectBlockDevices(t *testing.T) {
+	valDevs := getValidBlockDevices()
+	attrs := collectBlockDevices(valDevs)
+	if len(attrs) != len(valDevs) {
+		t.Errorf("collected attributes for '%d' devices instead of '%d'", len(attrs), len(valDevs))
+	}
+	for _, bdev := range valDevs {
+		if !reflect.DeepEqual(attrs[bdev], collectBlockDevice(bdev, blockDevByIDLinks())) {
+			t.Errorf("concurrent and single collection differ for device '%s'", bdev)
+		}
+	}
+}
+
+func TestBlockDeviceStableIDs(t *testing.T) {
+	bdevConf := BlockDev{
+		AllBlockDevs:    []string{"sda", "sdb", "sdc", "sdd"},
+		BlockAttributes: make(map[string]map[string]string),
+	}
+	bdevConf.BlockAttributes["sda"] = map[string]string{"WWN": "naa.5000c500a1b2c3d4", "SERIAL": "S1", "BYID": "scsi-35000c500a1b2c3d4 wwn-0x5000c500a1b2c3d4"}
+	bdevConf.BlockAttributes["sdb"] = map[string]string{"WWN": "", "SERIAL": "S2", "BYID": "ata-QEMU_HARDDISK_S2"}
+	bdevConf.BlockAttributes["sdc"] = map[string]string{"WWN": "", "SERIAL": "S3", "BYID": ""}
+	bdevConf.BlockAttributes["sdd"] = map[string]string{"WWN": "", "SERIAL": "", "BYID": ""}
+	if err := storeBlockDeviceInfo(bdevConf); err != nil {
+		t.Error("storing block device info failed")
+	}
+	exp := map[string]string{"sda": "naa.5000c500a1b2c3d4", "sdb": "ata-QEMU_HARDDISK_S2", "sdc": "S3"}
+	if ids := BlockDeviceStableIDs(); !reflect.DeepEqual(ids, exp) {
+		t.Errorf("expected:'%+v' - actual:'%+v'\n", exp, ids)
+	}
+	if bdev := GetAvailBlockInfo("BYID", ".*wwn-0x5000c500a1b2c3d4.*"); !reflect.DeepEqual(bdev, []string{"sda"}) {
+		t.Errorf("wrong block devices for by-id link: '%+v'", bdev)
+	}
+	if bdev := GetAvailBlockInfo("SERIAL", ".*S[23].*"); !reflect.DeepEqual(bdev, []string{"sdb", "sdc"}) {
+		t.Errorf("wrong block devices for serial: '%+v'", bdev)
+	}
+	// anchored exact match of the stable identifiers (section tags)
+	if bdev := GetAvailBlockInfo("BYID", "^scsi-35000c500a1b2c3d4$"); !reflect.DeepEqual(bdev, []string{"sda"}) {
+		t.Errorf("wrong block devices for by-id link: '%+v'", bdev)
+	}
+	if bdev := GetAvailBlockInfo("BYID", "^wwn-0x5000c500a1b2$"); len(bdev) != 0 {
+		t.Errorf("partial by-id link should not match: '%+v'", bdev)
+	}
+	if bdev := GetAvailBlockInfo("SERIAL", "^S2$"); !reflect.DeepEqual(bdev, []string{"sdb"}) {
+		t.Errorf("wrong block devices for serial: '%+v'", bdev)
+	}
+
+	// exclude by kernel name, wwn, serial and by-id link
+	SetBlockDeviceExcludes("/dev/sdd, naa.5000c500a1b2c3d4 S3,/dev/disk/by-id/ata-QEMU_HARDDISK_S2")
+	defer SetBlockDeviceExcludes("")
+	for _, bdev := range bdevConf.AllBlockDevs {
+		if !isExcludedBlockDevice(bdev, bdevConf.BlockAttributes[bdev]) {
+			t.Errorf("block device '%s' should be excluded", bdev)
+		}
+	}
+	SetBlockDeviceExcludes("sdb")
+	excludeBlockDevices(&bdevConf)
+	if !reflect.DeepEqual(bdevConf.AllBlockDevs, []string{"sda", "sdc", "sdd"}) {
+		t.Errorf("wrong block devices after exclude: '%+v'", bdevConf.AllBlockDevs)
+	}
+	if _, ok := bdevConf.BlockAttributes["sdb"]; ok {
+		t.Error("attributes of excluded block device 'sdb' still available")
+	}
+	_ = os.Remove(path.Join(SaptuneSectionDir, "/blockdev.run"))
+}
+
+func TestBlockDevByIDLinks(t *testing.T) {
+	oldByIDDir := diskByIDDir
+	defer func() { diskByIDDir = oldByIDDir }()
+	diskByIDDir = t.TempDir()
+	for link, target := range map[string]string{"wwn-0x1": "../../sda", "scsi-1": "../../sda", "scsi-1-part1": "../../sda1", "nvme-eui.1": "../../nvme0n1"} {
+		if err := os.Symlink(target, path.Join(diskByIDDir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := blockDevByIDLinks()
+	if !reflect.DeepEqual(links["sda"], []string{"scsi-1", "wwn-0x1"}) || len(links["nvme0n1"]) != 1 || len(links["sda1"]) != 1 {
+		t.Errorf("wrong by-id links: '%+v'", links)
+	}
+}
+
+func TestUdevByIDLinks(t *testing.T) {
+	oldByIDDir := diskByIDDir
+	oldSysBlockDir := sysBlockDir
+	oldUdevDataDir := udevDataDir
+	defer func() {
+		diskByIDDir = oldByIDDir
+		sysBlockDir = oldSysBlockDir
+		udevDataDir = oldUdevDataDir
+	}()
+	// /dev/disk/by-id not available (PrivateDevices=true)
+	diskByIDDir = path.Join(t.TempDir(), "not_avail")
+	sysBlockDir = t.TempDir()
+	udevDataDir = t.TempDir()
+	for bdev, devNum := range map[string]string{"sda": "8:0", "nvme0n1": "259:0", "sdb": "8:16"} {
+		if err := os.MkdirAll(path.Join(sysBlockDir, bdev), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path.Join(sysBlockDir, bdev, "dev"), []byte(devNum+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	udevData := map[string]string{
+		"b8:0":   "S:disk/by-path/pci-0000:00:1f.2-ata-1\nS:disk/by-id/wwn-0x1\nS:disk/by-id/scsi-1\nE:ID_SERIAL=1\n",
+		"b259:0": "S:disk/by-id/nvme-eui.1\n",
+	}
+	for file, content := range udevData {
+		if err := os.WriteFile(path.Join(udevDataDir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := blockDevByIDLinks()
+	if !reflect.DeepEqual(links["sda"], []string{"scsi-1", "wwn-0x1"}) || !reflect.DeepEqual(links["nvme0n1"], []string{"nvme-eui.1"}) || len(links["sdb"]) != 0 {
+		t.Errorf("wrong by-id links: '%+v'", links)
+	}
+}
+
+func TestBlockDeviceStack(t *testing.T) {
+	oldSysBlockDir := sysBlockDir
+	defer func() { sysBlockDir = oldSysBlockDir }()
+	sysBlockDir = t.TempDir()
+	mkdir := func(dirs ...string) {
+		for _, dir := range dirs {
+			if err := os.MkdirAll(path.Join(sysBlockDir, dir), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write := func(file, content string) {
+		if err := os.WriteFile(path.Join(sysBlockDir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := func(target, name string) {
+		if err := os.Symlink(target, path.Join(sysBlockDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// LVM (dm-1) on multipath (dm-0) with the paths sda and sdb
+	// MD RAID (md0) on the partition sdc1, stand alone disk sdd
+	mkdir("sda/holders", "sdb/holders", "sdc/sdc1/holders", "sdd", "dm-0/dm", "dm-0/slaves", "dm-0/holders", "dm-1/dm", "dm-1/slaves", "dm-1/holders", "md0/md", "md0/slaves", "md0/holders")
+	write("dm-0/dm/uuid", "mpath-3600a098038304437\n")
+	write("dm-1/dm/uuid", "LVM-Ak3Xb8jm\n")
+	write("sdc/sdc1/partition", "1\n")
+	link("../../dm-0", "sda/holders/dm-0")
+	link("../../dm-0", "sdb/holders/dm-0")
+	link("../../sda", "dm-0/slaves/sda")
+	link("../../sdb", "dm-0/slaves/sdb")
+	link("../../dm-1", "dm-0/holders/dm-1")
+	link("../../dm-0", "dm-1/slaves/dm-0")
+	link("../../../md0", "sdc/sdc1/holders/md0")
+	link("../../sdc/sdc1", "md0/slaves/sdc1")
+
+	stacks := map[string]string{"sda": "", "dm-0": "mpath", "dm-1": "lvm", "md0": "md", "sdd": ""}
+	for bdev, exp := range stacks {
+		if stack := blockDevStackType(bdev); stack != exp {
+			t.Errorf("'%s': expected stack type '%s', got '%s'", bdev, exp, stack)
+		}
+	}
+	if slaves := blockDevSlaves("dm-0"); !reflect.DeepEqual(slaves, []string{"sda", "sdb"}) {
+		t.Errorf("wrong slaves of dm-0: '%+v'", slaves)
+	}
+	if slaves := blockDevSlaves("md0"); !reflect.DeepEqual(slaves, []string{"sdc"}) {
+		t.Errorf("wrong slaves of md0: '%+v'", slaves)
+	}
+	if holders := blockDevHolders("sdc"); !reflect.DeepEqual(holders, []string{"md0"}) {
+		t.Errorf("wrong holders of sdc: '%+v'", holders)
+	}
+	if holders := blockDevHolders("sdd"); len(holders) != 0 {
+		t.Errorf("wrong holders of sdd: '%+v'", holders)
+	}
+
+	bdevConf := BlockDev{
+		AllBlockDevs:    []string{"dm-0", "dm-1", "md0", "sda", "sdb", "sdc", "sdd"},
+		BlockAttributes: make(map[string]map[string]string),
+	}
+	for _, bdev := range bdevConf.AllBlockDevs {
+		bdevConf.BlockAttributes[bdev] = map[string]string{
+			"STACK":   blockDevStackType(bdev),
+			"SLAVES":  strings.Join(blockDevSlaves(bdev), " "),
+			"HOLDERS": strings.Join(blockDevHolders(bdev), " "),
+		}
+	}
+	if err := storeBlockDeviceInfo(bdevConf); err != nil {
+		t.Error("storing block device info failed")
+	}
+	defer os.Remove(path.Join(SaptuneSectionDir, "/blockdev.run"))
+
+	layers := map[string][]string{
+		"":       {"dm-0", "sdc", "sdd"},
+		"top":    {"dm-1", "md0", "sdd"},
+		"leaves": {"sda", "sdb", "sdc", "sdd"},
+		"both":   bdevConf.AllBlockDevs,
+	}
+	for layer, exp := range layers {
+		if bdevs := SelectBlockDeviceLayer(bdevConf.AllBlockDevs, layer); !reflect.DeepEqual(bdevs, exp) {
+			t.Errorf("layer '%s': expected '%+v', got '%+v'", layer, exp, bdevs)
+		}
+	}
+	if rel := blockStackRelatives(&bdevConf, "sda"); !reflect.DeepEqual(rel, []string{"dm-0", "dm-1"}) {
+		t.Errorf("wrong relatives of sda: '%+v'", rel)
+	}
+	if rel := blockStackRelatives(&bdevConf, "dm-0"); !reflect.DeepEqual(rel, []string{"dm-1", "sda", "sdb"}) {
+		t.Errorf("wrong relatives of dm-0: '%+v'", rel)
+	}
+	if diffs := BlockStackDiffs("vm.swappiness"); len(diffs) != 0 {
+		t.Errorf("unexpected layer diffs '%+v'", diffs)
+	}
+}
+
+func TestBlockTunables(t *testing.T) {
+	if names := BlockTunableNames(); len(names) != len(BlockTunables) || names[0] != "ADD_RANDOM" {
+		t.Errorf("wrong tunable names '%+v'", names)
+	}
+	keys := map[string][]string{
+		"READ_EXPIRE_sda":      {"READ_EXPIRE", "sda"},
+		"WBT_LAT_USEC_nvme0n1": {"WBT_LAT_USEC", "nvme0n1"},
+		"RQ_AFFINITY_dm-0":     {"RQ_AFFINITY", "dm-0"},
+		"READ_AHEAD_KB_sda":    {"", ""},
+		"SLICE_IDLE":           {"", ""},
+	}
+	for key, exp := range keys {
+		if name, bdev := GetBlockTunable(key); name != exp[0] || bdev != exp[1] {
+			t.Errorf("'%s': expected '%+v', got '%s', '%s'", key, exp, name, bdev)
+		}
+	}
+	if key := BlockTunableKey("SLICE_IDLE", "sdb"); key != "block/sdb/queue/iosched/slice_idle" {
+		t.Errorf("wrong sys key '%s'", key)
+	}
+	devs := map[string]string{"IO_SCHEDULER_sda": "sda", "NRREQ_vda": "vda", "MAX_SECTORS_KB_dm-1": "dm-1", "FIFO_BATCH_sdc": "sdc", "vm.swappiness": ""}
+	for key, exp := range devs {
+		if bdev := BlockKeyDevice(key); bdev != exp {
+			t.Errorf("'%s': expected device '%s', got '%s'", key, exp, bdev)
+		}
+	}
+	if param, sect := GetSysSearchParam("READ_EXPIRE_sda"); param != "sys:block.sda.queue.iosched.read_expire" || sect != "sys" {
+		t.Errorf("wrong search param '%s', '%s'", param, sect)
+	}
+	if param, sect := GetSysSearchParam("block.sda.queue.iosched.read_expire"); param != "READ_EXPIRE_sda" || sect != "block" {
+		t.Errorf("wrong search param '%s', '%s'", param, sect)
+	}
+	if param, sect := GetSysSearchParam("block.sda.queue.rq_affinity"); param != "RQ_AFFINITY_sda" || sect != "block" {
+		t.Errorf("wrong search param '%s', '%s'", param, sect)
+	}
+}
+
+func TestBlockQueueAttributesLive(t *testing.T) {
+	oldSysBlockDir := sysBlockDir
+	oldChangedDir := blockDevChangedDir
+	defer func() {
+		sysBlockDir = oldSysBlockDir
+		blockDevChangedDir = oldChangedDir
+	}()
+	sysBlockDir = t.TempDir()
+	blockDevChangedDir = t.TempDir()
+	write := func(file, content string) {
+		if err := os.MkdirAll(path.Dir(path.Join(sysBlockDir, file)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path.Join(sysBlockDir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("sda/queue/scheduler", "none [mq-deadline] kyber bfq\n")
+	write("sda/queue/nr_requests", "64\n")
+	write("sda/queue/read_ahead_kb", "128\n")
+	write("sda/queue/max_sectors_kb", "512\n")
+	write("sda/queue/iosched/read_expire", "500\n")
+
+	bdevConf := BlockDev{
+		AllBlockDevs:    []string{"sda"},
+		BlockAttributes: map[string]map[string]string{"sda": BlockQueueAttributes("sda")},
+	}
+	bdevConf.BlockAttributes["sda"]["WWN"] = "naa.5000c500a1b2c3d4"
+	if err := storeBlockDeviceInfo(bdevConf); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path.Join(SaptuneSectionDir, "/blockdev.run"))
+	blkDev, _ := GetBlockDeviceInfo()
+	if attrs := blkDev.BlockAttributes["sda"]; attrs["IO_SCHEDULER"] != "mq-deadline" || attrs["READ_AHEAD_KB"] != "128" || attrs["READ_EXPIRE"] != "500" {
+		t.Errorf("wrong queue attributes '%+v'", attrs)
+	}
+
+	// queue values written without an udev event (e.g. by apply)
+	write("sda/queue/read_ahead_kb", "4096\n")
+	write("sda/queue/scheduler", "[none] mq-deadline kyber bfq\n")
+	if err := os.RemoveAll(path.Join(sysBlockDir, "sda/queue/iosched")); err != nil {
+		t.Fatal(err)
+	}
+	blkDev, _ = GetBlockDeviceInfo()
+	attrs := blkDev.BlockAttributes["sda"]
+	if attrs["READ_AHEAD_KB"] != "4096" || attrs["IO_SCHEDULER"] != "none" || attrs["READ_EXPIRE"] != "" {
+		t.Errorf("stale queue attributes '%+v'", attrs)
+	}
+	if attrs["WWN"] != "naa.5000c500a1b2c3d4" || attrs["NRREQ"] != "64" {
+		t.Errorf("wrong stored attributes '%+v'", attrs)
+	}
+	if val := BlockQueueValue("sda", "read_ahead_kb"); val != "4096" {
+		t.Errorf("expected '4096', got '%s'", val)
+	}
+	if val := BlockQueueValue("sdz", "read_ahead_kb"); val != "" {
+		t.Errorf("expected empty value for missing device, got '%s'", val)
+	}
+}
+
+func TestBlockDeviceExcludeOnRead(t *testing.T) {
+	oldChangedDir := blockDevChangedDir
+	defer func() {
+		blockDevChangedDir = oldChangedDir
+		SetBlockDeviceExcludes("")
+	}()
+	blockDevChangedDir = t.TempDir()
+	bdevConf := BlockDev{
+		AllBlockDevs:    []string{"sda", "sdb"},
+		BlockAttributes: map[string]map[string]string{"sda": {"SERIAL": "S1"}, "sdb": {"SERIAL": "S2"}},
+	}
+	if err := storeBlockDeviceInfo(bdevConf); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path.Join(SaptuneSectionDir, "/blockdev.run"))
+
+	// newly excluded device, without a new collection
+	SetBlockDeviceExcludes("S2")
+	if blkDev, _ := GetBlockDeviceInfo(); !reflect.DeepEqual(blkDev.AllBlockDevs, []string{"sda"}) {
+		t.Errorf("excluded device still available: '%+v'", blkDev.AllBlockDevs)
+	}
+	if bdevs := GetAvailBlockInfo("pat", "^sd.*"); !reflect.DeepEqual(bdevs, []string{"sda"}) {
+		t.Errorf("excluded device still matched: '%+v'", bdevs)
+	}
+	// no longer excluded device comes back
+	SetBlockDeviceExcludes("")
+	blkDev, _ := GetBlockDeviceInfo()
+	if !reflect.DeepEqual(blkDev.AllBlockDevs, []string{"sda", "sdb"}) || blkDev.BlockAttributes["sdb"]["SERIAL"] != "S2" {
+		t.Errorf("un-excluded device not available: '%+v'", blkDev)
+	}
+}
@@ -17,6 +17,7 @@ var defaultCommand = map[string]bool{
 	"service disable":             false,
 	"service enablestart":         false,
 	"service disablestop":         false,
+	"service watch":               false,
 	"note list":                   false,
 	"note revertall":              false,
 	"note enabled":                false,
@@ -59,6 +60,9 @@ var defaultCommand = map[string]bool{
 	"configure IGNORE_RELOAD":     false,
 	"configure DEBUG":             false,
 	"configure TrentoASDP":        false,
+	"configure WATCH_INTERVAL":    false,
+	"configure DRIFT_REMEDIATION": false,
+	"configure PRISTINE_BASELINE": false,
 	"configure reset":             false,
 	"configure show":              false,
 	"refresh applied":             false,
@@ -119,6 +123,7 @@ func lockCommandsMap() map[string]bool {
 	lockCommand["service takeover"] = true
 	lockCommand["service enablestart"] = true
 	lockCommand["service disablestop"] = true
+	lockCommand["service watch"] = true
 	lockCommand["note revertall"] = true
 	lockCommand["note apply"] = true
 	lockCommand["note customise"] = true
@@ -146,6 +151,7 @@ func lockCommandsMap() map[string]bool {
 	lockCommand["staging release"] = true
 	lockCommand["configure reset"] = true
 	lockCommand["configure TrentoASDP"] = true
+	lockCommand["configure WATCH_INTERVAL"] = true
 	lockCommand["refresh applied"] = true
 	lockCommand["revert all"] = true
 
@@ -0,0 +1,202 @@
+package system
+
+// handling of the core dump settings
+// kernel.core_pattern and the systemd-coredump configuration coredump.conf
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// CoredumpConfDir is the drop-in directory of the systemd-coredump
+// configuration coredump.conf
+var CoredumpConfDir = "/etc/systemd/coredump.conf.d"
+
+// coredumpMainConfs are the locations of the main coredump configuration
+// file in the order of their priority. Only the first existing file is used
+var coredumpMainConfs = []string{"/etc/systemd/coredump.conf", "/run/systemd/coredump.conf", "/usr/local/lib/systemd/coredump.conf", "/usr/lib/systemd/coredump.conf", "/usr/etc/systemd/coredump.conf"}
+
+// coredumpDropInDirs are the locations of the coredump drop-in files in the
+// order of their priority
+var coredumpDropInDirs = []string{"/etc/systemd/coredump.conf.d", "/run/systemd/coredump.conf.d", "/usr/local/lib/systemd/coredump.conf.d", "/usr/lib/systemd/coredump.conf.d", "/usr/etc/systemd/coredump.conf.d"}
+
+// coredumpDir is the storage directory of systemd-coredump for
+// 'Storage=external'
+var coredumpDir = "/var/lib/systemd/coredump"
+
+// journalDirs are the storage directories of the journal for
+// 'Storage=journal' (persistent and volatile)
+var journalDirs = []string{"/var/log/journal", "/run/log/journal"}
+
+// fsFreeSpace is used to mock the free space of a file system in the tests
+var fsFreeSpace = freeSpace
+
+// coredumpDefaults contains the compiled-in defaults of systemd-coredump
+// on 64bit systems. An empty value means 'calculated by systemd-coredump'
+var coredumpDefaults = map[string]string{
+	"Storage":         "external",
+	"ProcessSizeMax":  "32G",
+	"ExternalSizeMax": "32G",
+	"JournalSizeMax":  "767M",
+	"MaxUse":          "",
+}
+
+// coredumpKeys are the keys of coredump.conf, which can be used in the
+// [coredump] section
+var coredumpKeys = []string{"Storage", "ProcessSizeMax", "ExternalSizeMax", "MaxUse"}
+
+// IsCoredumpKey checks, if the key is a supported key of coredump.conf
+func IsCoredumpKey(key string) bool {
+	for _, k := range coredumpKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCoredumpValue returns the effective value of a coredump.conf key of
+// the merged systemd-coredump configuration or the compiled-in default
+func GetCoredumpValue(key string) string {
+	if val, ok := systemdConfValue(coredumpMainConfs, coredumpDropInDirs, "Coredump", key); ok {
+		return val
+	}
+	return coredumpDefaults[key]
+}
+
+// IsValidCoredumpValue checks, if the value is valid for the
+// coredump.conf key
+func IsValidCoredumpValue(key, value string) bool {
+	switch key {
+	case "Storage":
+		return value == "none" || value == "external" || value == "journal"
+	case "ProcessSizeMax", "ExternalSizeMax", "JournalSizeMax", "MaxUse":
+		_, err := CoredumpSize(value)
+		return err == nil
+	}
+	return false
+}
+
+// CoredumpSize converts a size value of coredump.conf like '32G' or
+// 'infinity' into bytes. math.MaxInt64 means 'infinity'
+func CoredumpSize(value string) (int64, error) {
+	return parseLimit("coredump", value)
+}
+
+// CoreSizeToBytes converts the expected size of a full core into bytes.
+// The size can be given with the units of coredump.conf (e.g. '512G') or
+// as percentage of the main memory (e.g. '100%')
+func CoreSizeToBytes(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasSuffix(value, "%") {
+		return CoredumpSize(value)
+	}
+	pct, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || pct <= 0 {
+		return 0, fmt.Errorf("wrong core size '%s'", value)
+	}
+	return int64(float64(GetMainMemSizeMB()) * 1048576 * pct / 100), nil
+}
+
+// CoredumpVerdict checks, if a full core of 'size' bytes could actually be
+// written with the current core dump configuration (kernel.core_pattern,
+// systemd-coredump settings and core file size limit) and the free space
+// of the storage path. 'coreLimit' is the core file size limit in bytes,
+// math.MaxInt64 means 'unlimited', a negative value means 'unknown'.
+// Returns an empty string, if the core fits, otherwise the reason
+func CoredumpVerdict(size, coreLimit int64) string {
+	if coreLimit >= 0 && coreLimit < size {
+		return fmt.Sprintf("truncated (core_limit %d)", coreLimit/1024)
+	}
+	pattern, err := GetSysctlString("kernel.core_pattern")
+	if err != nil {
+		return "unknown (kernel.core_pattern not available)"
+	}
+	storageDir := ""
+	switch {
+	case strings.HasPrefix(pattern, "|"):
+		handler := strings.Fields(strings.TrimPrefix(pattern, "|"))
+		if len(handler) == 0 || path.Base(handler[0]) != "systemd-coredump" {
+			return fmt.Sprintf("unknown (core piped to '%s')", strings.Join(handler, " "))
+		}
+		storageDir, err = coredumpStorage(size)
+		if err != nil {
+			return err.Error()
+		}
+	case strings.HasPrefix(pattern, "/"):
+		storageDir = path.Dir(pattern)
+	default:
+		return fmt.Sprintf("unknown (relative core_pattern '%s')", pattern)
+	}
+	free, err := fsFreeSpace(storageDir)
+	if err != nil {
+		return fmt.Sprintf("unknown (free space of '%s' not available)", storageDir)
+	}
+	if free < size {
+		return fmt.Sprintf("no space (%dM free in %s)", free/1048576, storageDir)
+	}
+	return ""
+}
+
+// coredumpStorage checks the systemd-coredump settings for a core of
+// 'size' bytes and returns the storage directory
+func coredumpStorage(size int64) (string, error) {
+	storage := GetCoredumpValue("Storage")
+	if storage == "none" {
+		return "", fmt.Errorf("not stored (Storage=none)")
+	}
+	sizeKeys := []string{"ProcessSizeMax", "ExternalSizeMax"}
+	storageDir := coredumpDir
+	if storage == "journal" {
+		sizeKeys = []string{"ProcessSizeMax", "JournalSizeMax"}
+		storageDir = journalDirs[len(journalDirs)-1]
+		for _, dir := range journalDirs {
+			if _, err := os.Stat(dir); err == nil {
+				storageDir = dir
+				break
+			}
+		}
+	}
+	for _, key := range sizeKeys {
+		val := GetCoredumpValue(key)
+		max, err := CoredumpSize(val)
+		if err != nil {
+			return "", fmt.Errorf("unknown (%s=%s)", key, val)
+		}
+		if max < size {
+			return "", fmt.Errorf("truncated (%s=%s)", key, val)
+		}
+	}
+	if val := GetCoredumpValue("MaxUse"); val != "" {
+		if maxUse, err := CoredumpSize(val); err == nil && maxUse < size {
+			WarningLog("a full core exceeds MaxUse=%s of systemd-coredump, older core files will be removed", val)
+		}
+	}
+	return storageDir, nil
+}
+
+// freeSpace returns the free space in bytes of the file system containing
+// the directory. If the directory does not exist, the next existing parent
+// directory is used
+func freeSpace(dir string) (int64, error) {
+	for {
+		if _, err := os.Stat(dir); err == nil || dir == "/" || dir == "." {
+			break
+		}
+		dir = path.Dir(dir)
+	}
+	fs := syscall.Statfs_t{}
+	if err := syscall.Statfs(dir, &fs); err != nil {
+		return 0, err
+	}
+	free := float64(fs.Bavail) * float64(fs.Bsize)
+	if free >= math.MaxInt64 {
+		return math.MaxInt64, nil
+	}
+	return int64(free), nil
+}
@@ -0,0 +1,114 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestGetCoredumpValue(t *testing.T) {
+	oldMainConfs := coredumpMainConfs
+	oldDropInDirs := coredumpDropInDirs
+	defer func() {
+		coredumpMainConfs = oldMainConfs
+		coredumpDropInDirs = oldDropInDirs
+	}()
+	tstDir := path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/coredump")
+	coredumpMainConfs = []string{"/tmp/saptune_test_coredump/coredump.conf", path.Join(tstDir, "coredump.conf")}
+	coredumpDropInDirs = []string{path.Join(tstDir, "coredump.conf.d")}
+
+	vals := map[string]string{"Storage": "external", "ProcessSizeMax": "64G", "ExternalSizeMax": "16G", "MaxUse": ""}
+	for key, exp := range vals {
+		if val := GetCoredumpValue(key); val != exp {
+			t.Errorf("%s - expected: '%s', got: '%s'", key, exp, val)
+		}
+	}
+	if !IsCoredumpKey("MaxUse") || IsCoredumpKey("Compress") {
+		t.Error("wrong coredump keys")
+	}
+	if !IsValidCoredumpValue("Storage", "journal") || IsValidCoredumpValue("Storage", "disk") {
+		t.Error("wrong check of 'Storage'")
+	}
+	if !IsValidCoredumpValue("ProcessSizeMax", "infinity") || IsValidCoredumpValue("ProcessSizeMax", "32X") {
+		t.Error("wrong check of 'ProcessSizeMax'")
+	}
+	if size, err := CoreSizeToBytes("8G"); err != nil || size != 8<<30 {
+		t.Error(size, err)
+	}
+	if _, err := CoreSizeToBytes("abc%"); err == nil {
+		t.Error("'abc%' should be reported as error")
+	}
+}
+
+func TestCoredumpVerdict(t *testing.T) {
+	oldMainConfs := coredumpMainConfs
+	oldDropInDirs := coredumpDropInDirs
+	oldFreeSpace := fsFreeSpace
+	defer func() {
+		coredumpMainConfs = oldMainConfs
+		coredumpDropInDirs = oldDropInDirs
+		fsFreeSpace = oldFreeSpace
+		DisableFactCache()
+	}()
+	tstDir := path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/coredump")
+	tmpDir := "/tmp/saptune_test_coredump"
+	defer os.RemoveAll(tmpDir)
+	coredumpMainConfs = []string{path.Join(tstDir, "coredump.conf")}
+	coredumpDropInDirs = []string{tmpDir, path.Join(tstDir, "coredump.conf.d")}
+	fsFreeSpace = func(dir string) (int64, error) {
+		if dir == "/var/crash" {
+			return 4 << 30, nil
+		}
+		if dir == "/nofs" {
+			return 0, fmt.Errorf("no file system")
+		}
+		return 100 << 30, nil
+	}
+	EnableFactCache()
+
+	tests := []struct {
+		pattern     string
+		size, limit int64
+		exp         string
+	}{
+		{"|/usr/lib/systemd/systemd-coredump %P %u %g %s %t %c %h", 8 << 30, -1, ""},
+		{"|/usr/lib/systemd/systemd-coredump %P %u %g %s %t %c %h", 20 << 30, -1, "truncated (ExternalSizeMax=16G)"},
+		{"|/usr/lib/systemd/systemd-coredump %P %u %g %s %t %c %h", 8 << 30, 1 << 30, "truncated (core_limit 1048576)"},
+		{"/var/crash/core.%e.%p", 2 << 30, -1, ""},
+		{"/var/crash/core.%e.%p", 8 << 30, -1, "no space (4096M free in /var/crash)"},
+		{"/nofs/core", 8 << 30, -1, "unknown (free space of '/nofs' not available)"},
+		{"|/usr/share/apport/apport %p", 8 << 30, -1, "unknown (core piped to '/usr/share/apport/apport %p')"},
+		{"core", 8 << 30, -1, "unknown (relative core_pattern 'core')"},
+	}
+	for _, tst := range tests {
+		storeFact("/proc/sys", "kernel.core_pattern", tst.pattern, nil)
+		if val := CoredumpVerdict(tst.size, tst.limit); val != tst.exp {
+			t.Errorf("'%s' - expected: '%s', got: '%s'", tst.pattern, tst.exp, val)
+		}
+	}
+
+	storeFact("/proc/sys", "kernel.core_pattern", "|/usr/lib/systemd/systemd-coredump %P", nil)
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(tmpDir, "saptune-Storage.conf"), []byte("[Coredump]\nStorage=none\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if val := CoredumpVerdict(8<<30, -1); val != "not stored (Storage=none)" {
+		t.Error(val)
+	}
+	if err := os.WriteFile(path.Join(tmpDir, "saptune-Storage.conf"), []byte("[Coredump]\nStorage=journal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if val := CoredumpVerdict(8<<30, -1); val != "truncated (JournalSizeMax=767M)" {
+		t.Error(val)
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	free, err := freeSpace("/tmp/saptune_test_not_existing/dir")
+	if err != nil || free <= 0 {
+		t.Error(free, err)
+	}
+}
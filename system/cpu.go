@@ -1,6 +1,6 @@
 package system
 
-// wrapper to cpupower command
+// wrapper to cpupower command and the cpu related sysfs files
 
 import (
 	"encoding/binary"
@@ -17,9 +17,7 @@ import (
 
 // constant definition
 const (
-	notSupportedX86 = "System does not support Intel's performance bias setting"
-	notSupportedIBM = "Subcommand not supported on POWER."
-	cpuDirSys       = "devices/system/cpu"
+	cpuDirSys = "devices/system/cpu"
 )
 
 var cpuPlatformFile = "/sys/devices/cpu/caps/pmu_name"
@@ -30,6 +28,7 @@ var isCPU = regexp.MustCompile(`^cpu\d+$`)
 var isState = regexp.MustCompile(`^state\d+$`)
 var perfCnt = 0
 var govCnt = 0
+var eppCnt = 0
 var latCnt = 0
 
 // C-state latency table
@@ -37,77 +36,25 @@ var cslatTable = map[string]int{}
 var cslatTabCnt = 0
 
 // GetPerfBias retrieve CPU performance configuration from the system
+// read /sys/devices/system/cpu/cpu*/power/energy_perf_bias
 func GetPerfBias() string {
-	isPBCpu := regexp.MustCompile(`analyzing CPU \d+`)
-	isPBias := regexp.MustCompile(`perf-bias: \d+`)
-	setAll := true
-	str := ""
-	oldpb := "99"
-	cmdName := cpupowerCmd
-	cmdArgs := []string{"-c", "all", "info", "-b"}
-
 	if !supportsPerfBiasSettings() {
 		return "all:none"
 	}
-
-	cmdOut, err := exec.Command(cmdName, cmdArgs...).CombinedOutput()
-	if err != nil {
-		WarningLog("There was an error running external command 'cpupower -c all info -b': %v, output: %s", err, cmdOut)
+	pBias := getCPUValues(perfBiasFile)
+	if len(pBias) == 0 {
 		return "all:none"
 	}
-
-	for k, line := range strings.Split(strings.TrimSpace(string(cmdOut)), "\n") {
-		switch {
-		case line == notSupportedX86 || line == notSupportedIBM:
-			// safety net - check already done in supportsPerfBiasSettings()
-			return "all:none"
-		case isPBCpu.MatchString(line):
-			str = str + fmt.Sprintf("cpu%d", k/2)
-		case isPBias.MatchString(line):
-			pb := strings.Split(line, ":")
-			if len(pb) < 2 {
-				continue
-			}
-			if oldpb == "99" {
-				oldpb = strings.TrimSpace(pb[1])
-			}
-			if oldpb != strings.TrimSpace(pb[1]) {
-				setAll = false
-			}
-			str = str + fmt.Sprintf(":%s ", strings.TrimSpace(pb[1]))
-		}
-	}
-	if setAll {
-		str = "all:" + oldpb
-	}
-	return strings.TrimSpace(str)
+	return CPUValString(groupCPUValues(pBias))
 }
 
-// SetPerfBias set CPU performance configuration to the system using 'cpupower' command
+// SetPerfBias set CPU performance configuration to the system
+// write /sys/devices/system/cpu/cpu*/power/energy_perf_bias
 func SetPerfBias(value string) error {
-	//cmd := exec.Command("cpupower", "-c", "all", "set", "-b", value)
 	if !supportsPerfBiasSettings() {
 		return nil
 	}
-
-	cpu := ""
-	for k, entry := range strings.Fields(value) {
-		fields := strings.Split(entry, ":")
-		if len(fields) < 2 {
-			continue
-		}
-		if fields[0] != "all" {
-			cpu = strconv.Itoa(k)
-		} else {
-			cpu = fields[0]
-		}
-		out, err := exec.Command(cpupowerCmd, "-c", cpu, "set", "-b", fields[1]).CombinedOutput()
-		if err != nil {
-			WarningLog("failed to invoke external command 'cpupower -c %s set -b %s': %v, output: %s", cpu, fields[1], err, out)
-			return err
-		}
-	}
-	return nil
+	return setCPUValues(value, perfBiasFile, isValidPerfBias)
 }
 
 // supportsPerfBiasSettings checks, if Perf Bias is supported for the system
@@ -161,17 +108,11 @@ func SecureBootEnabled() bool {
 }
 
 // supportsPerfBias check, if the system will support CPU performance settings
+// the kernel offers the Energy Performance Bias in
+// /sys/devices/system/cpu/cpu*/power/energy_perf_bias, if the cpu supports it
 func supportsPerfBias() bool {
-	cmdName := cpupowerCmd
-	cmdArgs := []string{"info", "-b"}
-
-	if !CmdIsAvailable(cmdName) {
-		WarningLog("command '%s' not found", cmdName)
-		return false
-	}
-	cmdOut, err := exec.Command(cmdName, cmdArgs...).CombinedOutput()
-	PrintLog(perfCnt, "info", "supportsPerfBias - command 'cpupower info -b' returned: %s - %v", string(cmdOut), err)
-	if err != nil || (err == nil && (strings.Contains(string(cmdOut), notSupportedX86) || strings.Contains(string(cmdOut), notSupportedIBM))) {
+	if _, err := os.Stat(perfBiasFile("cpu0")); err != nil {
+		PrintLog(perfCnt, "info", "supportsPerfBias - file '%s' not available - %v", perfBiasFile("cpu0"), err)
 		// does not support perf bias
 		if SecureBootEnabled() {
 			PrintLog(perfCnt, "warn", "Cannot set Perf Bias when SecureBoot is enabled, skipping")
@@ -183,6 +124,18 @@ func supportsPerfBias() bool {
 	return true
 }
 
+// perfBiasFile returns the path of the energy_perf_bias file of a cpu
+func perfBiasFile(cpu string) string {
+	return path.Join(cpuDir, cpu, "power", "energy_perf_bias")
+}
+
+// isValidPerfBias checks, if the value is a valid Energy Performance Bias
+// (0 - 15)
+func isValidPerfBias(cpu, value string) bool {
+	pb, err := strconv.Atoi(value)
+	return err == nil && pb >= 0 && pb <= 15
+}
+
 // GetGovernor retrieve performance configuration regarding to cpu frequency
 // from the system
 func GetGovernor() map[string]string {
@@ -379,6 +332,238 @@ func isValidGovernor(cpu, gov string) bool {
 	return false
 }
 
+// GetEPP retrieve the Energy Performance Preference EPP of the cpus
+// from the system
+// read /sys/devices/system/cpu/cpu*/cpufreq/energy_performance_preference
+func GetEPP() map[string]string {
+	gEPP := make(map[string]string)
+	if !supportsEPPSettings("") {
+		gEPP["all"] = "none"
+		return gEPP
+	}
+	epp := getCPUValues(eppFile)
+	if len(epp) == 0 {
+		gEPP["all"] = "none"
+		return gEPP
+	}
+	return groupCPUValues(epp)
+}
+
+// SetEPP set the Energy Performance Preference EPP of the cpus
+// write /sys/devices/system/cpu/cpu*/cpufreq/energy_performance_preference
+func SetEPP(value string) error {
+	if !supportsEPPSettings(value) {
+		return nil
+	}
+	return setCPUValues(value, eppFile, isValidEPP)
+}
+
+// supportsEPPSettings checks, if the Energy Performance Preference is
+// supported by the system. Only the drivers intel_pstate and amd-pstate in
+// active mode (amd-pstate-epp) offer the EPP, acpi-cpufreq and the passive
+// modes of the pstate drivers do not.
+func supportsEPPSettings(value string) bool {
+	setEPP := true
+	driver := CPUFreqDriver()
+	switch {
+	case value == "all:none":
+		PrintLog(eppCnt, "warn", "Energy Performance Preference settings not supported by the system")
+		setEPP = false
+	case driver != "intel_pstate" && driver != "amd-pstate-epp":
+		PrintLog(eppCnt, "info", "CPU driver '%s' does not support Energy Performance Preference settings", driver)
+		setEPP = false
+	default:
+		if _, err := os.Stat(eppFile("cpu0")); err != nil {
+			// check only first cpu - cpu0, not all
+			PrintLog(eppCnt, "warn", "Energy Performance Preference settings not supported by the system. Missing file '%s'", eppFile("cpu0"))
+			setEPP = false
+		}
+	}
+	if eppCnt == 0 {
+		eppCnt++
+	}
+	return setEPP
+}
+
+// eppFile returns the path of the energy_performance_preference file of a cpu
+func eppFile(cpu string) string {
+	return path.Join(cpuDir, cpu, "cpufreq", "energy_performance_preference")
+}
+
+// isValidEPP checks, if the value is listed in
+// /sys/devices/system/cpu/cpu*/cpufreq/energy_performance_available_preferences
+// intel_pstate additionally accepts raw EPP values between 0 and 255
+func isValidEPP(cpu, value string) bool {
+	val, err := os.ReadFile(path.Join(cpuDir, cpu, "cpufreq", "energy_performance_available_preferences"))
+	if err != nil {
+		return false
+	}
+	for _, pref := range strings.Fields(string(val)) {
+		if pref == value {
+			return true
+		}
+	}
+	if CPUFreqDriver() == "intel_pstate" {
+		if epp, err := strconv.Atoi(value); err == nil && epp >= 0 && epp <= 255 {
+			return true
+		}
+	}
+	return false
+}
+
+// CPUFreqDriver returns the cpufreq scaling driver of the system, e.g.
+// intel_pstate, intel_cpufreq, amd-pstate, amd-pstate-epp or acpi-cpufreq
+// read /sys/devices/system/cpu/cpu0/cpufreq/scaling_driver
+func CPUFreqDriver() string {
+	val, err := os.ReadFile(path.Join(cpuDir, "cpu0", "cpufreq", "scaling_driver"))
+	if err != nil || strings.TrimSpace(string(val)) == "" {
+		return "none"
+	}
+	return strings.TrimSpace(string(val))
+}
+
+// onlineCPUs returns the names of the online cpus sorted by cpu number
+func onlineCPUs() []string {
+	cpus := []string{}
+	dirCont, err := os.ReadDir(cpuDir)
+	if err != nil {
+		return cpus
+	}
+	for _, entry := range dirCont {
+		if isCPU.MatchString(entry.Name()) && isCPUonline(entry.Name()) {
+			cpus = append(cpus, entry.Name())
+		}
+	}
+	sort.Slice(cpus, func(i, j int) bool { return cpuNumber(cpus[i]) < cpuNumber(cpus[j]) })
+	return cpus
+}
+
+// PossibleCPUs returns the number of possible cpus of the system from
+// /sys/devices/system/cpu/possible (e.g. '0-63'). In contrast to the number
+// of online cpus the value does not change with cpu hotplug or SMT
+// settings. Returns 0, if the file is not available
+func PossibleCPUs() int {
+	val, err := os.ReadFile(path.Join(cpuDir, "possible"))
+	if err != nil {
+		return 0
+	}
+	return cpuListCount(strings.TrimSpace(string(val)))
+}
+
+// cpuListCount returns the number of cpus of a cpu list like '0-3,8,10-11'
+func cpuListCount(list string) int {
+	cnt := 0
+	for _, cpuRange := range strings.Split(list, ",") {
+		bounds := strings.SplitN(cpuRange, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				continue
+			}
+		}
+		cnt = cnt + last - first + 1
+	}
+	return cnt
+}
+
+// cpuNumber returns the number of a cpu name like 'cpu12'
+// 'all' and unknown names are sorted first (-1)
+func cpuNumber(cpu string) int {
+	num, err := strconv.Atoi(strings.TrimPrefix(cpu, "cpu"))
+	if err != nil {
+		return -1
+	}
+	return num
+}
+
+// getCPUValues reads the per cpu file returned by cpuFile for all online
+// cpus. Missing or unreadable files are reported as 'none'
+func getCPUValues(cpuFile func(string) string) map[string]string {
+	vals := make(map[string]string)
+	for _, cpu := range onlineCPUs() {
+		val, err := readCachedFile(cpuFile(cpu))
+		if err != nil || strings.TrimSpace(val) == "" {
+			InfoLog("Unable to read '%s' for CPU '%s' - %v", cpuFile(cpu), cpu, err)
+			vals[cpu] = "none"
+			continue
+		}
+		vals[cpu] = strings.TrimSpace(val)
+	}
+	return vals
+}
+
+// groupCPUValues returns 'all:<value>', if all cpus share the same value.
+// Otherwise the values of all cpus are returned
+func groupCPUValues(vals map[string]string) map[string]string {
+	oldval := ""
+	for _, val := range vals {
+		if oldval == "" {
+			oldval = val
+		}
+		if oldval != val {
+			return vals
+		}
+	}
+	return map[string]string{"all": oldval}
+}
+
+// CPUValString returns the per cpu values as string sorted by cpu number
+// like 'cpu0:performance cpu1:powersave cpu2:performance'
+func CPUValString(vals map[string]string) string {
+	cpus := make([]string, 0, len(vals))
+	for cpu := range vals {
+		cpus = append(cpus, cpu)
+	}
+	sort.Slice(cpus, func(i, j int) bool { return cpuNumber(cpus[i]) < cpuNumber(cpus[j]) })
+	str := ""
+	for _, cpu := range cpus {
+		str = str + fmt.Sprintf("%s:%s ", cpu, vals[cpu])
+	}
+	return strings.TrimSpace(str)
+}
+
+// setCPUValues writes the values of a string like 'all:<value>' or
+// 'cpu0:<value> cpu1:<value>' to the per cpu file returned by cpuFile.
+// 'all' is set for all online cpus, offline cpus and invalid values are
+// skipped. A failed write does not stop the writes of the remaining cpus,
+// the errors of all cpus are returned together
+func setCPUValues(value string, cpuFile func(string) string, isValid func(string, string) bool) error {
+	failed := []string{}
+	for _, entry := range strings.Fields(value) {
+		fields := strings.Split(entry, ":")
+		if len(fields) < 2 || fields[1] == "none" {
+			continue
+		}
+		cpus := []string{fields[0]}
+		if fields[0] == "all" {
+			cpus = onlineCPUs()
+		}
+		for _, cpu := range cpus {
+			if !isCPUonline(cpu) {
+				DebugLog("setCPUValues - Skipping CPU '%s' because it's offline", cpu)
+				continue
+			}
+			if !isValid(cpu, fields[1]) {
+				WarningLog("'%s' is not a valid value for '%s' of cpu '%s', skipping.", fields[1], path.Base(cpuFile(cpu)), cpu)
+				continue
+			}
+			forgetFact("file", cpuFile(cpu))
+			if err := os.WriteFile(cpuFile(cpu), []byte(fields[1]), 0644); err != nil {
+				WarningLog("failed to set '%s' in '%s': %v", fields[1], cpuFile(cpu), err)
+				failed = append(failed, err.Error())
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
 // GetFLInfo retrieve CPU latency configuration from the system and returns
 // the current latency,
 // the latency states of all CPUs to save Latency states for 'revert',
@@ -3,7 +3,6 @@ package system
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path"
 	"strings"
 	"testing"
@@ -32,12 +31,8 @@ func TestSupportsPerfBias(t *testing.T) {
 	if !supportsPerfBias() {
 		t.Skip("System does not support Intel's performance bias setting. Skipping test")
 	}
-	cmdName := "/usr/bin/cpupower"
-	cmdArgs := []string{"info", "-b"}
-
-	cmdOut, err := exec.Command(cmdName, cmdArgs...).CombinedOutput()
-	if err != nil || (err == nil && (strings.Contains(string(cmdOut), notSupportedX86) || strings.Contains(string(cmdOut), notSupportedIBM))) {
-		t.Error(string(cmdOut))
+	if _, err := os.Stat("/sys/devices/system/cpu/cpu0/power/energy_perf_bias"); err != nil {
+		t.Error(err)
 	}
 }
 
@@ -236,6 +231,94 @@ func TestIsCPUonline(t *testing.T) {
 	cpuDir = oldCPUDir
 }
 
+func TestPerfBiasAndEPP(t *testing.T) {
+	oldCPUDir := cpuDir
+	defer func() { cpuDir = oldCPUDir }()
+	cpuDir = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/cpu-epp")
+
+	if drv := CPUFreqDriver(); drv != "intel_pstate" {
+		t.Errorf("expected 'intel_pstate', actual '%s'\n", drv)
+	}
+	if cpus := onlineCPUs(); strings.Join(cpus, " ") != "cpu0 cpu1" {
+		t.Errorf("expected 'cpu0 cpu1', actual '%v'\n", cpus)
+	}
+
+	// cpu2 is offline, so its different values are ignored
+	if val := GetPerfBias(); val != "all:6" {
+		t.Error(val)
+	}
+	if err := SetPerfBias("cpu0:6 cpu1:15"); err != nil {
+		t.Error(err)
+	}
+	if val := GetPerfBias(); val != "cpu0:6 cpu1:15" {
+		t.Error(val)
+	}
+	// invalid value and offline cpu are skipped
+	if err := SetPerfBias("cpu1:16 cpu2:0"); err != nil {
+		t.Error(err)
+	}
+	if val := GetPerfBias(); val != "cpu0:6 cpu1:15" {
+		t.Error(val)
+	}
+	if err := SetPerfBias("all:6"); err != nil {
+		t.Error(err)
+	}
+	if val := GetPerfBias(); val != "all:6" {
+		t.Error(val)
+	}
+	if val, _ := os.ReadFile(perfBiasFile("cpu2")); strings.TrimSpace(string(val)) != "15" {
+		t.Errorf("offline cpu changed: '%s'\n", string(val))
+	}
+
+	if val := CPUValString(GetEPP()); val != "all:balance_performance" {
+		t.Error(val)
+	}
+	if !isValidEPP("cpu0", "power") || !isValidEPP("cpu0", "128") {
+		t.Error("reports invalid, but shouldn't")
+	}
+	if isValidEPP("cpu0", "unknown") || isValidEPP("cpu0", "256") {
+		t.Error("reports valid, but shouldn't")
+	}
+	if err := SetEPP("cpu0:performance cpu1:unknown"); err != nil {
+		t.Error(err)
+	}
+	if val := CPUValString(GetEPP()); val != "cpu0:performance cpu1:balance_performance" {
+		t.Error(val)
+	}
+	if err := SetEPP("all:balance_performance"); err != nil {
+		t.Error(err)
+	}
+	if val := CPUValString(GetEPP()); val != "all:balance_performance" {
+		t.Error(val)
+	}
+	if err := SetEPP("all:none"); err != nil {
+		t.Error(err)
+	}
+
+	// acpi-cpufreq does not offer EPP
+	drvFile := path.Join(cpuDir, "cpu0", "cpufreq", "scaling_driver")
+	if err := os.WriteFile(drvFile, []byte("acpi-cpufreq\n"), 0644); err != nil {
+		t.Error(err)
+	}
+	if val := CPUValString(GetEPP()); val != "all:none" {
+		t.Error(val)
+	}
+	if err := os.WriteFile(drvFile, []byte("intel_pstate\n"), 0644); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCPUValString(t *testing.T) {
+	val := CPUValString(map[string]string{"cpu10": "power", "cpu2": "performance", "cpu0": "performance"})
+	if val != "cpu0:performance cpu2:performance cpu10:power" {
+		t.Error(val)
+	}
+	val = CPUValString(groupCPUValues(map[string]string{"cpu0": "6", "cpu1": "6"}))
+	if val != "all:6" {
+		t.Error(val)
+	}
+}
+
 func TestSecureBootEnabled(t *testing.T) {
 	oldEfiDir := efiVarsDir
 	defer func() { efiVarsDir = oldEfiDir }()
@@ -264,17 +347,7 @@ func TestMissingCmd(t *testing.T) {
 	if err := os.Rename(cmdName, savName); err != nil {
 		t.Error(err)
 	}
-	value := GetPerfBias()
-	if value != "all:none" {
-		t.Error(value)
-	}
-	if err := SetPerfBias("all:15"); err != nil {
-		t.Error(err)
-	}
-	if supportsPerfBias() {
-		t.Errorf("reports supported, but shouldn't")
-	}
-	if supportsGovernorSettings(value) {
+	if supportsGovernorSettings("") {
 		t.Errorf("reports supported, but shouldn't")
 	}
 	if err := SetGovernor("all:performance"); err != nil {
@@ -289,13 +362,6 @@ func TestCPUErrorCases(t *testing.T) {
 	oldCpupowerCmd := cpupowerCmd
 	defer func() { cpupowerCmd = oldCpupowerCmd }()
 	cpupowerCmd = "/usr/bin/false"
-	val := GetPerfBias()
-	if val != "all:none" {
-		t.Error(val)
-	}
-	if err := SetPerfBias("all:15"); err != nil {
-		t.Errorf("should return 'nil' and not '%v'\n", err)
-	}
 	if isValidGovernor("cpu0", "performance") {
 		if err := SetGovernor("all:performance"); err == nil {
 			t.Error("should return an error and not 'nil'")
@@ -305,14 +371,21 @@ func TestCPUErrorCases(t *testing.T) {
 			t.Errorf("should return 'nil' and not '%v'\n", err)
 		}
 	}
-	if supportsPerfBias() {
-		t.Error("reports supported, but shouldn't")
-	}
 	cpupowerCmd = oldCpupowerCmd
 
 	oldCPUDir := cpuDir
 	defer func() { cpuDir = oldCPUDir }()
 	cpuDir = "/unknownDir"
+	val := GetPerfBias()
+	if val != "all:none" {
+		t.Error(val)
+	}
+	if err := SetPerfBias("all:15"); err != nil {
+		t.Errorf("should return 'nil' and not '%v'\n", err)
+	}
+	if supportsPerfBias() {
+		t.Error("reports supported, but shouldn't")
+	}
 	gval := GetGovernor()
 	if len(gval) != 1 {
 		t.Errorf("should return only one entry, but returns: %+v", gval)
@@ -350,3 +423,55 @@ func TestCPUErrorCases(t *testing.T) {
 	}
 	cpuDir = oldCPUDir
 }
+
+func TestPossibleCPUs(t *testing.T) {
+	for list, cnt := range map[string]int{"0": 1, "0-63": 64, "0-3,8,10-11": 7, "": 0, "x-1,2": 1, "3-1": 0} {
+		if val := cpuListCount(list); val != cnt {
+			t.Errorf("cpu list '%s': expected '%d', got '%d'", list, cnt, val)
+		}
+	}
+	oldCPUDir := cpuDir
+	defer func() { cpuDir = oldCPUDir }()
+	cpuDir = t.TempDir()
+	if val := PossibleCPUs(); val != 0 {
+		t.Errorf("expected '0' for a missing file, got '%d'", val)
+	}
+	if err := os.WriteFile(path.Join(cpuDir, "possible"), []byte("0-15\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if val := PossibleCPUs(); val != 16 {
+		t.Errorf("expected '16', got '%d'", val)
+	}
+}
+
+func TestSetCPUValuesWriteFails(t *testing.T) {
+	oldCPUDir := cpuDir
+	defer func() { cpuDir = oldCPUDir }()
+	cpuDir = t.TempDir()
+	cpuFile := func(cpu string) string { return path.Join(cpuDir, cpu, "value") }
+	for _, cpu := range []string{"cpu0", "cpu1", "cpu2"} {
+		if err := os.MkdirAll(path.Join(cpuDir, cpu), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if cpu != "cpu0" {
+			if err := os.WriteFile(path.Join(cpuDir, cpu, "online"), []byte("1"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// a directory instead of the file can not be written, even by root
+	if err := os.MkdirAll(cpuFile("cpu1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	isValid := func(cpu, value string) bool { return true }
+	err := setCPUValues("all:1", cpuFile, isValid)
+	if err == nil || !strings.Contains(err.Error(), cpuFile("cpu1")) {
+		t.Errorf("expected an error for cpu1, got '%v'", err)
+	}
+	// the cpus before and after the failing cpu are written
+	for _, cpu := range []string{"cpu0", "cpu2"} {
+		if val, _ := os.ReadFile(cpuFile(cpu)); string(val) != "1" {
+			t.Errorf("%s: expected '1', got '%s'", cpu, string(val))
+		}
+	}
+}
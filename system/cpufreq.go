@@ -0,0 +1,339 @@
+package system
+
+// handling of the cpu frequency limits and the turbo settings
+// in /sys/devices/system/cpu
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var freqCnt = 0
+var turboCnt = 0
+
+// isFreqValue matches the frequency values of the Note definition file
+// like '2400000', '2400MHz', '2.4 GHz' or '80%'
+var isFreqValue = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(%|khz|mhz|ghz)?$`)
+
+// freqUnits contains the multiplier to kHz
+var freqUnits = map[string]float64{
+	"":    1,
+	"khz": 1,
+	"mhz": 1000,
+	"ghz": 1000000,
+}
+
+// GetCPUFreq retrieve the frequency limit 'scaling_min_freq' or
+// 'scaling_max_freq' (in kHz) of the online cpus from the system
+// read /sys/devices/system/cpu/cpu*/cpufreq/scaling_m[in|ax]_freq
+func GetCPUFreq(key string) map[string]string {
+	gFreq := make(map[string]string)
+	if !supportsCPUFreqSettings(key, "") {
+		gFreq["all"] = "none"
+		return gFreq
+	}
+	freq := getCPUValues(cpuFreqFile(key))
+	if len(freq) == 0 {
+		gFreq["all"] = "none"
+		return gFreq
+	}
+	return groupCPUValues(freq)
+}
+
+// SetCPUFreq set the frequency limit 'scaling_min_freq' or
+// 'scaling_max_freq' of the cpus. The values need to be in kHz
+// (see CPUFreqExpected)
+func SetCPUFreq(key, value string) error {
+	if key == "scaling_min_freq" {
+		return SetCPUFreqLimits(value, "")
+	}
+	return SetCPUFreqLimits("", value)
+}
+
+// SetCPUFreqLimits set the frequency limits 'scaling_min_freq' and
+// 'scaling_max_freq' of the cpus. An empty value leaves the limit untouched.
+// The kernel rejects a minimum above the current maximum and a maximum
+// below the current minimum. So for each cpu the maximum is written first,
+// if the minimum is raised above the current maximum, otherwise the minimum
+// is written first
+func SetCPUFreqLimits(minValue, maxValue string) error {
+	values := map[string]string{"scaling_min_freq": minValue, "scaling_max_freq": maxValue}
+	for key, value := range values {
+		if value != "" && !supportsCPUFreqSettings(key, value) {
+			values[key] = ""
+		}
+	}
+	if values["scaling_min_freq"] == "" && values["scaling_max_freq"] == "" {
+		return nil
+	}
+	failed := []string{}
+	for _, cpu := range onlineCPUs() {
+		limits := map[string]string{"scaling_min_freq": cpuValue(values["scaling_min_freq"], cpu), "scaling_max_freq": cpuValue(values["scaling_max_freq"], cpu)}
+		order := []string{"scaling_min_freq", "scaling_max_freq"}
+		if curMax, err := cpuFreqInfo(cpu, "scaling_max_freq"); err == nil {
+			if newMin, err := strconv.Atoi(limits["scaling_min_freq"]); err == nil && newMin > curMax {
+				order = []string{"scaling_max_freq", "scaling_min_freq"}
+			}
+		}
+		for _, key := range order {
+			if limits[key] == "" || limits[key] == "none" {
+				continue
+			}
+			if err := setCPUValues(cpu+":"+limits[key], cpuFreqFile(key), isValidCPUFreqLimit(key)); err != nil {
+				failed = append(failed, err.Error())
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// cpuValue returns the value of the cpu from a string like 'all:<value>'
+// or 'cpu0:<value> cpu1:<value>'. Returns an empty string, if the cpu has
+// no value
+func cpuValue(value, cpu string) string {
+	val := ""
+	for _, entry := range strings.Fields(value) {
+		fields := strings.Split(entry, ":")
+		if len(fields) < 2 {
+			continue
+		}
+		if fields[0] == cpu {
+			return fields[1]
+		}
+		if fields[0] == "all" {
+			val = fields[1]
+		}
+	}
+	return val
+}
+
+// CPUFreqExpected converts the frequency value of the Note definition file
+// to the kHz values of the online cpus. The result is grouped like the
+// values of GetCPUFreq
+func CPUFreqExpected(key, value string) map[string]string {
+	freq := make(map[string]string)
+	if !supportsCPUFreqSettings(key, "") {
+		freq["all"] = value
+		return freq
+	}
+	for _, cpu := range onlineCPUs() {
+		khz, err := CPUFreqToKHz(cpu, value)
+		if err != nil {
+			WarningLog("%v", err)
+			khz = value
+		}
+		freq[cpu] = khz
+	}
+	if len(freq) == 0 {
+		freq["all"] = value
+		return freq
+	}
+	return groupCPUValues(freq)
+}
+
+// CPUFreqToKHz converts a frequency value to kHz for the given cpu.
+// Absolute values can have the units kHz (default), MHz or GHz, a
+// percentage is related to 'cpuinfo_max_freq' of the cpu
+func CPUFreqToKHz(cpu, value string) (string, error) {
+	fields := isFreqValue.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if fields == nil {
+		return "", fmt.Errorf("wrong frequency value '%s', use kHz, MHz, GHz or a percentage of cpuinfo_max_freq", value)
+	}
+	num, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return "", fmt.Errorf("wrong frequency value '%s' - %v", value, err)
+	}
+	if fields[2] != "%" {
+		return strconv.Itoa(int(num * freqUnits[fields[2]])), nil
+	}
+	if num > 100 {
+		return "", fmt.Errorf("wrong frequency value '%s', percentage greater than 100", value)
+	}
+	maxFreq, err := cpuFreqInfo(cpu, "cpuinfo_max_freq")
+	if err != nil {
+		return "", fmt.Errorf("can not convert frequency value '%s' for cpu '%s' - %v", value, cpu, err)
+	}
+	return strconv.Itoa(int(float64(maxFreq) * num / 100)), nil
+}
+
+// supportsCPUFreqSettings checks, if the frequency limits are supported
+// by the system
+func supportsCPUFreqSettings(key, value string) bool {
+	setFreq := true
+	if value == "all:none" {
+		PrintLog(freqCnt, "warn", "CPU frequency settings not supported by the system")
+		setFreq = false
+	} else if _, err := os.Stat(cpuFreqFile(key)("cpu0")); err != nil {
+		// check only first cpu - cpu0, not all
+		PrintLog(freqCnt, "warn", "CPU frequency settings not supported by the system. Missing file '%s'", cpuFreqFile(key)("cpu0"))
+		setFreq = false
+	}
+	if freqCnt == 0 {
+		freqCnt++
+	}
+	return setFreq
+}
+
+// cpuFreqFile returns a function, which returns the path of the cpufreq file
+// 'key' of a cpu
+func cpuFreqFile(key string) func(string) string {
+	return func(cpu string) string {
+		return path.Join(cpuDir, cpu, "cpufreq", key)
+	}
+}
+
+// cpuFreqInfo returns the integer value of the cpufreq file 'key' of a cpu
+// like cpuinfo_min_freq or cpuinfo_max_freq
+func cpuFreqInfo(cpu, key string) (int, error) {
+	val, err := os.ReadFile(cpuFreqFile(key)(cpu))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(val)))
+}
+
+// isValidCPUFreq checks, if the frequency value (kHz) is in the range of
+// 'cpuinfo_min_freq' and 'cpuinfo_max_freq' of the cpu
+func isValidCPUFreq(cpu, value string) bool {
+	freq, err := strconv.Atoi(value)
+	if err != nil || freq <= 0 {
+		return false
+	}
+	if minFreq, err := cpuFreqInfo(cpu, "cpuinfo_min_freq"); err == nil && freq < minFreq {
+		return false
+	}
+	if maxFreq, err := cpuFreqInfo(cpu, "cpuinfo_max_freq"); err == nil && freq > maxFreq {
+		return false
+	}
+	return true
+}
+
+// isValidCPUFreqLimit returns a function, which checks the frequency value
+// (kHz) like isValidCPUFreq and additionally like the kernel, that the
+// minimum is not above the current maximum and the maximum is not below the
+// current minimum of the cpu
+func isValidCPUFreqLimit(key string) func(string, string) bool {
+	return func(cpu, value string) bool {
+		if !isValidCPUFreq(cpu, value) {
+			return false
+		}
+		freq, _ := strconv.Atoi(value)
+		if key == "scaling_min_freq" {
+			curMax, err := cpuFreqInfo(cpu, "scaling_max_freq")
+			return err != nil || freq <= curMax
+		}
+		curMin, err := cpuFreqInfo(cpu, "scaling_min_freq")
+		return err != nil || freq >= curMin
+	}
+}
+
+// GetTurbo retrieve the turbo state ('on' or 'off') from the system
+// read /sys/devices/system/cpu/intel_pstate/no_turbo (intel_pstate) or
+// /sys/devices/system/cpu/cpufreq/boost (acpi-cpufreq, amd-pstate)
+func GetTurbo() string {
+	tFile, inverted := turboFile()
+	if tFile == "" {
+		return "all:none"
+	}
+	val, err := os.ReadFile(tFile)
+	if err != nil {
+		PrintLog(turboCnt, "info", "Problems reading file '%s' - %v", tFile, err)
+		return "all:none"
+	}
+	// no_turbo=1 means turbo off, boost=1 means turbo on
+	if (strings.TrimSpace(string(val)) == "1") != inverted {
+		return "on"
+	}
+	return "off"
+}
+
+// SetTurbo set the turbo state ('on' or 'off') to the system
+func SetTurbo(value string) error {
+	tFile, inverted := turboFile()
+	if tFile == "" || value == "all:none" {
+		return nil
+	}
+	if value != "on" && value != "off" {
+		WarningLog("'%s' is not a valid value for turbo, skipping.", value)
+		return nil
+	}
+	val := "0"
+	if (value == "on") != inverted {
+		val = "1"
+	}
+	if err := os.WriteFile(tFile, []byte(val), 0644); err != nil {
+		WarningLog("failed to set '%s' in '%s': %v", val, tFile, err)
+		return err
+	}
+	return nil
+}
+
+// turboFile returns the file to control the turbo state and if the content
+// is inverted (no_turbo)
+func turboFile() (string, bool) {
+	noTurbo := path.Join(cpuDir, "intel_pstate", "no_turbo")
+	boost := path.Join(cpuDir, "cpufreq", "boost")
+	tFile := ""
+	inverted := false
+	if _, err := os.Stat(noTurbo); err == nil {
+		tFile = noTurbo
+		inverted = true
+	} else if _, err := os.Stat(boost); err == nil {
+		tFile = boost
+	} else {
+		PrintLog(turboCnt, "warn", "Turbo settings not supported by the system. Missing files '%s' and '%s'", noTurbo, boost)
+	}
+	if turboCnt == 0 {
+		turboCnt++
+	}
+	return tFile, inverted
+}
+
+// GetMinPerfPct retrieve the minimal P-state limit in percent of the
+// maximal performance from the system (intel_pstate only)
+// read /sys/devices/system/cpu/intel_pstate/min_perf_pct
+func GetMinPerfPct() string {
+	val, err := os.ReadFile(path.Join(cpuDir, "intel_pstate", "min_perf_pct"))
+	if err != nil {
+		InfoLog("min_perf_pct not supported by the system - %v", err)
+		return "all:none"
+	}
+	return strings.TrimSpace(string(val))
+}
+
+// SetMinPerfPct set the minimal P-state limit in percent of the maximal
+// performance (intel_pstate only). The value needs to be between 0 and
+// the current value of intel_pstate/max_perf_pct
+func SetMinPerfPct(value string) error {
+	pctFile := path.Join(cpuDir, "intel_pstate", "min_perf_pct")
+	if value == "all:none" {
+		return nil
+	}
+	if _, err := os.Stat(pctFile); err != nil {
+		InfoLog("min_perf_pct not supported by the system - %v", err)
+		return nil
+	}
+	pct, err := strconv.Atoi(value)
+	if err != nil || pct < 0 || pct > 100 {
+		WarningLog("'%s' is not a valid value for min_perf_pct, skipping.", value)
+		return nil
+	}
+	if maxPct, err := os.ReadFile(path.Join(cpuDir, "intel_pstate", "max_perf_pct")); err == nil {
+		if max, err := strconv.Atoi(strings.TrimSpace(string(maxPct))); err == nil && pct > max {
+			WarningLog("value '%s' for min_perf_pct is greater than max_perf_pct '%d', skipping.", value, max)
+			return nil
+		}
+	}
+	if err := os.WriteFile(pctFile, []byte(value), 0644); err != nil {
+		WarningLog("failed to set '%s' in '%s': %v", value, pctFile, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package system
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestCPUFreqToKHz(t *testing.T) {
+	oldCPUDir := cpuDir
+	defer func() { cpuDir = oldCPUDir }()
+	cpuDir = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/cpu-freq")
+
+	for val, exp := range map[string]string{"2400000": "2400000", "2400 kHz": "2400", "2400MHz": "2400000", "2.4 GHz": "2400000", "80%": "2400000", "100%": "3000000"} {
+		khz, err := CPUFreqToKHz("cpu0", val)
+		if err != nil || khz != exp {
+			t.Errorf("'%s': expected '%s', actual '%s' - %v\n", val, exp, khz, err)
+		}
+	}
+	for _, val := range []string{"fast", "120%", "-1", "2400 THz"} {
+		if khz, err := CPUFreqToKHz("cpu0", val); err == nil {
+			t.Errorf("'%s': expected an error, actual '%s'\n", val, khz)
+		}
+	}
+	if khz, err := CPUFreqToKHz("cpu7", "80%"); err == nil {
+		t.Errorf("expected an error for missing cpu, actual '%s'\n", khz)
+	}
+}
+
+func TestCPUFreq(t *testing.T) {
+	oldCPUDir := cpuDir
+	defer func() { cpuDir = oldCPUDir }()
+	cpuDir = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/cpu-freq")
+
+	if val := CPUValString(GetCPUFreq("scaling_min_freq")); val != "all:800000" {
+		t.Error(val)
+	}
+	if val := CPUValString(GetCPUFreq("scaling_max_freq")); val != "cpu0:3000000 cpu1:2000000" {
+		t.Error(val)
+	}
+	// percentage of the different cpuinfo_max_freq of the cpus
+	if val := CPUValString(CPUFreqExpected("scaling_max_freq", "50%")); val != "cpu0:1500000 cpu1:1000000" {
+		t.Error(val)
+	}
+	if val := CPUValString(CPUFreqExpected("scaling_min_freq", "1GHz")); val != "all:1000000" {
+		t.Error(val)
+	}
+	if !isValidCPUFreq("cpu1", "2000000") || isValidCPUFreq("cpu1", "2500000") || isValidCPUFreq("cpu0", "700000") {
+		t.Error("wrong validation of frequency values")
+	}
+	if err := SetCPUFreq("scaling_min_freq", "all:1000000"); err != nil {
+		t.Error(err)
+	}
+	if val := CPUValString(GetCPUFreq("scaling_min_freq")); val != "all:1000000" {
+		t.Error(val)
+	}
+	// values out of range are skipped
+	if err := SetCPUFreq("scaling_min_freq", "cpu0:800000 cpu1:9000000"); err != nil {
+		t.Error(err)
+	}
+	if val := CPUValString(GetCPUFreq("scaling_min_freq")); val != "cpu0:800000 cpu1:1000000" {
+		t.Error(val)
+	}
+	// revert
+	if err := SetCPUFreq("scaling_min_freq", "all:800000"); err != nil {
+		t.Error(err)
+	}
+	if val := CPUValString(GetCPUFreq("scaling_min_freq")); val != "all:800000" {
+		t.Error(val)
+	}
+	if err := SetCPUFreq("scaling_min_freq", "all:none"); err != nil {
+		t.Error(err)
+	}
+
+	cpuDir = "/unknownDir"
+	if val := CPUValString(GetCPUFreq("scaling_max_freq")); val != "all:none" {
+		t.Error(val)
+	}
+	if val := CPUValString(CPUFreqExpected("scaling_max_freq", "80%")); val != "all:80%" {
+		t.Error(val)
+	}
+}
+
+func TestTurboAndMinPerfPct(t *testing.T) {
+	oldCPUDir := cpuDir
+	defer func() { cpuDir = oldCPUDir }()
+	cpuDir = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/cpu-freq")
+
+	// intel_pstate/no_turbo is preferred to cpufreq/boost
+	if val := GetTurbo(); val != "on" {
+		t.Error(val)
+	}
+	if err := SetTurbo("off"); err != nil {
+		t.Error(err)
+	}
+	if val := GetTurbo(); val != "off" {
+		t.Error(val)
+	}
+	if val, _ := os.ReadFile(path.Join(cpuDir, "intel_pstate", "no_turbo")); string(val) != "1" {
+		t.Errorf("expected '1', actual '%s'\n", string(val))
+	}
+	if err := SetTurbo("fast"); err != nil {
+		t.Error(err)
+	}
+	if err := SetTurbo("on"); err != nil {
+		t.Error(err)
+	}
+	if val := GetTurbo(); val != "on" {
+		t.Error(val)
+	}
+
+	if val := GetMinPerfPct(); val != "20" {
+		t.Error(val)
+	}
+	if err := SetMinPerfPct("50"); err != nil {
+		t.Error(err)
+	}
+	if val := GetMinPerfPct(); val != "50" {
+		t.Error(val)
+	}
+	if err := SetMinPerfPct("101"); err != nil {
+		t.Error(err)
+	}
+	if err := SetMinPerfPct("20"); err != nil {
+		t.Error(err)
+	}
+	if val := GetMinPerfPct(); val != "20" {
+		t.Error(val)
+	}
+
+	cpuDir = "/unknownDir"
+	if val := GetTurbo(); val != "all:none" {
+		t.Error(val)
+	}
+	if err := SetTurbo("off"); err != nil {
+		t.Error(err)
+	}
+	if val := GetMinPerfPct(); val != "all:none" {
+		t.Error(val)
+	}
+	if err := SetMinPerfPct("50"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCPUFreqLimitsOrder(t *testing.T) {
+	oldCPUDir := cpuDir
+	defer func() { cpuDir = oldCPUDir }()
+	cpuDir = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/cpu-freq")
+
+	if err := SetCPUFreqLimits("", "cpu0:1000000"); err != nil {
+		t.Error(err)
+	}
+	// raise both limits, the new minimum is above the current maximum
+	if err := SetCPUFreqLimits("cpu0:2000000", "cpu0:2500000"); err != nil {
+		t.Error(err)
+	}
+	if min, max := CPUValString(GetCPUFreq("scaling_min_freq")), CPUValString(GetCPUFreq("scaling_max_freq")); min != "cpu0:2000000 cpu1:800000" || max != "cpu0:2500000 cpu1:2000000" {
+		t.Errorf("raise: min '%s', max '%s'", min, max)
+	}
+	// lower both limits, the new maximum is below the current minimum
+	if err := SetCPUFreqLimits("cpu0:900000", "cpu0:1500000"); err != nil {
+		t.Error(err)
+	}
+	if min, max := CPUValString(GetCPUFreq("scaling_min_freq")), CPUValString(GetCPUFreq("scaling_max_freq")); min != "cpu0:900000 cpu1:800000" || max != "cpu0:1500000 cpu1:2000000" {
+		t.Errorf("lower: min '%s', max '%s'", min, max)
+	}
+	// a maximum below the current minimum is rejected like by the kernel
+	if err := SetCPUFreq("scaling_max_freq", "cpu0:800000"); err != nil {
+		t.Error(err)
+	}
+	if max := CPUValString(GetCPUFreq("scaling_max_freq")); max != "cpu0:1500000 cpu1:2000000" {
+		t.Error(max)
+	}
+	// revert
+	if err := SetCPUFreqLimits("all:800000", "cpu0:3000000 cpu1:2000000"); err != nil {
+		t.Error(err)
+	}
+	if min, max := CPUValString(GetCPUFreq("scaling_min_freq")), CPUValString(GetCPUFreq("scaling_max_freq")); min != "all:800000" || max != "cpu0:3000000 cpu1:2000000" {
+		t.Errorf("revert: min '%s', max '%s'", min, max)
+	}
+}
@@ -15,6 +15,7 @@ var actTunedProfile = "/etc/tuned/active_profile"
 
 // SystemctlEnable call systemctl enable on thing.
 func SystemctlEnable(thing string) error {
+	forgetServiceFacts(thing)
 	out, err := exec.Command(systemctlCmd, "enable", thing).CombinedOutput()
 	if err != nil {
 		return ErrorLog("%v - Failed to call systemctl enable on %s - %s", err, thing, strings.TrimSpace(string(out)))
@@ -35,6 +36,7 @@ func SystemctlStatus(thing string) error {
 
 // SystemctlDisable call systemctl disable on thing.
 func SystemctlDisable(thing string) error {
+	forgetServiceFacts(thing)
 	out, err := exec.Command(systemctlCmd, "disable", thing).CombinedOutput()
 	if err != nil {
 		return ErrorLog("%v - Failed to call systemctl disable on %s - %s", err, thing, strings.TrimSpace(string(out)))
@@ -75,6 +77,7 @@ func SystemdDetectVirt(opt string) (bool, string, error) {
 
 // execSystemctlCmd will execute /usr/bin/systemctl with the requested command
 func execSystemctlCmd(service, cmd string) error {
+	forgetServiceFacts(service)
 	running, err := IsSystemRunning()
 	if err != nil {
 		return ErrorLog("%v - Failed to call systemctl %s on %s", err, cmd, service)
@@ -147,16 +150,19 @@ func SystemctlDisableStop(thing string) error {
 
 // checkSystemctlState checks for a special state
 func checkSystemctlState(service, cmd string) (bool, error) {
-	match := false
-	out, err := exec.Command(systemctlCmd, cmd, service).CombinedOutput()
-	DebugLog("checkSystemctlState, called from '%v' - /usr/bin/systemctl %s %s: '%+v %s'", CalledFrom(), cmd, service, err, strings.TrimSpace(string(out)))
-	if err == nil {
-		match = true
-	}
-	if len(out) == 0 && err != nil {
-		return match, ErrorLog("%v - Failed to call systemctl %s on %s", err, cmd, service)
-	}
-	return match, nil
+	state, err := cachedFact(cmd, service, func() (string, error) {
+		match := ""
+		out, err := exec.Command(systemctlCmd, cmd, service).CombinedOutput()
+		DebugLog("checkSystemctlState, called from '%v' - /usr/bin/systemctl %s %s: '%+v %s'", CalledFrom(), cmd, service, err, strings.TrimSpace(string(out)))
+		if err == nil {
+			match = "match"
+		}
+		if len(out) == 0 && err != nil {
+			return match, ErrorLog("%v - Failed to call systemctl %s on %s", err, cmd, service)
+		}
+		return match, nil
+	})
+	return state == "match", err
 }
 
 // SystemctlIsEnabled return true only if systemctl suggests that the thing is
@@ -186,12 +192,145 @@ func SystemctlIsStarting() bool {
 
 // SystemctlIsActive returns the output of 'systemctl is-active'
 func SystemctlIsActive(thing string) (string, error) {
-	out, err := exec.Command(systemctlCmd, "is-active", thing).CombinedOutput()
-	DebugLog("SystemctlIsActive - /usr/bin/systemctl is-active : '%+v %s'", err, strings.TrimSpace(string(out)))
-	if len(out) == 0 && err != nil {
-		return "", ErrorLog("%v - Failed to call systemctl is-active", err)
+	return cachedFact("active-state", thing, func() (string, error) {
+		out, err := exec.Command(systemctlCmd, "is-active", thing).CombinedOutput()
+		DebugLog("SystemctlIsActive - /usr/bin/systemctl is-active : '%+v %s'", err, strings.TrimSpace(string(out)))
+		if len(out) == 0 && err != nil {
+			return "", ErrorLog("%v - Failed to call systemctl is-active", err)
+		}
+		return strings.TrimSpace(string(out)), err
+	})
+}
+
+// UnitState contains the states of a systemd unit as reported by
+// 'systemctl show'
+type UnitState struct {
+	Unit          string
+	LoadState     string
+	ActiveState   string
+	UnitFileState string
+}
+
+// IsActive returns true, if 'systemctl is-active' would report success
+func (us UnitState) IsActive() bool {
+	return us.ActiveState == "active" || us.ActiveState == "reloading" || us.ActiveState == "refreshing"
+}
+
+// IsEnabled returns true, if 'systemctl is-enabled' would report success
+func (us UnitState) IsEnabled() bool {
+	switch us.UnitFileState {
+	case "enabled", "enabled-runtime", "alias", "static", "indirect", "generated", "transient":
+		return true
+	}
+	return false
+}
+
+// SystemctlShowUnits returns the states of all given units.
+// All units are queried with a single call of 'systemctl show'. If this
+// fails, the states are queried unit by unit as fallback.
+func SystemctlShowUnits(units ...string) map[string]UnitState {
+	if len(units) == 0 {
+		return map[string]UnitState{}
+	}
+	units = uniqueUnits(units)
+	cmdArgs := append([]string{"show", "--no-pager", "--property=Id,LoadState,ActiveState,UnitFileState", "--"}, units...)
+	out, err := exec.Command(systemctlCmd, cmdArgs...).Output()
+	DebugLog("SystemctlShowUnits - /usr/bin/systemctl %s : '%+v'", strings.Join(cmdArgs, " "), err)
+	if err == nil {
+		if states, err := parseUnitStates(units, string(out)); err == nil {
+			return states
+		}
+	}
+	DebugLog("SystemctlShowUnits - batch query failed (%v), falling back to single unit queries", err)
+	states := make(map[string]UnitState)
+	for _, unit := range units {
+		states[unit] = showSingleUnit(unit)
+	}
+	return states
+}
+
+// uniqueUnits removes duplicate units from the list, as 'systemctl show'
+// prints a property block for each unit given on the command line
+func uniqueUnits(units []string) []string {
+	seen := make(map[string]bool)
+	uniq := []string{}
+	for _, unit := range units {
+		if !seen[unit] {
+			seen[unit] = true
+			uniq = append(uniq, unit)
+		}
+	}
+	return uniq
+}
+
+// parseUnitStates parses the output of 'systemctl show' for several units.
+// The property blocks are separated by an empty line and are in the same
+// order as the units on the command line.
+func parseUnitStates(units []string, out string) (map[string]UnitState, error) {
+	states := make(map[string]UnitState)
+	blocks := strings.Split(strings.TrimSpace(out), "\n\n")
+	if len(blocks) != len(units) {
+		return nil, fmt.Errorf("got %d property blocks for %d units", len(blocks), len(units))
+	}
+	for i, block := range blocks {
+		state := UnitState{Unit: units[i]}
+		for _, line := range strings.Split(block, "\n") {
+			fields := strings.SplitN(strings.TrimSpace(line), "=", 2)
+			if len(fields) != 2 {
+				continue
+			}
+			switch fields[0] {
+			case "LoadState":
+				state.LoadState = fields[1]
+			case "ActiveState":
+				state.ActiveState = fields[1]
+			case "UnitFileState":
+				state.UnitFileState = fields[1]
+			}
+		}
+		if state.ActiveState == "" {
+			return nil, fmt.Errorf("missing active state of unit '%s'", units[i])
+		}
+		states[units[i]] = state
+	}
+	return states, nil
+}
+
+// showSingleUnit queries the states of a single unit with
+// 'systemctl is-active' and 'systemctl is-enabled'
+func showSingleUnit(unit string) UnitState {
+	state := UnitState{Unit: unit}
+	out, _ := exec.Command(systemctlCmd, "is-active", unit).Output()
+	state.ActiveState = strings.TrimSpace(string(out))
+	out, _ = exec.Command(systemctlCmd, "is-enabled", unit).Output()
+	state.UnitFileState = strings.TrimSpace(string(out))
+	if state.ActiveState == "" {
+		state.ActiveState = "unknown"
+	}
+	return state
+}
+
+// QueryUnitStates fetches the states of all given units with a single
+// query and stores them in the per-run fact cache, so that following
+// calls of SystemctlIsRunning, SystemctlIsEnabled and SystemctlIsActive
+// for these units do not need to call systemctl again.
+// Nothing is done, if the fact cache is not enabled.
+func QueryUnitStates(units ...string) {
+	if !FactCacheEnabled() || len(units) == 0 {
+		return
+	}
+	for unit, state := range SystemctlShowUnits(units...) {
+		active, enabled := "", ""
+		if state.IsActive() {
+			active = "match"
+		}
+		if state.IsEnabled() {
+			enabled = "match"
+		}
+		storeFact("is-active", unit, active, nil)
+		storeFact("is-enabled", unit, enabled, nil)
+		storeFact("active-state", unit, state.ActiveState, nil)
 	}
-	return strings.TrimSpace(string(out)), err
 }
 
 // SystemctlDaemonReload calls 'systemctl daemon-reload'
@@ -240,12 +379,15 @@ func IsSystemRunning() (bool, error) {
 func IsServiceAvailable(service string) bool {
 	match := false
 	cmdArgs := []string{"--no-pager", "list-unit-files", "-t", "service"}
-	cmdOut, err := exec.Command(systemctlCmd, cmdArgs...).CombinedOutput()
+	cmdOut, err := cachedFact("unit-files", "service", func() (string, error) {
+		out, err := exec.Command(systemctlCmd, cmdArgs...).CombinedOutput()
+		return string(out), err
+	})
 	if err != nil {
 		_ = ErrorLog("Failed to call '%s %v' to get the available services - %v", systemctlCmd, strings.Join(cmdArgs, " "), err)
 		return match
 	}
-	for _, line := range strings.Split(string(cmdOut), "\n") {
+	for _, line := range strings.Split(cmdOut, "\n") {
 		fields := strings.Fields(line)
 		if len(fields) == 0 {
 			continue
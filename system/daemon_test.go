@@ -560,3 +560,68 @@ func TestSystemdDetectVirt(t *testing.T) {
 
 	systemddvCmd = oldSystemddvCmd
 }
+
+func TestParseUnitStates(t *testing.T) {
+	units := []string{"saptune.service", "sapconf.service", "notthere.service"}
+	out := "Id=saptune.service\nLoadState=loaded\nActiveState=active\nUnitFileState=enabled\n\nId=sapconf.service\nLoadState=loaded\nActiveState=inactive\nUnitFileState=disabled\n\nId=notthere.service\nLoadState=not-found\nActiveState=inactive\nUnitFileState=\n"
+	states, err := parseUnitStates(units, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !states["saptune.service"].IsActive() || !states["saptune.service"].IsEnabled() {
+		t.Errorf("wrong state for saptune.service: '%+v'", states["saptune.service"])
+	}
+	if states["sapconf.service"].IsActive() || states["sapconf.service"].IsEnabled() {
+		t.Errorf("wrong state for sapconf.service: '%+v'", states["sapconf.service"])
+	}
+	if states["notthere.service"].LoadState != "not-found" || states["notthere.service"].IsEnabled() {
+		t.Errorf("wrong state for notthere.service: '%+v'", states["notthere.service"])
+	}
+	// number of property blocks does not match the number of units
+	if _, err := parseUnitStates(units[:2], out); err == nil {
+		t.Error("expected an error for mismatching property blocks")
+	}
+	if _, err := parseUnitStates([]string{"saptune.service"}, "Id=saptune.service\n"); err == nil {
+		t.Error("expected an error for missing active state")
+	}
+	if uniq := uniqueUnits([]string{"a", "b", "a"}); len(uniq) != 2 || uniq[0] != "a" || uniq[1] != "b" {
+		t.Errorf("wrong unique units: '%v'", uniq)
+	}
+	for _, fstate := range []string{"static", "enabled-runtime", "alias", "indirect", "generated", "transient"} {
+		if !(UnitState{UnitFileState: fstate}).IsEnabled() {
+			t.Errorf("unit file state '%s' should be reported as enabled", fstate)
+		}
+	}
+	for _, fstate := range []string{"masked", "linked", "disabled", "bad", ""} {
+		if (UnitState{UnitFileState: fstate}).IsEnabled() {
+			t.Errorf("unit file state '%s' should not be reported as enabled", fstate)
+		}
+	}
+}
+
+func TestQueryUnitStates(t *testing.T) {
+	oldSystemctlCmd := systemctlCmd
+	defer func() { systemctlCmd = oldSystemctlCmd }()
+	systemctlCmd = "/usr/bin/false"
+	// fact cache disabled, nothing stored
+	QueryUnitStates("saptune.service")
+	EnableFactCache()
+	defer DisableFactCache()
+	// fallback path, as the batch query fails
+	QueryUnitStates("saptune.service", "saptune.service")
+	if active, _ := SystemctlIsRunning("saptune.service"); active {
+		t.Error("saptune.service should not be reported as running")
+	}
+	if state, _ := SystemctlIsActive("saptune.service"); state != "unknown" {
+		t.Errorf("wrong active state '%s'", state)
+	}
+	systemctlCmd = "/usr/bin/echo"
+	// value from cache, echo would report success
+	if enabled, _ := SystemctlIsEnabled("saptune.service"); enabled {
+		t.Error("saptune.service should not be reported as enabled")
+	}
+	forgetServiceFacts("saptune.service")
+	if enabled, _ := SystemctlIsEnabled("saptune.service"); !enabled {
+		t.Error("saptune.service should be reported as enabled")
+	}
+}
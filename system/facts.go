@@ -0,0 +1,118 @@
+package system
+
+// per-run cache of system facts (sysctl and sysfs values, service states,
+// rpm versions, block device information, mount tables, cpu settings), so
+// that each fact is read only once per saptune call, even if it is needed
+// by several notes
+
+import (
+	"os"
+	"sync"
+)
+
+// fact is a cached result of a system inspection
+type fact struct {
+	value string
+	err   error
+}
+
+// factCache holds the facts read from the system while the cache is enabled
+type factCache struct {
+	sync.Mutex
+	enabled bool
+	facts   map[string]fact
+}
+
+var facts = factCache{facts: make(map[string]fact)}
+
+// EnableFactCache enables the per-run fact cache.
+// The cache is meant for read-only operations like 'verify', where the
+// same system values are inspected by several notes.
+func EnableFactCache() {
+	facts.Lock()
+	defer facts.Unlock()
+	facts.enabled = true
+}
+
+// DisableFactCache disables the per-run fact cache and drops all cached facts
+func DisableFactCache() {
+	facts.Lock()
+	defer facts.Unlock()
+	facts.enabled = false
+	facts.facts = make(map[string]fact)
+}
+
+// FactCacheEnabled returns true, if the per-run fact cache is enabled
+func FactCacheEnabled() bool {
+	facts.Lock()
+	defer facts.Unlock()
+	return facts.enabled
+}
+
+// factKey returns the cache key of a fact of the given kind
+func factKey(kind, key string) string {
+	return kind + ":" + key
+}
+
+// cachedFact returns the cached fact of the given kind and key. If the fact
+// is not yet available or the cache is disabled, the fact is read from the
+// system by the given function. Reading is done outside of the lock, so
+// different facts can be read concurrently.
+func cachedFact(kind, key string, read func() (string, error)) (string, error) {
+	facts.Lock()
+	enabled := facts.enabled
+	if enabled {
+		if f, ok := facts.facts[factKey(kind, key)]; ok {
+			facts.Unlock()
+			return f.value, f.err
+		}
+	}
+	facts.Unlock()
+
+	value, err := read()
+	if enabled {
+		storeFact(kind, key, value, err)
+	}
+	return value, err
+}
+
+// storeFact stores a fact in the cache, if the cache is enabled
+func storeFact(kind, key, value string, err error) {
+	facts.Lock()
+	defer facts.Unlock()
+	if facts.enabled {
+		facts.facts[factKey(kind, key)] = fact{value: value, err: err}
+	}
+}
+
+// forgetFact removes a fact from the cache. Needs to be called, if a
+// value is changed on the system
+func forgetFact(kind, key string) {
+	facts.Lock()
+	defer facts.Unlock()
+	delete(facts.facts, factKey(kind, key))
+}
+
+// readCachedFile returns the content of a file (e.g. /proc/mounts or a per
+// cpu sysfs file) from the fact cache or reads it from the system
+func readCachedFile(file string) (string, error) {
+	return cachedFact("file", file, func() (string, error) {
+		content, err := os.ReadFile(file)
+		return string(content), err
+	})
+}
+
+// forgetServiceFacts removes the cached states of a service. Needs to be
+// called, if the service is started, stopped, enabled or disabled
+func forgetServiceFacts(service string) {
+	forgetFact("is-active", service)
+	forgetFact("is-enabled", service)
+	forgetFact("active-state", service)
+}
+
+// forgetSysFacts removes the cached values of a /sys/ key. Needs to be
+// called, if the key is written
+func forgetSysFacts(parameter string) {
+	forgetFact("/sys", parameter)
+	forgetFact("syschoice", parameter)
+}
@@ -0,0 +1,158 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"testing"
+)
+
+func TestFactCache(t *testing.T) {
+	reads := 0
+	read := func() (string, error) {
+		reads++
+		return fmt.Sprintf("value%d", reads), nil
+	}
+
+	// cache disabled - read every time
+	DisableFactCache()
+	if FactCacheEnabled() {
+		t.Error("fact cache should be disabled")
+	}
+	val1, _ := cachedFact("test", "key", read)
+	val2, _ := cachedFact("test", "key", read)
+	if val1 != "value1" || val2 != "value2" {
+		t.Errorf("unexpected values '%s', '%s' with disabled cache", val1, val2)
+	}
+
+	// cache enabled - read only once
+	EnableFactCache()
+	defer DisableFactCache()
+	if !FactCacheEnabled() {
+		t.Error("fact cache should be enabled")
+	}
+	val1, _ = cachedFact("test", "key", read)
+	val2, _ = cachedFact("test", "key", read)
+	if val1 != "value3" || val2 != "value3" || reads != 3 {
+		t.Errorf("unexpected values '%s', '%s' (%d reads) with enabled cache", val1, val2, reads)
+	}
+	// different kind, different fact
+	if val, _ := cachedFact("other", "key", read); val != "value4" {
+		t.Errorf("unexpected value '%s' for other kind", val)
+	}
+	// errors are cached too
+	errRead := func() (string, error) {
+		reads++
+		return "PNA", fmt.Errorf("not available")
+	}
+	_, err1 := cachedFact("test", "errkey", errRead)
+	_, err2 := cachedFact("test", "errkey", errRead)
+	if err1 == nil || err2 == nil || reads != 5 {
+		t.Errorf("unexpected errors '%v', '%v' (%d reads)", err1, err2, reads)
+	}
+
+	// changed value
+	forgetFact("test", "key")
+	if val, _ := cachedFact("test", "key", read); val != "value6" {
+		t.Errorf("unexpected value '%s' after forgetFact", val)
+	}
+
+	// concurrent access
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key%d", i%3)
+			_, _ = cachedFact("concurrent", key, func() (string, error) { return key, nil })
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if val, _ := cachedFact("concurrent", key, read); val != key {
+			t.Errorf("unexpected value '%s' for '%s'", val, key)
+		}
+	}
+
+	DisableFactCache()
+	if val, _ := cachedFact("test", "key", read); val != "value7" {
+		t.Errorf("unexpected value '%s' after disabling the cache", val)
+	}
+}
+
+func TestFactCacheSys(t *testing.T) {
+	EnableFactCache()
+	defer DisableFactCache()
+	key := "kernel/mm/transparent_hugepage/enabled"
+	val1, err1 := GetSysChoice(key)
+	val2, err2 := GetSysChoice(key)
+	if val1 != val2 || err1 != err2 {
+		t.Errorf("cached value differs: '%s' - '%s'", val1, val2)
+	}
+	if choice, _ := GetSysChoice("kernel/not_avail"); choice != "PNA" {
+		t.Error(choice)
+	}
+	if choice, _ := GetSysChoice("kernel/not_avail"); choice != "PNA" {
+		t.Error(choice)
+	}
+}
+
+func TestFactCacheFiles(t *testing.T) {
+	tstFile := path.Join(t.TempDir(), "mounts")
+	_ = os.WriteFile(tstFile, []byte("tmpfs /dev/shm tmpfs rw,nosuid,nodev 0 0\n"), 0644)
+	EnableFactCache()
+	defer DisableFactCache()
+	if mounts := ParseMtab(tstFile); len(mounts) != 1 {
+		t.Errorf("unexpected mounts '%+v'", mounts)
+	}
+	// changed file content is not seen, as long as the fact is cached
+	_ = os.WriteFile(tstFile, []byte("tmpfs /dev/shm tmpfs rw 0 0\n/dev/sda1 / xfs rw 0 0\n"), 0644)
+	if mounts := ParseMtab(tstFile); len(mounts) != 1 {
+		t.Errorf("unexpected mounts '%+v'", mounts)
+	}
+	forgetFact("file", tstFile)
+	if mounts := ParseMtab(tstFile); len(mounts) != 2 {
+		t.Errorf("unexpected mounts '%+v'", mounts)
+	}
+}
+
+func TestFactCacheBlockDevices(t *testing.T) {
+	oldChangedDir := blockDevChangedDir
+	defer func() { blockDevChangedDir = oldChangedDir }()
+	blockDevChangedDir = t.TempDir()
+	bdevFile := path.Join(SaptuneSectionDir, "blockdev.run")
+	defer os.Remove(bdevFile)
+	bdevConf := BlockDev{
+		AllBlockDevs:    []string{"sda"},
+		BlockAttributes: map[string]map[string]string{"sda": {"IO_SCHEDULER": "none", "NRREQ": "32"}},
+	}
+	if err := storeBlockDeviceInfo(bdevConf); err != nil {
+		t.Fatal(err)
+	}
+
+	EnableFactCache()
+	defer DisableFactCache()
+	blkDev1, _ := GetBlockDeviceInfo()
+	// each caller gets its own copy
+	blkDev1.BlockAttributes["sda"]["IO_SCHEDULER"] = "bfq"
+	blkDev2, _ := GetBlockDeviceInfo()
+	if blkDev2.BlockAttributes["sda"]["IO_SCHEDULER"] != "none" {
+		t.Errorf("cached block device information changed by caller: '%+v'", blkDev2)
+	}
+	// information read from the cache, not from the file
+	_ = os.WriteFile(bdevFile, []byte("{}"), 0644)
+	if blkDev, _ := GetBlockDeviceInfo(); len(blkDev.AllBlockDevs) != 1 {
+		t.Errorf("block device information not cached: '%+v'", blkDev)
+	}
+	// storing new information drops the cached one
+	bdevConf.AllBlockDevs = []string{"sda", "sdb"}
+	bdevConf.BlockAttributes["sdb"] = map[string]string{"IO_SCHEDULER": "bfq"}
+	if err := storeBlockDeviceInfo(bdevConf); err != nil {
+		t.Fatal(err)
+	}
+	if blkDev, _ := GetBlockDeviceInfo(); len(blkDev.AllBlockDevs) != 2 {
+		t.Errorf("outdated block device information: '%+v'", blkDev)
+	}
+}
@@ -2,7 +2,6 @@ package system
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
 	"reflect"
 	"regexp"
@@ -135,12 +134,9 @@ func ParseMounts(txt string) (mounts MountPoints) {
 // ParseMtab return all mount points defined in a given file.
 // Returns empty list of mount points on error
 func ParseMtab(file string) MountPoints {
-	mounts := ""
-	content, err := os.ReadFile(file)
+	mounts, err := readCachedFile(file)
 	if err != nil {
 		ErrorLog("failed to read file '%s': %v", file, err)
-	} else {
-		mounts = string(content)
 	}
 	return ParseMounts(mounts)
 }
@@ -166,6 +162,7 @@ func ParseMtabMounts() MountPoints {
 // RemountSHM invoke mount command to resize /dev/shm to the specified value.
 func RemountSHM(newSizeMB uint64) error {
 	InfoLog("RemountSHM - remount size is '%d'M\n", newSizeMB)
+	forgetFact("file", procMounts)
 	cmd := exec.Command("mount", "-o", fmt.Sprintf("remount,size=%dM", newSizeMB), "/dev/shm")
 	if out, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to invoke external command mount: %v, output: %s", err, out)
@@ -0,0 +1,67 @@
+package system
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// journalSocket is the socket of the native journald protocol
+var journalSocket = "/run/systemd/journal/socket"
+
+// SaptuneDriftMsgID is the journal MESSAGE_ID used for compliance events
+// written by 'saptune service watch'
+const SaptuneDriftMsgID = "6a3b2d1ee0c54c1f9d5e0c8b5f3a7c21"
+
+// journal priorities (syslog levels)
+const (
+	JournalPrioErr     = "3"
+	JournalPrioWarning = "4"
+	JournalPrioNotice  = "5"
+	JournalPrioInfo    = "6"
+)
+
+// journalMessage builds the datagram for the native journald protocol
+// from the given fields. The field names are sorted to get a stable result.
+// Values containing a newline are serialised in the binary format.
+func journalMessage(fields map[string]string) []byte {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var msg bytes.Buffer
+	for _, key := range keys {
+		val := fields[key]
+		if strings.Contains(val, "\n") {
+			msg.WriteString(key + "\n")
+			_ = binary.Write(&msg, binary.LittleEndian, uint64(len(val)))
+			msg.WriteString(val + "\n")
+		} else {
+			msg.WriteString(key + "=" + val + "\n")
+		}
+	}
+	return msg.Bytes()
+}
+
+// JournalSend sends a structured log entry to the systemd journal
+// The field names have to be upper case (e.g. MESSAGE, PRIORITY).
+// SYSLOG_IDENTIFIER is set to 'saptune', if not provided by the caller.
+// Logging of a failure is left to the caller
+func JournalSend(fields map[string]string) error {
+	if _, ok := fields["SYSLOG_IDENTIFIER"]; !ok {
+		fields["SYSLOG_IDENTIFIER"] = "saptune"
+	}
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the systemd journal - %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(journalMessage(fields)); err != nil {
+		return fmt.Errorf("failed to write to the systemd journal - %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package system
+
+import (
+	"testing"
+)
+
+func TestJournalMessage(t *testing.T) {
+	fields := map[string]string{"PRIORITY": "4", "MESSAGE": "drift detected", "SAPTUNE_NOTE": "4711"}
+	exp := "MESSAGE=drift detected\nPRIORITY=4\nSAPTUNE_NOTE=4711\n"
+	if msg := string(journalMessage(fields)); msg != exp {
+		t.Errorf("got: '%s', expected: '%s'\n", msg, exp)
+	}
+	fields = map[string]string{"MESSAGE": "a\nb"}
+	exp = "MESSAGE\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n"
+	if msg := string(journalMessage(fields)); msg != exp {
+		t.Errorf("got: '%q', expected: '%q'\n", msg, exp)
+	}
+}
+
+func TestJournalSend(t *testing.T) {
+	oldSocket := journalSocket
+	defer func() { journalSocket = oldSocket }()
+	journalSocket = "/tmp/saptune_no_journal_socket"
+	fields := map[string]string{"MESSAGE": "test"}
+	if err := JournalSend(fields); err == nil {
+		t.Error("expected an error for a missing journal socket")
+	}
+	if fields["SYSLOG_IDENTIFIER"] != "saptune" {
+		t.Errorf("got: '%s', expected: 'saptune'\n", fields["SYSLOG_IDENTIFIER"])
+	}
+}
@@ -166,22 +166,24 @@ type JSol struct {
 
 // JStatus is the whole 'saptune status'
 type JStatus struct {
-	Services        JStatusServs   `json:"services"`
-	SystemdSysState string         `json:"systemd system state"`
-	TuningState     string         `json:"tuning state"`
-	VirtEnv         string         `json:"virtualization"`
-	SaptuneVersion  string         `json:"configured version"`
-	RPMVersion      string         `json:"package version"`
-	ConfiguredSol   []string       `json:"Solution enabled"`
-	ConfSolNotes    []JSol         `json:"Notes enabled by Solution"`
-	AppliedSol      []JAppliedSol  `json:"Solution applied"`
-	AppliedSolNotes []JSol         `json:"Notes applied by Solution"`
-	ConfiguredNotes []string       `json:"Notes enabled additionally"`
-	EnabledNotes    []string       `json:"Notes enabled"`
-	AppliedNotes    []string       `json:"Notes applied"`
-	OrphanedOver    []string       `json:"orphaned Overrides"`
-	Staging         JStatusStaging `json:"staging"`
-	Msg             string         `json:"remember message"`
+	Services        JStatusServs    `json:"services"`
+	SystemdSysState string          `json:"systemd system state"`
+	TuningState     string          `json:"tuning state"`
+	RebootRequired  bool            `json:"reboot required"`
+	RebootParams    []PendingReboot `json:"reboot pending parameters"`
+	VirtEnv         string          `json:"virtualization"`
+	SaptuneVersion  string          `json:"configured version"`
+	RPMVersion      string          `json:"package version"`
+	ConfiguredSol   []string        `json:"Solution enabled"`
+	ConfSolNotes    []JSol          `json:"Notes enabled by Solution"`
+	AppliedSol      []JAppliedSol   `json:"Solution applied"`
+	AppliedSolNotes []JSol          `json:"Notes applied by Solution"`
+	ConfiguredNotes []string        `json:"Notes enabled additionally"`
+	EnabledNotes    []string        `json:"Notes enabled"`
+	AppliedNotes    []string        `json:"Notes applied"`
+	OrphanedOver    []string        `json:"orphaned Overrides"`
+	Staging         JStatusStaging  `json:"staging"`
+	Msg             string          `json:"remember message"`
 }
 
 // JStatusStaging contains the staging infos for 'saptune status'
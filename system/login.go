@@ -2,7 +2,10 @@ package system
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"path"
+	"sort"
 	"strings"
 )
 
@@ -79,3 +82,101 @@ func SetTasksMax(userID, limit string) error {
 	_, err := exec.Command(cmdName, cmdArgs...).CombinedOutput()
 	return err
 }
+
+// logindMainConfs are the locations of the main logind configuration file
+// in the order of their priority. Only the first existing file is used
+var logindMainConfs = []string{"/etc/systemd/logind.conf", "/run/systemd/logind.conf", "/usr/local/lib/systemd/logind.conf", "/usr/lib/systemd/logind.conf", "/usr/etc/systemd/logind.conf"}
+
+// logindDropInDirs are the locations of the logind drop-in files in the
+// order of their priority. A drop-in file masks drop-in files with the
+// same name in directories of lower priority
+var logindDropInDirs = []string{"/etc/systemd/logind.conf.d", "/run/systemd/logind.conf.d", "/usr/local/lib/systemd/logind.conf.d", "/usr/lib/systemd/logind.conf.d", "/usr/etc/systemd/logind.conf.d"}
+
+// GetLogindConfValue returns the effective value of a logind.conf key of
+// the merged logind configuration (main configuration file and drop-in
+// files) and if the key is set at all.
+// The drop-in files are read in lexicographic order of their names,
+// the last setting of a key wins
+func GetLogindConfValue(key string) (string, bool) {
+	return systemdConfValue(logindMainConfs, logindDropInDirs, "Login", key)
+}
+
+// systemdConfValue returns the effective value of a key of a systemd
+// configuration file in the section 'section' of the merged configuration
+// (main configuration file and drop-in files). The last setting wins
+func systemdConfValue(mainConfs, dropInDirs []string, section, key string) (string, bool) {
+	val := ""
+	found := false
+	for _, conf := range systemdConfFiles(mainConfs, dropInDirs) {
+		if v, ok := systemdConfKeyFromFile(conf, section, key); ok {
+			val = v
+			found = true
+		}
+	}
+	return val, found
+}
+
+// systemdConfFiles returns the configuration files in the order
+// systemd reads them
+func systemdConfFiles(mainConfs, dropInDirs []string) []string {
+	files := []string{}
+	for _, conf := range mainConfs {
+		if _, err := os.Stat(conf); err == nil {
+			files = append(files, conf)
+			break
+		}
+	}
+	dropIns := make(map[string]string)
+	names := []string{}
+	for _, dir := range dropInDirs {
+		dirCont, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range dirCont {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".conf") {
+				continue
+			}
+			if _, ok := dropIns[name]; ok {
+				// masked by a directory of higher priority
+				continue
+			}
+			dropIns[name] = path.Join(dir, name)
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		files = append(files, dropIns[name])
+	}
+	return files
+}
+
+// systemdConfKeyFromFile returns the last setting of key in the section
+// 'section' of a systemd configuration file
+func systemdConfKeyFromFile(fileName, section, key string) (string, bool) {
+	val := ""
+	found := false
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return val, found
+	}
+	inSection := false
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inSection = line == "["+section+"]"
+			continue
+		}
+		fields := strings.SplitN(line, "=", 2)
+		if inSection && len(fields) == 2 && strings.TrimSpace(fields[0]) == key {
+			val = strings.TrimSpace(fields[1])
+			found = true
+		}
+	}
+	return val, found
+}
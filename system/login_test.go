@@ -2,6 +2,7 @@ package system
 
 import (
 	"os"
+	"path"
 	"testing"
 )
 
@@ -125,3 +126,38 @@ func TestMissingSystemctlCmd(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestGetLogindConfValue(t *testing.T) {
+	oldMainConfs := logindMainConfs
+	oldDropInDirs := logindDropInDirs
+	defer func() {
+		logindMainConfs = oldMainConfs
+		logindDropInDirs = oldDropInDirs
+	}()
+	tstDir := path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/logind")
+	// only the first existing main configuration file is used
+	logindMainConfs = []string{path.Join(tstDir, "run/logind.conf"), path.Join(tstDir, "etc/logind.conf"), path.Join(tstDir, "usr/logind.conf")}
+	logindDropInDirs = []string{path.Join(tstDir, "etc/logind.conf.d"), path.Join(tstDir, "run/logind.conf.d"), path.Join(tstDir, "usr/logind.conf.d")}
+
+	// saptune-RemoveIPC.conf is the last drop-in file
+	if val, found := GetLogindConfValue("RemoveIPC"); !found || val != "no" {
+		t.Errorf("RemoveIPC: expected 'no', actual '%s' - %v", val, found)
+	}
+	// 50-masked.conf in /run masks the file with the same name in /usr
+	if val, found := GetLogindConfValue("KillUserProcesses"); !found || val != "no" {
+		t.Errorf("KillUserProcesses: expected 'no', actual '%s' - %v", val, found)
+	}
+	// set in the unused main configuration file and outside [Login]
+	if val, found := GetLogindConfValue("HandlePowerKey"); found {
+		t.Errorf("HandlePowerKey: expected not found, actual '%s'", val)
+	}
+
+	logindMainConfs = []string{path.Join(tstDir, "usr/logind.conf")}
+	logindDropInDirs = []string{}
+	if val, found := GetLogindConfValue("KillUserProcesses"); !found || val != "yes" {
+		t.Errorf("KillUserProcesses: expected 'yes', actual '%s' - %v", val, found)
+	}
+	if val, found := GetLogindConfValue("RemoveIPC"); found {
+		t.Errorf("RemoveIPC: expected not found, actual '%s'", val)
+	}
+}
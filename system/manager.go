@@ -0,0 +1,284 @@
+package system
+
+// handling of the systemd manager defaults of system.conf
+
+import (
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SystemdConfDir is the drop-in directory of the systemd manager
+// configuration system.conf
+var SystemdConfDir = "/etc/systemd/system.conf.d"
+
+// IsManagerKey matches the keys of the systemd manager defaults, which
+// can be used in the [systemd] section
+var IsManagerKey = regexp.MustCompile(`^Default(TasksMax|Limit[A-Z]+|Timeout(Start|Stop|Abort)Sec)$`)
+
+// managerProps is used to mock 'systemctl show' of the systemd manager
+// in the tests
+var managerProps = showManagerProps
+
+// isTimeSpan matches a single element of a systemd time span like '1min'
+var isTimeSpan = regexp.MustCompile(`^(\d+(?:\.\d+)?)([a-zµ]*)$`)
+
+// timeSpanSep separates the elements of a time span without spaces
+// like '1min30s'
+var timeSpanSep = regexp.MustCompile(`(\d)([a-zµ]+)\s*`)
+
+// timeUnits contains the systemd time span units in microseconds
+var timeUnits = map[string]float64{
+	"us": 1, "usec": 1, "µs": 1,
+	"ms": 1e3, "msec": 1e3,
+	"": 1e6, "s": 1e6, "sec": 1e6, "second": 1e6, "seconds": 1e6,
+	"m": 60e6, "min": 60e6, "minute": 60e6, "minutes": 60e6,
+	"h": 3600e6, "hr": 3600e6, "hour": 3600e6, "hours": 3600e6,
+	"d": 86400e6, "day": 86400e6, "days": 86400e6,
+	"w": 604800e6, "week": 604800e6, "weeks": 604800e6,
+}
+
+// sizeUnits contains the multiplier of the size units used by systemd for
+// resource limits (base 1024)
+var sizeUnits = map[string]float64{
+	"": 1, "b": 1, "k": 1 << 10, "m": 1 << 20, "g": 1 << 30, "t": 1 << 40, "p": 1 << 50, "e": 1 << 60,
+}
+
+// GetManagerValue returns the effective value of a systemd manager default
+// as reported by 'systemctl show' of the manager.
+// Limits are returned as 'soft:hard', if soft and hard limit differ.
+// 'NA', if the value is not available
+func GetManagerValue(key string) string {
+	if !IsManagerKey.MatchString(key) {
+		return "NA"
+	}
+	prop := managerProperty(key)
+	props := []string{prop}
+	if strings.HasPrefix(key, "DefaultLimit") {
+		props = append(props, prop+"Soft")
+	}
+	vals, err := managerProps(props...)
+	if err != nil || vals[prop] == "" {
+		InfoLog("systemd manager property '%s' not available - %v", prop, err)
+		return "NA"
+	}
+	val := vals[prop]
+	if soft, ok := vals[prop+"Soft"]; ok && soft != "" && soft != val {
+		val = soft + ":" + val
+	}
+	return val
+}
+
+// CmpManagerValue compares the actual value of a systemd manager default
+// with the expected value of the Note definition file. Both values are
+// normalized before, so '90', '90s' and '1min 30s' or '8M' and '8388608'
+// are equal. Percentages of DefaultTasksMax are related to the maximal
+// number of tasks of the system
+func CmpManagerValue(key, actval, expval string) bool {
+	if actval == expval {
+		return true
+	}
+	act, aerr := normManagerValue(key, actval)
+	exp, eerr := normManagerValue(key, expval)
+	return aerr == nil && eerr == nil && act == exp
+}
+
+// IsValidManagerValue checks, if the value is valid for the systemd
+// manager default
+func IsValidManagerValue(key, value string) bool {
+	if !IsManagerKey.MatchString(key) {
+		return false
+	}
+	_, err := normManagerValue(key, value)
+	return err == nil
+}
+
+// limitsKBItems are the resource limit items of limits.conf, which use KB,
+// while systemd uses bytes
+var limitsKBItems = map[string]bool{
+	"core":    true,
+	"data":    true,
+	"fsize":   true,
+	"memlock": true,
+	"rss":     true,
+	"stack":   true,
+}
+
+// ManagerLimit returns the soft and hard limit of the systemd manager
+// default of a resource limit item of limits.conf (e.g. nofile, memlock)
+// in the units of limits.conf. math.MaxInt64 means 'infinity'
+func ManagerLimit(item string) (int64, int64, error) {
+	key := "DefaultLimit" + strings.ToUpper(item)
+	val := GetManagerValue(key)
+	if val == "NA" {
+		return 0, 0, fmt.Errorf("systemd manager default '%s' not available", key)
+	}
+	soft, hard, err := parseLimitPair(key, val)
+	if err != nil {
+		return 0, 0, err
+	}
+	if limitsKBItems[item] {
+		if soft != math.MaxInt64 {
+			soft = soft / 1024
+		}
+		if hard != math.MaxInt64 {
+			hard = hard / 1024
+		}
+	}
+	return soft, hard, nil
+}
+
+// managerProperty maps a system.conf key to the property name of
+// 'systemctl show' (e.g. DefaultTimeoutStopSec to DefaultTimeoutStopUSec)
+func managerProperty(key string) string {
+	if strings.HasPrefix(key, "DefaultTimeout") {
+		return strings.TrimSuffix(key, "Sec") + "USec"
+	}
+	return key
+}
+
+// showManagerProps calls 'systemctl show' for the manager with the
+// given properties
+func showManagerProps(props ...string) (map[string]string, error) {
+	vals := make(map[string]string)
+	cmdArgs := []string{"show", "--no-pager", "--property=" + strings.Join(props, ",")}
+	out, err := exec.Command(systemctlCmd, cmdArgs...).Output()
+	DebugLog("showManagerProps - %s %s : '%+v'", systemctlCmd, strings.Join(cmdArgs, " "), err)
+	if err != nil {
+		return vals, err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(fields) == 2 {
+			vals[fields[0]] = fields[1]
+		}
+	}
+	return vals, nil
+}
+
+// normManagerValue converts a value of a systemd manager default into a
+// comparable string
+func normManagerValue(key, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(key, "DefaultTimeout"):
+		usec, err := parseTimeSpan(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(usec, 10), nil
+	case strings.HasPrefix(key, "DefaultLimit"):
+		soft, hard, err := parseLimitPair(key, value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d:%d", soft, hard), nil
+	case key == "DefaultTasksMax":
+		if value == "infinity" {
+			return value, nil
+		}
+		if strings.HasSuffix(value, "%") {
+			pct, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatInt(int64(float64(systemTasksMax())*pct/100), 10), nil
+		}
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return "", err
+		}
+		return value, nil
+	}
+	return value, nil
+}
+
+// parseTimeSpan converts a systemd time span like '1min 30s', '90' or
+// 'infinity' into microseconds. math.MaxInt64 means 'infinity'
+func parseTimeSpan(value string) (int64, error) {
+	if value == "infinity" {
+		return math.MaxInt64, nil
+	}
+	// '1min30s' is valid too
+	value = timeSpanSep.ReplaceAllString(strings.ToLower(value), "$1$2 ")
+	usec := 0.0
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty time span")
+	}
+	for _, span := range fields {
+		match := isTimeSpan.FindStringSubmatch(span)
+		if match == nil {
+			return 0, fmt.Errorf("wrong time span '%s'", value)
+		}
+		unit, ok := timeUnits[match[2]]
+		if !ok {
+			return 0, fmt.Errorf("wrong time unit '%s' in '%s'", match[2], value)
+		}
+		num, _ := strconv.ParseFloat(match[1], 64)
+		usec = usec + num*unit
+	}
+	return int64(usec), nil
+}
+
+// parseLimitPair converts the value of a resource limit like '1024:524288',
+// '8M' or 'infinity' into the soft and hard limit.
+// math.MaxInt64 means 'infinity'
+func parseLimitPair(key, value string) (int64, int64, error) {
+	fields := strings.SplitN(value, ":", 2)
+	soft, err := parseLimit(key, fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	hard := soft
+	if len(fields) == 2 {
+		if hard, err = parseLimit(key, fields[1]); err != nil {
+			return 0, 0, err
+		}
+	}
+	return soft, hard, nil
+}
+
+// parseLimit converts a single resource limit value. Size units are
+// only allowed for limits in bytes
+func parseLimit(key, value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "infinity" || value == "unlimited" {
+		return math.MaxInt64, nil
+	}
+	unit := ""
+	if key != "DefaultLimitNOFILE" && key != "DefaultLimitNPROC" && len(value) > 0 {
+		if last := value[len(value)-1:]; last < "0" || last > "9" {
+			unit = last
+			value = value[:len(value)-1]
+		}
+	}
+	mult, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("wrong size unit '%s' for '%s'", unit, key)
+	}
+	num, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("wrong limit value '%s' for '%s'", value, key)
+	}
+	if float64(num)*mult >= math.MaxInt64 {
+		return math.MaxInt64, nil
+	}
+	return int64(float64(num) * mult), nil
+}
+
+// systemTasksMax returns the maximal number of tasks of the system, which
+// is used by systemd as base for percentages of TasksMax
+func systemTasksMax() int64 {
+	// TASKS_MAX of systemd
+	max := int64(4194303)
+	if pidMax, err := GetSysctlInt("kernel.pid_max"); err == nil && pidMax > 0 && int64(pidMax-1) < max {
+		max = int64(pidMax - 1)
+	}
+	if threadsMax, err := GetSysctlInt("kernel.threads-max"); err == nil && threadsMax > 0 && int64(threadsMax) < max {
+		max = int64(threadsMax)
+	}
+	return max
+}
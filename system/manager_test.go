@@ -0,0 +1,117 @@
+package system
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestGetManagerValue(t *testing.T) {
+	oldManagerProps := managerProps
+	defer func() { managerProps = oldManagerProps }()
+	managerProps = func(props ...string) (map[string]string, error) {
+		vals := map[string]string{
+			"DefaultTasksMax":         "4915",
+			"DefaultLimitNOFILE":      "524288",
+			"DefaultLimitNOFILESoft":  "1024",
+			"DefaultLimitMEMLOCK":     "8388608",
+			"DefaultLimitMEMLOCKSoft": "8388608",
+			"DefaultTimeoutStopUSec":  "1min 30s",
+			"DefaultLimitSTACK":       "infinity",
+			"DefaultLimitSTACKSoft":   "8388608",
+		}
+		ret := make(map[string]string)
+		for _, prop := range props {
+			ret[prop] = vals[prop]
+		}
+		return ret, nil
+	}
+
+	for key, exp := range map[string]string{"DefaultTasksMax": "4915", "DefaultLimitNOFILE": "1024:524288", "DefaultLimitMEMLOCK": "8388608", "DefaultTimeoutStopSec": "1min 30s", "DefaultTimeoutStartSec": "NA", "UnknownKey": "NA"} {
+		if val := GetManagerValue(key); val != exp {
+			t.Errorf("%s: expected '%s', actual '%s'", key, exp, val)
+		}
+	}
+	soft, hard, err := ManagerLimit("nofile")
+	if err != nil || soft != 1024 || hard != 524288 {
+		t.Errorf("nofile: '%d:%d' - %v", soft, hard, err)
+	}
+	// limits.conf uses KB for memlock
+	soft, hard, err = ManagerLimit("memlock")
+	if err != nil || soft != 8192 || hard != 8192 {
+		t.Errorf("memlock: '%d:%d' - %v", soft, hard, err)
+	}
+	// limits.conf uses KB for stack, too
+	soft, hard, err = ManagerLimit("stack")
+	if err != nil || soft != 8192 || hard != math.MaxInt64 {
+		t.Errorf("stack: '%d:%d' - %v", soft, hard, err)
+	}
+	if _, _, err := ManagerLimit("nproc"); err == nil {
+		t.Error("expected an error for not available manager default")
+	}
+
+	managerProps = func(props ...string) (map[string]string, error) {
+		return map[string]string{}, fmt.Errorf("systemctl failed")
+	}
+	if val := GetManagerValue("DefaultTasksMax"); val != "NA" {
+		t.Error(val)
+	}
+}
+
+func TestCmpManagerValue(t *testing.T) {
+	matches := [][]string{
+		{"DefaultTimeoutStopSec", "1min 30s", "90"},
+		{"DefaultTimeoutStopSec", "1min 30s", "90s"},
+		{"DefaultTimeoutStopSec", "1min 30s", "1min30s"},
+		{"DefaultTimeoutStopSec", "infinity", "infinity"},
+		{"DefaultTimeoutStopSec", "500ms", "0.5s"},
+		{"DefaultLimitNOFILE", "1048576", "1048576:1048576"},
+		{"DefaultLimitNOFILE", "1024:524288", "1024:524288"},
+		{"DefaultLimitMEMLOCK", "8388608", "8M"},
+		{"DefaultLimitMEMLOCK", "infinity", "unlimited"},
+		{"DefaultTasksMax", "infinity", "infinity"},
+		{"DefaultTasksMax", "4915", "4915"},
+	}
+	for _, m := range matches {
+		if !CmpManagerValue(m[0], m[1], m[2]) {
+			t.Errorf("%s: '%s' and '%s' should match", m[0], m[1], m[2])
+		}
+	}
+	diffs := [][]string{
+		{"DefaultTimeoutStopSec", "1min 30s", "60"},
+		{"DefaultTimeoutStopSec", "1min 30s", "fast"},
+		{"DefaultLimitNOFILE", "1024:524288", "524288"},
+		{"DefaultLimitNOFILE", "1024", "1K"},
+		{"DefaultLimitMEMLOCK", "8388608", "8G"},
+		{"DefaultTasksMax", "4915", "infinity"},
+	}
+	for _, d := range diffs {
+		if CmpManagerValue(d[0], d[1], d[2]) {
+			t.Errorf("%s: '%s' and '%s' should not match", d[0], d[1], d[2])
+		}
+	}
+	// percentage of the maximal number of tasks
+	pct := fmt.Sprintf("%d", systemTasksMax()*15/100)
+	if !CmpManagerValue("DefaultTasksMax", pct, "15%") {
+		t.Errorf("'%s' and '15%%' should match", pct)
+	}
+}
+
+func TestParseTimeSpanAndLimit(t *testing.T) {
+	for val, exp := range map[string]int64{"90": 90000000, "1min 30s": 90000000, "2h": 7200000000, "100ms": 100000, "infinity": math.MaxInt64} {
+		if usec, err := parseTimeSpan(val); err != nil || usec != exp {
+			t.Errorf("'%s': expected '%d', actual '%d' - %v", val, exp, usec, err)
+		}
+	}
+	for _, val := range []string{"", "1 lightyear", "-5s"} {
+		if _, err := parseTimeSpan(val); err == nil {
+			t.Errorf("'%s': expected an error", val)
+		}
+	}
+	if !IsValidManagerValue("DefaultLimitMEMLOCK", "64G") || IsValidManagerValue("DefaultLimitNOFILE", "64G") || IsValidManagerValue("DefaultUnknown", "1") {
+		t.Error("wrong validation of manager values")
+	}
+	if key := managerProperty("DefaultTimeoutStopSec"); key != "DefaultTimeoutStopUSec" {
+		t.Error(key)
+	}
+}
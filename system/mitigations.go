@@ -0,0 +1,65 @@
+package system
+
+// handling of the CPU vulnerabilities
+// state of the mitigations read from /sys/devices/system/cpu/vulnerabilities
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// cpuVulnerabilitiesDir contains a file for each CPU vulnerability known by
+// the running kernel
+var cpuVulnerabilitiesDir = "/sys/devices/system/cpu/vulnerabilities"
+
+// GetCPUVulnerabilities returns the sorted list of the CPU vulnerabilities
+// known by the running kernel
+func GetCPUVulnerabilities() []string {
+	vulns := []string{}
+	entries, err := os.ReadDir(cpuVulnerabilitiesDir)
+	if err != nil {
+		InfoLog("failed to read the CPU vulnerabilities - %v", err)
+		return vulns
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			vulns = append(vulns, entry.Name())
+		}
+	}
+	sort.Strings(vulns)
+	return vulns
+}
+
+// GetCPUVulnerabilityState returns the mitigation state of the CPU
+// vulnerability reported by the kernel like 'Not affected',
+// 'Vulnerable' or 'Mitigation: PTI'
+func GetCPUVulnerabilityState(vuln string) (string, error) {
+	if vuln == "" || strings.Contains(vuln, "/") {
+		return "", fmt.Errorf("wrong CPU vulnerability name '%s'", vuln)
+	}
+	content, err := os.ReadFile(path.Join(cpuVulnerabilitiesDir, vuln))
+	if err != nil {
+		return "", err
+	}
+	// multiple blanks are reduced to one blank
+	return strings.Join(strings.Fields(string(content)), " "), nil
+}
+
+// MatchesMitigationState checks, if the mitigation state of a CPU
+// vulnerability matches one of the expected states.
+// The expected states are a '|' separated list. Each entry is compared
+// case-insensitive with the beginning of the state, so 'Mitigation' matches
+// 'Mitigation: PTI'
+func MatchesMitigationState(state, expected string) bool {
+	state = strings.ToLower(state)
+	for _, exp := range strings.Split(expected, "|") {
+		exp = strings.ToLower(strings.Join(strings.Fields(exp), " "))
+		if exp != "" && strings.HasPrefix(state, exp) {
+			return true
+		}
+	}
+	return false
+}
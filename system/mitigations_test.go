@@ -0,0 +1,58 @@
+package system
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestCPUVulnerabilities(t *testing.T) {
+	oldVulnDir := cpuVulnerabilitiesDir
+	defer func() { cpuVulnerabilitiesDir = oldVulnDir }()
+	cpuVulnerabilitiesDir = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/vulnerabilities")
+
+	exp := []string{"mds", "meltdown", "spectre_v1", "spectre_v2", "srbds"}
+	if vulns := GetCPUVulnerabilities(); !reflect.DeepEqual(vulns, exp) {
+		t.Errorf("expected: '%+v', got: '%+v'", exp, vulns)
+	}
+	state, err := GetCPUVulnerabilityState("spectre_v2")
+	if err != nil || state != "Mitigation: Retpolines; IBPB: conditional; IBRS_FW; STIBP: conditional; RSB filling" {
+		t.Errorf("got: '%s', %v", state, err)
+	}
+	if state, err = GetCPUVulnerabilityState("srbds"); err != nil || state != "Not affected" {
+		t.Errorf("got: '%s', %v", state, err)
+	}
+	if _, err = GetCPUVulnerabilityState("retbleed"); err == nil {
+		t.Error("not existing vulnerability should be reported as error")
+	}
+	if _, err = GetCPUVulnerabilityState("../cpu0"); err == nil {
+		t.Error("wrong vulnerability name should be reported as error")
+	}
+
+	cpuVulnerabilitiesDir = "/tmp/saptune_test_not_existing/vulnerabilities"
+	if vulns := GetCPUVulnerabilities(); len(vulns) != 0 {
+		t.Errorf("expected empty list, got: '%+v'", vulns)
+	}
+}
+
+func TestMatchesMitigationState(t *testing.T) {
+	tests := []struct {
+		state, expected string
+		match           bool
+	}{
+		{"Mitigation: PTI", "Mitigation", true},
+		{"Mitigation: PTI", "mitigation: pti", true},
+		{"Mitigation: PTI", "Not affected", false},
+		{"Not affected", "Mitigation|Not affected", true},
+		{"Not affected", "Mitigation|Not\taffected", true},
+		{"Vulnerable: SMT vulnerable", "Mitigation | Not affected", false},
+		{"Vulnerable: SMT vulnerable", "Vulnerable", true},
+		{"Vulnerable", "", false},
+	}
+	for _, tst := range tests {
+		if match := MatchesMitigationState(tst.state, tst.expected); match != tst.match {
+			t.Errorf("'%s' - '%s': expected '%v', got '%v'", tst.state, tst.expected, tst.match, match)
+		}
+	}
+}
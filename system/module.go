@@ -0,0 +1,119 @@
+package system
+
+// handling of kernel module parameters (/sys/module/<module>/parameters)
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// modprobeDirs contains the directories of the modprobe configuration files
+// in ascending precedence. A file in a later directory overrides a file
+// with the same name in an earlier directory
+var modprobeDirs = []string{"/lib/modprobe.d", "/usr/lib/modprobe.d", "/run/modprobe.d", "/etc/modprobe.d"}
+
+// ModuleParameter returns the module and the parameter name of a sys key
+// 'module/<module>/parameters/<param>' (or with '.' as separator).
+// Returns false, if the key is no module parameter
+func ModuleParameter(key string) (string, string, bool) {
+	fields := strings.Split(strings.Replace(key, ".", "/", -1), "/")
+	if len(fields) != 4 || fields[0] != "module" || fields[2] != "parameters" {
+		return "", "", false
+	}
+	return fields[1], fields[3], true
+}
+
+// normModuleName returns the module name with '_' instead of '-', as both
+// are equivalent for the kernel and modprobe
+func normModuleName(module string) string {
+	return strings.Replace(module, "-", "_", -1)
+}
+
+// modprobeConfFiles returns the modprobe configuration files ('*.conf') in
+// the order they are read by modprobe
+func modprobeConfFiles() []string {
+	confFiles := make(map[string]string)
+	for _, dir := range modprobeDirs {
+		_, files := ListDir(dir, "")
+		for _, file := range files {
+			if strings.HasSuffix(file, ".conf") {
+				confFiles[file] = path.Join(dir, file)
+			}
+		}
+	}
+	names := make([]string, 0, len(confFiles))
+	for name := range confFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	files := make([]string, 0, len(names))
+	for _, name := range names {
+		files = append(files, confFiles[name])
+	}
+	return files
+}
+
+// ConfiguredModuleOption returns the value of the module parameter
+// configured for the next load of the module by the kernel command line
+// ('<module>.<param>=<value>') or by an 'options' line of the modprobe
+// configuration files. Returns an empty string, if the parameter is not
+// configured
+func ConfiguredModuleOption(module, param string) string {
+	module = normModuleName(module)
+	value := ""
+	for _, file := range modprobeConfFiles() {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 3 || fields[0] != "options" || normModuleName(fields[1]) != module {
+				continue
+			}
+			for _, opt := range fields[2:] {
+				if kv := strings.SplitN(opt, "=", 2); len(kv) == 2 && kv[0] == param {
+					value = kv[1]
+				}
+			}
+		}
+	}
+	// the kernel command line wins over the modprobe configuration
+	if cmdVal := ParseCmdline(ProcCmdLine, module+"."+param); cmdVal != "NA" && cmdVal != "" {
+		value = cmdVal
+	}
+	return value
+}
+
+// SetModuleParameter sets the module parameter 'parameter' (a sys key
+// 'module/<module>/parameters/<param>') to 'value'.
+// Module parameters, which are read-only at runtime, can only be changed
+// by the module options during the next load of the module. If the value is
+// configured in the module options, the parameter 'key' is registered as
+// 'pending reboot' instead of failing
+func SetModuleParameter(key, parameter, value string) error {
+	module, param, ok := ModuleParameter(parameter)
+	if !ok {
+		return SetSysString(parameter, value)
+	}
+	current, _ := GetSysString(parameter)
+	if current == value {
+		// nothing to change, e.g. revert of a value waiting for
+		// a reload of the module
+		RemovePendingReboot(key)
+		return nil
+	}
+	err := SetSysString(parameter, value)
+	if err == nil {
+		RemovePendingReboot(key)
+		return nil
+	}
+	if ConfiguredModuleOption(module, param) == value {
+		AddPendingReboot(key, fmt.Sprintf("module parameter configured in the module options, active after a reload of module '%s' or a reboot", module))
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package system
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestModuleParameter(t *testing.T) {
+	for key, exp := range map[string][]string{"module.nvme_core.parameters.io_timeout": {"nvme_core", "io_timeout"}, "module/watchdog/parameters/open_timeout": {"watchdog", "open_timeout"}} {
+		module, param, ok := ModuleParameter(key)
+		if !ok || module != exp[0] || param != exp[1] {
+			t.Errorf("'%s' - got: '%s', '%s', '%v'", key, module, param, ok)
+		}
+	}
+	for _, key := range []string{"kernel.mm.ksm.run", "module.nvme_core.io_timeout", "block.sda.queue.scheduler"} {
+		if _, _, ok := ModuleParameter(key); ok {
+			t.Errorf("'%s' reported as module parameter", key)
+		}
+	}
+}
+
+func TestConfiguredModuleOption(t *testing.T) {
+	oldModprobeDirs := modprobeDirs
+	oldCmdline := ProcCmdLine
+	defer func() {
+		modprobeDirs = oldModprobeDirs
+		ProcCmdLine = oldCmdline
+	}()
+	tstDir := "/tmp/saptune_test_modprobe"
+	defer os.RemoveAll(tstDir)
+	modprobeDirs = []string{path.Join(tstDir, "usr"), path.Join(tstDir, "etc")}
+	for _, dir := range modprobeDirs {
+		_ = os.MkdirAll(dir, 0755)
+	}
+	ProcCmdLine = path.Join(tstDir, "cmdline")
+	_ = os.WriteFile(ProcCmdLine, []byte("root=/dev/sda1 quiet"), 0644)
+
+	if val := ConfiguredModuleOption("nvme_core", "io_timeout"); val != "" {
+		t.Errorf("got: '%s', expected: ''", val)
+	}
+	_ = os.WriteFile(path.Join(modprobeDirs[0], "50-nvme.conf"), []byte("options nvme-core io_timeout=30\n"), 0644)
+	_ = os.WriteFile(path.Join(modprobeDirs[0], "60-nvme.conf"), []byte("# options nvme_core io_timeout=60\noptions nvme_core multipath=N io_timeout=4294967295\n"), 0644)
+	if val := ConfiguredModuleOption("nvme_core", "io_timeout"); val != "4294967295" {
+		t.Errorf("got: '%s', expected: '4294967295'", val)
+	}
+	// file in /etc overrides the file with the same name in /usr
+	_ = os.WriteFile(path.Join(modprobeDirs[1], "60-nvme.conf"), []byte("options nvme_core multipath=N\n"), 0644)
+	if val := ConfiguredModuleOption("nvme_core", "io_timeout"); val != "30" {
+		t.Errorf("got: '%s', expected: '30'", val)
+	}
+	// kernel command line wins
+	_ = os.WriteFile(ProcCmdLine, []byte("root=/dev/sda1 nvme_core.io_timeout=240 quiet"), 0644)
+	if val := ConfiguredModuleOption("nvme_core", "io_timeout"); val != "240" {
+		t.Errorf("got: '%s', expected: '240'", val)
+	}
+}
+
+func TestSetModuleParameter(t *testing.T) {
+	// read-only module parameter
+	param := "module.kernel.parameters.consoleblank"
+	current, err := GetSysString(param)
+	if err != nil || os.Getuid() != 0 {
+		t.Skip("read-only module parameter not available")
+	}
+	oldModprobeDirs := modprobeDirs
+	oldCmdline := ProcCmdLine
+	oldPendingFile := pendingRebootFile
+	defer func() {
+		modprobeDirs = oldModprobeDirs
+		ProcCmdLine = oldCmdline
+		pendingRebootFile = oldPendingFile
+	}()
+	tstDir := "/tmp/saptune_test_module"
+	defer os.RemoveAll(tstDir)
+	modprobeDirs = []string{tstDir}
+	_ = os.MkdirAll(tstDir, 0755)
+	ProcCmdLine = path.Join(tstDir, "cmdline")
+	_ = os.WriteFile(ProcCmdLine, []byte("quiet"), 0644)
+	pendingRebootFile = path.Join(tstDir, "pending_reboot")
+	newVal := current + "1"
+
+	if err := SetModuleParameter(param, param, newVal); err == nil {
+		t.Error("expected an error for a not configured read-only module parameter")
+	}
+	if reason := PendingRebootReason(param, newVal); reason != "" {
+		t.Errorf("expected no pending reboot, got: '%s'", reason)
+	}
+	_ = os.WriteFile(path.Join(tstDir, "kernel.conf"), []byte("options kernel consoleblank="+newVal+"\n"), 0644)
+	if err := SetModuleParameter(param, param, newVal); err != nil {
+		t.Error(err)
+	}
+	if reason := PendingRebootReason(param, newVal); reason == "" {
+		t.Error("missing pending reboot for the configured module parameter")
+	}
+	// revert to the active value
+	if err := SetModuleParameter(param, param, current); err != nil {
+		t.Error(err)
+	}
+	if reason := PendingRebootReason(param, current); reason != "" {
+		t.Errorf("expected no pending reboot, got: '%s'", reason)
+	}
+}
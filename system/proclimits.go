@@ -0,0 +1,281 @@
+package system
+
+// check of the effective resource limits of the running processes
+// read from /proc/<pid>/limits
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procDir is the proc filesystem with the running processes
+var procDir = "/proc"
+
+// getentCmd resolves the domains of limits.conf by the name service switch
+var getentCmd = "/usr/bin/getent"
+
+// passwdFile and groupFile are used to resolve the domains of limits.conf,
+// if getent is not available
+var passwdFile = "/etc/passwd"
+var groupFile = "/etc/group"
+
+// limitsRuntimeCheck controls, if the effective limits of the running
+// processes are checked (LIMITS_RUNTIME_CHECK of the saptune configuration)
+var limitsRuntimeCheck = false
+
+// procLimitNames maps the items of limits.conf to the names used in
+// /proc/<pid>/limits
+var procLimitNames = map[string]string{
+	"nofile":  "Max open files",
+	"memlock": "Max locked memory",
+	"nproc":   "Max processes",
+}
+
+// SetLimitsRuntimeCheck enables or disables the check of the effective
+// limits of the running processes (LIMITS_RUNTIME_CHECK of the saptune
+// configuration)
+func SetLimitsRuntimeCheck(val string) {
+	limitsRuntimeCheck = val == "yes"
+}
+
+// LimitsRuntimeCheck returns, if the effective limits of the running
+// processes should be checked
+func LimitsRuntimeCheck() bool {
+	return limitsRuntimeCheck
+}
+
+// ProcessLimits returns the effective limit 'item' of the running processes
+// belonging to the limits.conf domain (user or @group) grouped by the
+// process name. The values use the units of limits.conf (memlock in KB),
+// 'unlimited' for no limit. For type '-' soft and hard limit are returned
+// as 'soft:hard', if they differ. Different values of processes with the
+// same name are separated by ','
+func ProcessLimits(domain, typeName, item string) (map[string]string, error) {
+	procLimits := make(map[string]string)
+	limName, ok := procLimitNames[item]
+	if !ok {
+		return procLimits, fmt.Errorf("runtime check of limit item '%s' not supported", item)
+	}
+	uids, gids, err := domainIDs(domain)
+	if err != nil {
+		return procLimits, err
+	}
+	dirCont, err := os.ReadDir(procDir)
+	if err != nil {
+		return procLimits, err
+	}
+	values := make(map[string]map[string]bool)
+	for _, entry := range dirCont {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		name, uid, procGids := procIdentity(entry.Name())
+		if name == "" || !matchesDomain(uid, procGids, uids, gids) {
+			continue
+		}
+		soft, hard, err := procLimit(entry.Name(), limName, item)
+		if err != nil {
+			// process vanished in the meantime
+			DebugLog("ProcessLimits - %v", err)
+			continue
+		}
+		val := soft
+		switch typeName {
+		case "hard":
+			val = hard
+		case "-":
+			if soft != hard {
+				val = soft + ":" + hard
+			}
+		}
+		if values[name] == nil {
+			values[name] = make(map[string]bool)
+		}
+		values[name][val] = true
+	}
+	for name, vals := range values {
+		list := []string{}
+		for val := range vals {
+			list = append(list, val)
+		}
+		sort.Strings(list)
+		procLimits[name] = strings.Join(list, ",")
+	}
+	return procLimits, nil
+}
+
+// domainIDs resolves a limits.conf domain to the matching user ids or
+// group ids. Only user names and '@group' are supported, because a runtime
+// check of '*' or ranges of ids is not meaningful.
+// Users and groups are resolved by the name service switch, so users and
+// groups of a directory service (LDAP, SSSD) are found too
+func domainIDs(domain string) (map[string]bool, map[string]bool, error) {
+	uids := make(map[string]bool)
+	gids := make(map[string]bool)
+	if domain == "" || domain == "*" || strings.ContainsAny(domain, "%:") {
+		return uids, gids, fmt.Errorf("runtime check of limits domain '%s' not supported", domain)
+	}
+	if !strings.HasPrefix(domain, "@") {
+		// user name
+		for _, fields := range lookupIDEntries("passwd", domain, passwdFile) {
+			uids[fields[2]] = true
+		}
+		if len(uids) == 0 {
+			return uids, gids, fmt.Errorf("user '%s' not found", domain)
+		}
+		return uids, gids, nil
+	}
+	group := strings.TrimPrefix(domain, "@")
+	for _, fields := range lookupIDEntries("group", group, groupFile) {
+		gids[fields[2]] = true
+		if len(fields) > 3 && fields[3] != "" {
+			// members of the group
+			for _, member := range strings.Split(fields[3], ",") {
+				for _, user := range lookupIDEntries("passwd", member, passwdFile) {
+					uids[user[2]] = true
+				}
+			}
+		}
+	}
+	if len(gids) == 0 {
+		return uids, gids, fmt.Errorf("group '%s' not found", group)
+	}
+	return uids, gids, nil
+}
+
+// lookupIDEntries returns the colon separated fields of the entries of the
+// name service switch database 'db' (passwd or group) matching 'name'.
+// If getent is not available, the local file is read
+func lookupIDEntries(db, name, file string) [][]string {
+	entries := [][]string{}
+	if !CmdIsAvailable(getentCmd) {
+		for _, fields := range readIDFile(file) {
+			if fields[0] == name {
+				entries = append(entries, fields)
+			}
+		}
+		return entries
+	}
+	out, err := exec.Command(getentCmd, db, name).Output()
+	if err != nil {
+		// exit code 2 - name not found in the database
+		DebugLog("lookupIDEntries - 'getent %s %s' - %v", db, name, err)
+		return entries
+	}
+	for _, fields := range parseIDLines(string(out)) {
+		if fields[0] == name {
+			entries = append(entries, fields)
+		}
+	}
+	return entries
+}
+
+// readIDFile returns the colon separated fields of the lines of
+// /etc/passwd or /etc/group
+func readIDFile(file string) [][]string {
+	entries := [][]string{}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		DebugLog("readIDFile - %v", err)
+		return entries
+	}
+	return parseIDLines(string(content))
+}
+
+// parseIDLines returns the colon separated fields of the passwd or group
+// entries
+func parseIDLines(content string) [][]string {
+	entries := [][]string{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		entries = append(entries, fields)
+	}
+	return entries
+}
+
+// procIdentity returns the name, the effective user id and the effective
+// and supplementary group ids of a process from /proc/<pid>/status
+func procIdentity(pid string) (string, string, []string) {
+	name, uid := "", ""
+	gids := []string{}
+	content, err := os.ReadFile(path.Join(procDir, pid, "status"))
+	if err != nil {
+		return name, uid, gids
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Name:":
+			name = fields[1]
+		case "Uid:":
+			if len(fields) > 2 {
+				uid = fields[2]
+			}
+		case "Gid:":
+			if len(fields) > 2 {
+				gids = append(gids, fields[2])
+			}
+		case "Groups:":
+			gids = append(gids, fields[1:]...)
+		}
+	}
+	return name, uid, gids
+}
+
+// matchesDomain checks, if the process belongs to the users or groups of
+// the limits.conf domain
+func matchesDomain(uid string, procGids []string, uids, gids map[string]bool) bool {
+	if uids[uid] {
+		return true
+	}
+	for _, gid := range procGids {
+		if gids[gid] {
+			return true
+		}
+	}
+	return false
+}
+
+// procLimit returns the soft and hard limit 'limName' of a process from
+// /proc/<pid>/limits in the units of limits.conf
+func procLimit(pid, limName, item string) (string, string, error) {
+	content, err := os.ReadFile(path.Join(procDir, pid, "limits"))
+	if err != nil {
+		return "", "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, limName) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, limName))
+		if len(fields) < 2 {
+			break
+		}
+		return procLimitValue(fields[0], item), procLimitValue(fields[1], item), nil
+	}
+	return "", "", fmt.Errorf("limit '%s' not found for process '%s'", limName, pid)
+}
+
+// procLimitValue converts a value of /proc/<pid>/limits to the units of
+// limits.conf. memlock is reported in bytes, but configured in KB
+func procLimitValue(val, item string) string {
+	if val == "unlimited" || item != "memlock" {
+		return val
+	}
+	bytes, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return val
+	}
+	return strconv.FormatInt(bytes/1024, 10)
+}
@@ -0,0 +1,107 @@
+package system
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestProcessLimits(t *testing.T) {
+	oldProcDir := procDir
+	oldPasswdFile := passwdFile
+	oldGroupFile := groupFile
+	oldGetentCmd := getentCmd
+	defer func() {
+		procDir = oldProcDir
+		passwdFile = oldPasswdFile
+		groupFile = oldGroupFile
+		getentCmd = oldGetentCmd
+	}()
+	tstDir := path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/proclimits")
+	procDir = path.Join(tstDir, "proc")
+	passwdFile = path.Join(tstDir, "passwd")
+	groupFile = path.Join(tstDir, "group")
+	// without getent the local files are read
+	getentCmd = "/file_does_not_exist"
+
+	tests := []struct {
+		domain, typeName, item string
+		exp                    map[string]string
+	}{
+		{"@sapsys", "soft", "nofile", map[string]string{"hdbindexserver": "1024,1048576", "sapstartsrv": "65536"}},
+		{"ha1adm", "-", "nofile", map[string]string{"hdbindexserver": "1024:1048576,1048576"}},
+		{"@sapsys", "hard", "memlock", map[string]string{"hdbindexserver": "8192", "sapstartsrv": "64"}},
+		{"@sapinst", "-", "nproc", map[string]string{"hdbindexserver": "unlimited", "sapstartsrv": "unlimited"}},
+	}
+	for _, tst := range tests {
+		val, err := ProcessLimits(tst.domain, tst.typeName, tst.item)
+		if err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(val, tst.exp) {
+			t.Errorf("%s %s %s - expected: '%+v', got: '%+v'", tst.domain, tst.typeName, tst.item, tst.exp, val)
+		}
+	}
+	for _, dom := range []string{"*", "unknown", "@unknown", "%sapsys", "1000:1010"} {
+		if _, err := ProcessLimits(dom, "soft", "nofile"); err == nil {
+			t.Errorf("domain '%s' should be reported as error", dom)
+		}
+	}
+	if _, err := ProcessLimits("@sapsys", "soft", "stack"); err == nil {
+		t.Error("item 'stack' should be reported as error")
+	}
+}
+
+func TestProcessLimitsNSS(t *testing.T) {
+	oldProcDir := procDir
+	oldPasswdFile := passwdFile
+	oldGroupFile := groupFile
+	oldGetentCmd := getentCmd
+	defer func() {
+		procDir = oldProcDir
+		passwdFile = oldPasswdFile
+		groupFile = oldGroupFile
+		getentCmd = oldGetentCmd
+	}()
+	tstDir := path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/proclimits")
+	procDir = path.Join(tstDir, "proc")
+	// users and groups only known by the name service switch (e.g. LDAP)
+	passwdFile = "/file_does_not_exist"
+	groupFile = "/file_does_not_exist"
+	getentCmd = path.Join(t.TempDir(), "getent")
+	if err := os.WriteFile(getentCmd, []byte("#!/bin/sh\ngrep \"^$2:\" "+tstDir+"/$1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if val, err := ProcessLimits("ha1adm", "-", "nofile"); err != nil || !reflect.DeepEqual(val, map[string]string{"hdbindexserver": "1024:1048576,1048576"}) {
+		t.Errorf("unexpected limits '%+v' - %v", val, err)
+	}
+	if val, err := ProcessLimits("@sapinst", "-", "nproc"); err != nil || !reflect.DeepEqual(val, map[string]string{"hdbindexserver": "unlimited", "sapstartsrv": "unlimited"}) {
+		t.Errorf("unexpected limits '%+v' - %v", val, err)
+	}
+	uids, gids, err := domainIDs("@sapinst")
+	if err != nil || !reflect.DeepEqual(uids, map[string]bool{"1001": true, "1002": true}) || !reflect.DeepEqual(gids, map[string]bool{"1001": true}) {
+		t.Errorf("unexpected ids '%+v', '%+v' - %v", uids, gids, err)
+	}
+	for _, dom := range []string{"unknown", "@unknown"} {
+		if _, err := ProcessLimits(dom, "soft", "nofile"); err == nil {
+			t.Errorf("domain '%s' should be reported as error", dom)
+		}
+	}
+}
+
+func TestLimitsRuntimeCheck(t *testing.T) {
+	defer SetLimitsRuntimeCheck("no")
+	if LimitsRuntimeCheck() {
+		t.Error("runtime check should be disabled by default")
+	}
+	SetLimitsRuntimeCheck("yes")
+	if !LimitsRuntimeCheck() {
+		t.Error("runtime check should be enabled")
+	}
+	SetLimitsRuntimeCheck("no")
+	if LimitsRuntimeCheck() {
+		t.Error("runtime check should be disabled")
+	}
+}
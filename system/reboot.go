@@ -0,0 +1,190 @@
+package system
+
+// track settings, which are configured, but only get active after a reboot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GrubDefault is the grub default configuration file
+var GrubDefault = "/etc/default/grub"
+
+// procStat is used to get the boot time of the system
+var procStat = "/proc/stat"
+
+// pendingRebootFile contains the settings registered as 'pending reboot'
+// As it is located in /run the file will be removed by the next reboot,
+// which is exactly the time the settings get active
+var pendingRebootFile = "/run/saptune/pending_reboot"
+
+// PendingReboot describes a parameter, which is configured, but will only
+// get active after the next reboot of the system
+type PendingReboot struct {
+	Parameter string `json:"parameter"`
+	Reason    string `json:"reason"`
+}
+
+// ParseGrubDefault returns the value of the boot option 'option' found in
+// GRUB_CMDLINE_LINUX_DEFAULT or GRUB_CMDLINE_LINUX of the grub default
+// configuration file or 'NA', if not available
+func ParseGrubDefault(fileName, option string) string {
+	opt := "NA"
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return opt
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "GRUB_CMDLINE_LINUX_DEFAULT=") && !strings.HasPrefix(line, "GRUB_CMDLINE_LINUX=") {
+			continue
+		}
+		fields := strings.SplitN(line, "=", 2)
+		for _, param := range strings.Fields(strings.Trim(fields[1], "\"'")) {
+			pfields := strings.SplitN(param, "=", 2)
+			if pfields[0] == option {
+				if len(pfields) > 1 {
+					opt = pfields[1]
+				} else {
+					opt = option
+				}
+			}
+		}
+	}
+	return opt
+}
+
+// bootTime returns the time of the last boot of the system
+func bootTime() (time.Time, error) {
+	content, err := os.ReadFile(procStat)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "btime" {
+			btime, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(btime, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("boot time not found in '%s'", procStat)
+}
+
+// ChangedSinceBoot returns true, if the file was modified after the last
+// boot of the system
+func ChangedSinceBoot(fileName string) bool {
+	finfo, err := os.Stat(fileName)
+	if err != nil {
+		return false
+	}
+	btime, err := bootTime()
+	if err != nil {
+		DebugLog("ChangedSinceBoot - %v", err)
+		return false
+	}
+	return finfo.ModTime().After(btime)
+}
+
+// readPendingReboots reads the registered 'pending reboot' settings
+func readPendingReboots() map[string]string {
+	pending := make(map[string]string)
+	content, err := os.ReadFile(pendingRebootFile)
+	if err == nil && len(content) != 0 {
+		_ = json.Unmarshal(content, &pending)
+	}
+	return pending
+}
+
+// writePendingReboots writes the registered 'pending reboot' settings
+func writePendingReboots(pending map[string]string) error {
+	if len(pending) == 0 {
+		if err := os.Remove(pendingRebootFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	content, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(pendingRebootFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(pendingRebootFile, content, 0644)
+}
+
+// AddPendingReboot registers a parameter, which was set, but will only get
+// active after the next reboot (e.g. module parameter or SMT settings)
+func AddPendingReboot(param, reason string) {
+	pending := readPendingReboots()
+	pending[param] = reason
+	if err := writePendingReboots(pending); err != nil {
+		WarningLog("Failed to register pending reboot for parameter '%s' - %v", param, err)
+	}
+}
+
+// RemovePendingReboot removes a parameter from the registered 'pending
+// reboot' settings (e.g. after a revert)
+func RemovePendingReboot(param string) {
+	pending := readPendingReboots()
+	if _, ok := pending[param]; !ok {
+		return
+	}
+	delete(pending, param)
+	if err := writePendingReboots(pending); err != nil {
+		WarningLog("Failed to remove pending reboot for parameter '%s' - %v", param, err)
+	}
+}
+
+// PendingRebootReason returns the reason, why the parameter with the
+// expected value will only get active after the next reboot or an empty
+// string, if the parameter is not waiting for a reboot
+func PendingRebootReason(param, expected string) string {
+	return PendingRebootReasonOf(PendingRebootReasons(), param, expected)
+}
+
+// PendingRebootReasons returns the registered 'pending reboot' settings
+// as map of parameter and reason. Used to read the settings only once for
+// several parameters (see PendingRebootReasonOf)
+func PendingRebootReasons() map[string]string {
+	return readPendingReboots()
+}
+
+// PendingRebootReasonOf is like PendingRebootReason, but uses the
+// 'pending reboot' settings 'pending' read before by PendingRebootReasons
+func PendingRebootReasonOf(pending map[string]string, param, expected string) string {
+	if reason, ok := pending[param]; ok {
+		return reason
+	}
+	if strings.HasPrefix(param, "grub:") && expected != "" {
+		option := strings.TrimPrefix(param, "grub:")
+		if ParseGrubDefault(GrubDefault, option) == expected && ParseCmdline(ProcCmdLine, option) != expected {
+			return fmt.Sprintf("boot option configured in %s, but not yet active", GrubDefault)
+		}
+	}
+	return ""
+}
+
+// PendingReboots returns all registered 'pending reboot' settings and an
+// entry for the grub default configuration, if it was changed after the
+// last boot. The list is sorted by parameter name.
+func PendingReboots() []PendingReboot {
+	reboots := []PendingReboot{}
+	for param, reason := range readPendingReboots() {
+		reboots = append(reboots, PendingReboot{Parameter: param, Reason: reason})
+	}
+	if ChangedSinceBoot(GrubDefault) {
+		reboots = append(reboots, PendingReboot{Parameter: GrubDefault, Reason: "file changed after the last boot"})
+	}
+	sort.Slice(reboots, func(i, j int) bool { return reboots[i].Parameter < reboots[j].Parameter })
+	return reboots
+}
@@ -0,0 +1,105 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+var grubDefault = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/grub_default")
+
+func TestParseGrubDefault(t *testing.T) {
+	for option, exp := range map[string]string{"transparent_hugepage": "never", "numa_balancing": "disable", "intel_idle.max_cstate": "1", "quiet": "quiet", "processor.max_cstate": "NA"} {
+		if val := ParseGrubDefault(grubDefault, option); val != exp {
+			t.Errorf("option '%s' - got: '%s', expected: '%s'", option, val, exp)
+		}
+	}
+	if val := ParseGrubDefault("/file_does_not_exist", "quiet"); val != "NA" {
+		t.Errorf("got: '%s', expected: 'NA'", val)
+	}
+}
+
+func TestChangedSinceBoot(t *testing.T) {
+	oldProcStat := procStat
+	defer func() { procStat = oldProcStat }()
+	procStat = "/tmp/saptune_test_proc_stat"
+	defer os.Remove(procStat)
+	tstFile := "/tmp/saptune_test_changed"
+	_ = os.WriteFile(tstFile, []byte("test"), 0644)
+	defer os.Remove(tstFile)
+
+	// boot an hour ago
+	_ = os.WriteFile(procStat, []byte(fmt.Sprintf("cpu  1 2 3 4\nbtime %d\nprocesses 4711\n", time.Now().Add(-time.Hour).Unix())), 0644)
+	if !ChangedSinceBoot(tstFile) {
+		t.Error("expected file changed after boot")
+	}
+	// boot in the future
+	_ = os.WriteFile(procStat, []byte(fmt.Sprintf("btime %d\n", time.Now().Add(time.Hour).Unix())), 0644)
+	if ChangedSinceBoot(tstFile) {
+		t.Error("expected file not changed after boot")
+	}
+	// no boot time available
+	_ = os.WriteFile(procStat, []byte("cpu  1 2 3 4\n"), 0644)
+	if ChangedSinceBoot(tstFile) {
+		t.Error("expected 'false' for missing boot time")
+	}
+	if ChangedSinceBoot("/file_does_not_exist") {
+		t.Error("expected 'false' for missing file")
+	}
+}
+
+func TestPendingReboot(t *testing.T) {
+	oldPendingFile := pendingRebootFile
+	oldGrubDefault := GrubDefault
+	oldCmdline := ProcCmdLine
+	defer func() {
+		pendingRebootFile = oldPendingFile
+		GrubDefault = oldGrubDefault
+		ProcCmdLine = oldCmdline
+	}()
+	pendingRebootFile = "/tmp/saptune_test_reboot/pending_reboot"
+	defer os.RemoveAll(path.Dir(pendingRebootFile))
+	GrubDefault = grubDefault
+	ProcCmdLine = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/cmdline1")
+
+	AddPendingReboot("smt", "SMT control changed")
+	if reason := PendingRebootReason("smt", "off"); reason != "SMT control changed" {
+		t.Errorf("got: '%s', expected: 'SMT control changed'", reason)
+	}
+	// read once for several parameters
+	pending := PendingRebootReasons()
+	if reason := PendingRebootReasonOf(pending, "smt", "off"); reason != "SMT control changed" {
+		t.Errorf("got: '%s', expected: 'SMT control changed'", reason)
+	}
+	if reason := PendingRebootReasonOf(pending, "vm.swappiness", "10"); reason != "" {
+		t.Errorf("unexpected reason '%s'", reason)
+	}
+	reason := PendingRebootReason("grub:transparent_hugepage", "never")
+	if reason != "boot option configured in "+grubDefault+", but not yet active" {
+		t.Errorf("unexpected reason '%s'", reason)
+	}
+	if reason := PendingRebootReason("grub:transparent_hugepage", "always"); reason != "" {
+		t.Errorf("expected no reason, got: '%s'", reason)
+	}
+	if reason := PendingRebootReason("vm.swappiness", "10"); reason != "" {
+		t.Errorf("expected no reason, got: '%s'", reason)
+	}
+	found := false
+	for _, entry := range PendingReboots() {
+		if entry.Parameter == "smt" && entry.Reason == "SMT control changed" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("missing 'smt' in pending reboots: '%+v'", PendingReboots())
+	}
+	RemovePendingReboot("smt")
+	if reason := PendingRebootReason("smt", "off"); reason != "" {
+		t.Errorf("expected no reason, got: '%s'", reason)
+	}
+	if _, err := os.Stat(pendingRebootFile); !os.IsNotExist(err) {
+		t.Error("expected removed pending reboot file")
+	}
+}
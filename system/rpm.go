@@ -3,36 +3,198 @@ package system
 // wrapper to rpm command
 
 import (
-	"fmt"
 	"os/exec"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 	"unicode"
 )
 
 var alphanumPattern = regexp.MustCompile("([a-zA-Z]+)|([0-9]+)|(~)")
 
-// GetRpmVers return the version of an installed RPM
-func GetRpmVers(rpm string) string {
-	// rpm -q --qf '%{VERSION}-%{RELEASE}\n' glibc
-	rpmVers := ""
-	cmdName := "/bin/rpm"
-	rpm = strings.TrimPrefix(rpm, "?")
-	notInstalled := fmt.Sprintf("package %s is not installed", rpm)
-	cmdArgs := []string{"-q", "--qf", "%{VERSION}-%{RELEASE}\n", rpm}
+var rpmCmd = "/bin/rpm"
+
+// rpmQueryFormat is the query format used to get the package information
+const rpmQueryFormat = "%{NAME}|%{EPOCH}|%{VERSION}|%{RELEASE}|%{ARCH}\n"
+
+// RpmPackage contains the information of an installed package
+type RpmPackage struct {
+	Name    string
+	Epoch   string
+	Version string
+	Release string
+	Arch    string
+}
+
+// VersRel returns 'version-release' of the package
+func (pkg RpmPackage) VersRel() string {
+	return pkg.Version + "-" + pkg.Release
+}
+
+// EpochVersRel returns 'epoch:version-release' of the package or
+// 'version-release', if the package has no epoch
+func (pkg RpmPackage) EpochVersRel() string {
+	if pkg.Epoch == "" || pkg.Epoch == "0" {
+		return pkg.VersRel()
+	}
+	return pkg.Epoch + ":" + pkg.VersRel()
+}
+
+// rpmPackages caches the installed packages found by the rpm queries.
+// The rpm database is not changed by saptune, so the cache is kept for
+// the whole saptune call
+var rpmPackages = struct {
+	sync.Mutex
+	pkgs map[string][]RpmPackage
+}{pkgs: make(map[string][]RpmPackage)}
+
+// QueryRpmPackages returns the installed packages for all given package
+// names. A package name can contain the architecture (e.g. 'glibc.i686')
+// to select a special package of a multilib installation. A leading '?'
+// (optional package) is ignored.
+// All packages not yet available in the cache are queried with a single
+// call of rpm. Packages not installed are reported with an empty list.
+func QueryRpmPackages(rpms ...string) map[string][]RpmPackage {
+	result := make(map[string][]RpmPackage)
+	query := []string{}
+	rpmPackages.Lock()
+	defer rpmPackages.Unlock()
+	for _, rpm := range rpms {
+		rpm = strings.TrimPrefix(rpm, "?")
+		if pkgs, ok := rpmPackages.pkgs[rpm]; ok {
+			result[rpm] = pkgs
+		} else if _, ok := result[rpm]; !ok {
+			result[rpm] = []RpmPackage{}
+			query = append(query, rpm)
+		}
+	}
+	if len(query) == 0 {
+		return result
+	}
+	// rpm -q --qf '%{NAME}|%{EPOCH}|%{VERSION}|%{RELEASE}|%{ARCH}\n' glibc tuned
+	cmdArgs := append([]string{"-q", "--qf", rpmQueryFormat}, query...)
+	cmdOut, err := exec.Command(rpmCmd, cmdArgs...).CombinedOutput()
+	DebugLog("QueryRpmPackages - '%s %s': '%+v'", rpmCmd, strings.Join(cmdArgs, " "), err)
+	queried, unknown := parseRpmQuery(query, string(cmdOut))
+	if err != nil && len(unknown) != 0 {
+		// rpm reports an error for each package, which is not
+		// installed. So only warn about unexpected output
+		WarningLog("There was an error running external command 'rpm -q --qf '%s' %s': %v, output: %s", strings.TrimSpace(rpmQueryFormat), strings.Join(query, " "), err, strings.Join(unknown, "\n"))
+	}
+	for rpm, pkgs := range queried {
+		rpmPackages.pkgs[rpm] = pkgs
+		result[rpm] = pkgs
+	}
+	return result
+}
+
+// parseRpmQuery assigns the package lines of the rpm query output to the
+// queried package names. It returns the output lines, which are neither
+// package lines nor 'package is not installed' messages
+func parseRpmQuery(query []string, out string) (map[string][]RpmPackage, []string) {
+	pkgs := make(map[string][]RpmPackage)
+	unknown := []string{}
+	for _, rpm := range query {
+		pkgs[rpm] = []RpmPackage{}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 5 {
+			if !strings.HasSuffix(line, " is not installed") {
+				unknown = append(unknown, line)
+			}
+			continue
+		}
+		pkg := RpmPackage{Name: fields[0], Epoch: fields[1], Version: fields[2], Release: fields[3], Arch: fields[4]}
+		if pkg.Epoch == "(none)" {
+			pkg.Epoch = ""
+		}
+		for _, rpm := range query {
+			if rpm == pkg.Name || rpm == pkg.Name+"."+pkg.Arch {
+				pkgs[rpm] = append(pkgs[rpm], pkg)
+			}
+		}
+	}
+	return pkgs, unknown
+}
 
-	cmdOut, err := exec.Command(cmdName, cmdArgs...).CombinedOutput()
-	if err != nil {
-		if len(string(cmdOut)) == 0 || strings.TrimSpace(string(cmdOut)) != notInstalled {
-			WarningLog("There was an error running external command 'rpm -q --qf '%%{VERSION}-%%{RELEASE}' %s': %v, output: %s", rpm, err, cmdOut)
+// rpmArch returns the rpm architecture name of the running system
+func rpmArch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x86_64"
+	case "arm64":
+		return "aarch64"
+	}
+	return runtime.GOARCH
+}
+
+// selectRpmPackage returns the package of the native architecture of the
+// system, if more than one package is installed (multilib). If more than one
+// package of the native architecture is installed (multiversion, e.g.
+// kernel-default), the package with the highest epoch:version-release is
+// used.
+// If there is no package of the native architecture, the last package
+// reported by rpm is used.
+func selectRpmPackage(pkgs []RpmPackage) (RpmPackage, bool) {
+	if len(pkgs) == 0 {
+		return RpmPackage{}, false
+	}
+	selected := pkgs[len(pkgs)-1]
+	native := false
+	for _, pkg := range pkgs {
+		if pkg.Arch != rpmArch() && pkg.Arch != "noarch" {
+			continue
+		}
+		// a missing epoch is compared as epoch '0'
+		if !native || !CmpRpmVers(rpmEpoch(selected)+":"+selected.VersRel(), rpmEpoch(pkg)+":"+pkg.VersRel()) {
+			selected = pkg
 		}
+		native = true
+	}
+	return selected, true
+}
+
+// rpmEpoch returns the epoch of the package or '0', if the package has no
+// epoch
+func rpmEpoch(pkg RpmPackage) string {
+	if pkg.Epoch == "" {
+		return "0"
+	}
+	return pkg.Epoch
+}
+
+// GetRpmPackage returns the information of an installed package.
+// If more than one package is installed (multilib) the package of the
+// native architecture is returned. Use 'name.arch' to select a special
+// package.
+func GetRpmPackage(rpm string) (RpmPackage, bool) {
+	rpm = strings.TrimPrefix(rpm, "?")
+	return selectRpmPackage(QueryRpmPackages(rpm)[rpm])
+}
+
+// GetRpmVers return the version of an installed RPM
+func GetRpmVers(rpm string) string {
+	pkg, ok := GetRpmPackage(rpm)
+	if !ok {
 		return ""
 	}
-	for _, line := range strings.Split(strings.TrimSpace(string(cmdOut)), "\n") {
-		// ANGI: TODO - was, wenn mehr als eine Paketversion installiert ist
-		rpmVers = line
+	return pkg.VersRel()
+}
+
+// GetRpmEpochVers return the version of an installed RPM including the
+// epoch, if the package has an epoch
+func GetRpmEpochVers(rpm string) string {
+	pkg, ok := GetRpmPackage(rpm)
+	if !ok {
+		return ""
 	}
-	return rpmVers
+	return pkg.EpochVersRel()
 }
 
 /* compare rpm versions
@@ -56,6 +218,17 @@ func CmpRpmVers(vers1, vers2 string) bool {
 		// rpm version and release are equal
 		return true
 	}
+	// the epoch is only compared, if the expected version contains one
+	actE, vers1 := splitRpmEpoch(vers1)
+	expE, vers2 := splitRpmEpoch(vers2)
+	if expE != "" {
+		if actE == "" {
+			actE = "0"
+		}
+		if ret := CheckRpmVers(actE, expE); ret != 0 {
+			return ret > 0
+		}
+	}
 	// actV is 228-150.22.1, expV is 228-142.1
 	actV := strings.Split(vers1, "-")
 	expV := strings.Split(vers2, "-")
@@ -157,3 +330,13 @@ func CheckRpmVers(vers1, vers2 string) int {
 	}
 	return -1
 }
+
+// splitRpmEpoch splits 'epoch:version-release' into the epoch and
+// 'version-release'. The epoch is empty, if the version has no epoch
+func splitRpmEpoch(vers string) (string, string) {
+	fields := strings.SplitN(vers, ":", 2)
+	if len(fields) == 2 && fields[0] != "" && strings.Trim(fields[0], "0123456789") == "" {
+		return fields[0], fields[1]
+	}
+	return "", vers
+}
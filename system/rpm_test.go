@@ -201,3 +201,103 @@ func TestCheckRpmVers(t *testing.T) {
 		t.Error("unequal")
 	}
 }
+
+func TestParseRpmQuery(t *testing.T) {
+	query := []string{"glibc", "glibc.i686", "tuned", "not-avail"}
+	out := "glibc|(none)|2.31|150300.46.1|x86_64\nglibc|(none)|2.31|150300.46.1|i686\ntuned|1|2.19.0|150400.1.2|noarch\npackage not-avail is not installed\nerror: rpmdb: something went wrong\n"
+	pkgs, unknown := parseRpmQuery(query, out)
+	if len(pkgs["glibc"]) != 2 || len(pkgs["glibc.i686"]) != 1 || pkgs["glibc.i686"][0].Arch != "i686" {
+		t.Errorf("wrong multilib packages: '%+v'", pkgs)
+	}
+	if len(pkgs["not-avail"]) != 0 {
+		t.Errorf("package 'not-avail' should not be installed: '%+v'", pkgs["not-avail"])
+	}
+	if len(unknown) != 1 || unknown[0] != "error: rpmdb: something went wrong" {
+		t.Errorf("wrong unknown output: '%v'", unknown)
+	}
+	if pkgs["glibc"][0].Epoch != "" || pkgs["glibc"][0].EpochVersRel() != "2.31-150300.46.1" {
+		t.Errorf("wrong version of glibc: '%+v'", pkgs["glibc"][0])
+	}
+	if pkgs["tuned"][0].EpochVersRel() != "1:2.19.0-150400.1.2" || pkgs["tuned"][0].VersRel() != "2.19.0-150400.1.2" {
+		t.Errorf("wrong version of tuned: '%+v'", pkgs["tuned"][0])
+	}
+	if pkg, ok := selectRpmPackage(pkgs["glibc"]); !ok || (pkg.Arch != rpmArch() && pkg.Arch != "i686") {
+		t.Errorf("wrong selected package: '%+v'", pkg)
+	}
+	if _, ok := selectRpmPackage(pkgs["not-avail"]); ok {
+		t.Error("no package should be selected for 'not-avail'")
+	}
+}
+
+func TestSelectRpmPackageMultiversion(t *testing.T) {
+	arch := rpmArch()
+	// multiversion, rpm does not report the packages sorted by version
+	kernels := []RpmPackage{
+		{Name: "kernel-default", Version: "5.14.21", Release: "150500.55.65.1", Arch: arch},
+		{Name: "kernel-default", Version: "5.14.21", Release: "150500.55.83.1", Arch: arch},
+		{Name: "kernel-default", Version: "5.14.21", Release: "150500.55.7.1", Arch: arch},
+	}
+	if pkg, ok := selectRpmPackage(kernels); !ok || pkg.Release != "150500.55.83.1" {
+		t.Errorf("wrong selected package: '%+v'", pkg)
+	}
+	// epoch wins over version, missing epoch is '0'
+	pkgs := []RpmPackage{
+		{Name: "tstpkg", Epoch: "1", Version: "1.0", Release: "1", Arch: arch},
+		{Name: "tstpkg", Version: "2.0", Release: "1", Arch: arch},
+		{Name: "tstpkg", Epoch: "2", Version: "3.0", Release: "1", Arch: "i686"},
+	}
+	if pkg, ok := selectRpmPackage(pkgs); !ok || pkg.EpochVersRel() != "1:1.0-1" {
+		t.Errorf("wrong selected package: '%+v'", pkg)
+	}
+	// no package of the native architecture, the last one is used
+	pkgs = []RpmPackage{
+		{Name: "tstpkg", Version: "2.0", Release: "1", Arch: "i686"},
+		{Name: "tstpkg", Version: "1.0", Release: "1", Arch: "i586"},
+	}
+	if pkg, ok := selectRpmPackage(pkgs); !ok || pkg.Arch != "i586" {
+		t.Errorf("wrong selected package: '%+v'", pkg)
+	}
+}
+
+func TestQueryRpmPackages(t *testing.T) {
+	oldRpmCmd := rpmCmd
+	defer func() { rpmCmd = oldRpmCmd }()
+	rpmCmd = "/usr/bin/echo"
+	// echo prints the query arguments, which are no package lines
+	pkgs := QueryRpmPackages("?saptune-test-pkg", "saptune-test-pkg")
+	if len(pkgs) != 1 || len(pkgs["saptune-test-pkg"]) != 0 {
+		t.Errorf("wrong packages: '%+v'", pkgs)
+	}
+	if vers := GetRpmEpochVers("saptune-test-pkg"); vers != "" {
+		t.Errorf("wrong version: '%s'", vers)
+	}
+	// result is cached
+	rpmPackages.Lock()
+	rpmPackages.pkgs["saptune-test-pkg"] = []RpmPackage{{Name: "saptune-test-pkg", Epoch: "2", Version: "1.0", Release: "1", Arch: "noarch"}}
+	rpmPackages.Unlock()
+	if vers := GetRpmVers("?saptune-test-pkg"); vers != "1.0-1" {
+		t.Errorf("wrong version: '%s'", vers)
+	}
+	if vers := GetRpmEpochVers("saptune-test-pkg"); vers != "2:1.0-1" {
+		t.Errorf("wrong version: '%s'", vers)
+	}
+}
+
+func TestCmpRpmEpochVers(t *testing.T) {
+	// no epoch expected, epoch of the installed package is ignored
+	if !CmpRpmVers("1:228-150.22.1", vers2) {
+		t.Error("epoch should be ignored")
+	}
+	if !CmpRpmVers("1:228-142.1", "1:228-142.1") || !CmpRpmVers("2:227-1", "1:228-142.1") {
+		t.Error("higher epoch should win")
+	}
+	if CmpRpmVers("229-1", "1:228-142.1") || CmpRpmVers("1:229-1", "2:228-142.1") {
+		t.Error("lower epoch should lose")
+	}
+	if e, v := splitRpmEpoch("3:1.0-1"); e != "3" || v != "1.0-1" {
+		t.Errorf("wrong split '%s', '%s'", e, v)
+	}
+	if e, v := splitRpmEpoch("1.0-1"); e != "" || v != "1.0-1" {
+		t.Errorf("wrong split '%s', '%s'", e, v)
+	}
+}
@@ -28,10 +28,22 @@ func GetAvailServices() map[string]string {
 
 // GetServiceName returns the systemd service name for supported services
 func GetServiceName(service string) string {
+	serviceName, ok := LookupServiceName(service)
+	if !ok {
+		WarningLog("skipping unknown service '%s'", service)
+	}
+	return serviceName
+}
+
+// LookupServiceName returns the systemd service name for supported services
+// and false, if the service is not available on the system
+func LookupServiceName(service string) (string, bool) {
 	serviceName := ""
+	servicesMutex.Lock()
 	if len(services) == 0 {
 		services = GetAvailServices()
 	}
+	servicesMutex.Unlock()
 	if _, ok := services[service]; ok {
 		serviceName = service
 	} else {
@@ -40,8 +52,5 @@ func GetServiceName(service string) string {
 			serviceName = serv
 		}
 	}
-	if serviceName == "" {
-		WarningLog("skipping unknown service '%s'", service)
-	}
-	return serviceName
+	return serviceName, serviceName != ""
 }
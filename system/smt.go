@@ -0,0 +1,237 @@
+package system
+
+// handling of the simultaneous multithreading (SMT, hyper-threading)
+// x86_64: /sys/devices/system/cpu/smt/control
+// ppc64le: online cpu threads per core, like 'ppc64_cpu --smt'
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// smtArch is the architecture used to select the SMT handling
+var smtArch = runtime.GOARCH
+
+// ppcCPUTree is the device tree directory of the cpus on IBM Power
+var ppcCPUTree = "/proc/device-tree/cpus"
+
+var smtCnt = 0
+
+// GetSMT retrieve the SMT state from the system
+// x86_64: 'on' or 'off', ppc64le: number of online threads per core
+// 'all:none', if SMT is not supported by the system
+func GetSMT() string {
+	if smtArch == "ppc64le" {
+		return getPPCSMT()
+	}
+	control, err := smtControl()
+	if err != nil {
+		PrintLog(smtCnt, "info", "SMT settings not supported by the system - %v", err)
+		return "all:none"
+	}
+	switch control {
+	case "on", "off":
+		return control
+	case "forceoff":
+		// disabled by the boot option 'nosmt=force'
+		return "off"
+	default:
+		// notsupported, notimplemented
+		PrintLog(smtCnt, "info", "SMT settings not supported by the system - state is '%s'", control)
+		return "all:none"
+	}
+}
+
+// SetSMT set the SMT state of the system
+// If the SMT state can not be changed at runtime, because the boot option
+// 'nosmt=force' disabled SMT permanently, the setting is registered as
+// 'pending reboot', if the boot option is already removed from the grub
+// default configuration
+func SetSMT(value string, revert bool) error {
+	if revert {
+		RemovePendingReboot("smt")
+	}
+	if value == "all:none" || value == "" {
+		return nil
+	}
+	if smtArch == "ppc64le" {
+		return setPPCSMT(value)
+	}
+	if value != "on" && value != "off" {
+		WarningLog("'%s' is not a valid value for smt, skipping.", value)
+		return nil
+	}
+	control, err := smtControl()
+	if err != nil {
+		PrintLog(smtCnt, "info", "SMT settings not supported by the system - %v", err)
+		return nil
+	}
+	switch control {
+	case "notsupported", "notimplemented":
+		PrintLog(smtCnt, "info", "SMT settings not supported by the system - state is '%s'", control)
+		return nil
+	case "forceoff":
+		if value == "off" {
+			return nil
+		}
+		if !revert && ParseGrubDefault(GrubDefault, "nosmt") == "NA" {
+			AddPendingReboot("smt", "SMT disabled by boot option 'nosmt=force', which is removed from "+GrubDefault)
+		} else {
+			WarningLog("SMT disabled by boot option 'nosmt=force', can not enable SMT at runtime. Remove the boot option and reboot the system")
+		}
+		return nil
+	}
+	if control == value {
+		return nil
+	}
+	if err := os.WriteFile(path.Join(cpuDir, "smt", "control"), []byte(value), 0644); err != nil {
+		WarningLog("failed to set '%s' in '%s': %v", value, path.Join(cpuDir, "smt", "control"), err)
+		return err
+	}
+	return nil
+}
+
+// smtControl returns the content of /sys/devices/system/cpu/smt/control
+func smtControl() (string, error) {
+	control, err := os.ReadFile(path.Join(cpuDir, "smt", "control"))
+	return strings.TrimSpace(string(control)), err
+}
+
+// IsValidSMT checks, if the value is a valid SMT setting for the
+// architecture and returns the valid values
+func IsValidSMT(value string) (bool, string) {
+	if smtArch != "ppc64le" {
+		return value == "on" || value == "off", "on off"
+	}
+	tpc := threadsPerCore()
+	valid := fmt.Sprintf("on off 1 - %d", tpc)
+	if value == "on" || value == "off" {
+		return true, valid
+	}
+	threads, err := strconv.Atoi(value)
+	return err == nil && threads >= 1 && threads <= tpc, valid
+}
+
+// PPCSMTValue maps 'on' and 'off' to the number of threads per core
+// on IBM Power
+func PPCSMTValue(value string) string {
+	if smtArch != "ppc64le" {
+		return value
+	}
+	switch value {
+	case "on":
+		return strconv.Itoa(threadsPerCore())
+	case "off":
+		return "1"
+	}
+	return value
+}
+
+// getPPCSMT returns the number of online threads per core or
+// 'all:none', if the number differs between the cores
+func getPPCSMT() string {
+	tpc := threadsPerCore()
+	if tpc == 0 {
+		PrintLog(smtCnt, "info", "SMT settings not supported by the system - unknown number of threads per core")
+		return "all:none"
+	}
+	smt := -1
+	for core, threads := range ppcCoreThreads(tpc) {
+		online := 0
+		for _, cpu := range threads {
+			if isCPUonline(cpu) {
+				online++
+			}
+		}
+		if smt >= 0 && smt != online {
+			PrintLog(smtCnt, "info", "Inconsistent SMT setting, core %d has %d threads online, others %d", core, online, smt)
+			return "all:none"
+		}
+		smt = online
+	}
+	if smt < 0 {
+		return "all:none"
+	}
+	return strconv.Itoa(smt)
+}
+
+// setPPCSMT brings the first 'value' threads of each core online and the
+// remaining threads offline
+func setPPCSMT(value string) error {
+	value = PPCSMTValue(value)
+	if valid, choices := IsValidSMT(value); !valid {
+		WarningLog("'%s' is not a valid value for smt (valid values: %s), skipping.", value, choices)
+		return nil
+	}
+	smt, _ := strconv.Atoi(value)
+	for _, threads := range ppcCoreThreads(threadsPerCore()) {
+		for i, cpu := range threads {
+			state := "0"
+			if i < smt {
+				state = "1"
+			}
+			onlineFile := path.Join(cpuDir, cpu, "online")
+			if _, err := os.Stat(onlineFile); err != nil {
+				// cpu0 can not be brought offline
+				continue
+			}
+			if err := os.WriteFile(onlineFile, []byte(state), 0644); err != nil {
+				WarningLog("failed to set '%s' in '%s': %v", state, onlineFile, err)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ppcCoreThreads returns the cpu threads of each core. On IBM Power the
+// threads of a core are numbered consecutively
+func ppcCoreThreads(tpc int) [][]string {
+	cores := [][]string{}
+	if tpc == 0 {
+		return cores
+	}
+	dirCont, err := os.ReadDir(cpuDir)
+	if err != nil {
+		return cores
+	}
+	maxCPU := -1
+	for _, entry := range dirCont {
+		if isCPU.MatchString(entry.Name()) && cpuNumber(entry.Name()) > maxCPU {
+			maxCPU = cpuNumber(entry.Name())
+		}
+	}
+	for first := 0; first <= maxCPU; first += tpc {
+		threads := []string{}
+		for cpu := first; cpu < first+tpc && cpu <= maxCPU; cpu++ {
+			threads = append(threads, "cpu"+strconv.Itoa(cpu))
+		}
+		cores = append(cores, threads)
+	}
+	return cores
+}
+
+// threadsPerCore returns the number of hardware threads per core on
+// IBM Power from the size of the 'ibm,ppc-interrupt-server#s' property of
+// the first cpu in the device tree (4 bytes per thread), the same way as
+// 'ppc64_cpu' does
+func threadsPerCore() int {
+	dirCont, err := os.ReadDir(ppcCPUTree)
+	if err != nil {
+		return 0
+	}
+	for _, entry := range dirCont {
+		if !strings.HasPrefix(entry.Name(), "PowerPC,") {
+			continue
+		}
+		finfo, err := os.Stat(path.Join(ppcCPUTree, entry.Name(), "ibm,ppc-interrupt-server#s"))
+		if err == nil && finfo.Size() >= 4 {
+			return int(finfo.Size() / 4)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package system
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSMTx86(t *testing.T) {
+	oldCPUDir := cpuDir
+	oldArch := smtArch
+	oldPendingFile := pendingRebootFile
+	oldGrubDefault := GrubDefault
+	defer func() {
+		cpuDir = oldCPUDir
+		smtArch = oldArch
+		pendingRebootFile = oldPendingFile
+		GrubDefault = oldGrubDefault
+	}()
+	cpuDir = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/cpu-smt")
+	smtArch = "amd64"
+	pendingRebootFile = "/tmp/saptune_test_smt/pending_reboot"
+	defer os.RemoveAll(path.Dir(pendingRebootFile))
+	GrubDefault = "/tmp/saptune_test_smt/grub"
+	controlFile := path.Join(cpuDir, "smt", "control")
+
+	if val := GetSMT(); val != "on" {
+		t.Error(val)
+	}
+	if valid, _ := IsValidSMT("off"); !valid {
+		t.Error("reports invalid, but shouldn't")
+	}
+	if valid, choices := IsValidSMT("4"); valid || choices != "on off" {
+		t.Errorf("reports valid, but shouldn't - '%s'", choices)
+	}
+	if err := SetSMT("off", false); err != nil {
+		t.Error(err)
+	}
+	if val := GetSMT(); val != "off" {
+		t.Error(val)
+	}
+	if err := SetSMT("on", true); err != nil {
+		t.Error(err)
+	}
+	if val := GetSMT(); val != "on" {
+		t.Error(val)
+	}
+
+	// SMT disabled by 'nosmt=force', boot option removed from grub
+	if err := os.WriteFile(controlFile, []byte("forceoff"), 0644); err != nil {
+		t.Error(err)
+	}
+	if val := GetSMT(); val != "off" {
+		t.Error(val)
+	}
+	if err := SetSMT("on", false); err != nil {
+		t.Error(err)
+	}
+	if reason := PendingRebootReason("smt", "on"); reason == "" {
+		t.Error("missing pending reboot for 'smt'")
+	}
+	if err := SetSMT("off", true); err != nil {
+		t.Error(err)
+	}
+	if reason := PendingRebootReason("smt", "on"); reason != "" {
+		t.Errorf("pending reboot for 'smt' not removed - '%s'", reason)
+	}
+
+	if err := os.WriteFile(controlFile, []byte("notsupported"), 0644); err != nil {
+		t.Error(err)
+	}
+	if val := GetSMT(); val != "all:none" {
+		t.Error(val)
+	}
+	if err := SetSMT("off", false); err != nil {
+		t.Error(err)
+	}
+	if err := os.WriteFile(controlFile, []byte("on"), 0644); err != nil {
+		t.Error(err)
+	}
+	cpuDir = "/unknownDir"
+	if val := GetSMT(); val != "all:none" {
+		t.Error(val)
+	}
+	if err := SetSMT("off", false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSMTppc64le(t *testing.T) {
+	oldCPUDir := cpuDir
+	oldArch := smtArch
+	oldCPUTree := ppcCPUTree
+	defer func() {
+		cpuDir = oldCPUDir
+		smtArch = oldArch
+		ppcCPUTree = oldCPUTree
+	}()
+	cpuDir = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/cpu-ppc")
+	ppcCPUTree = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/ppc-dt/cpus")
+	smtArch = "ppc64le"
+
+	if tpc := threadsPerCore(); tpc != 4 {
+		t.Errorf("expected 4 threads per core, actual %d", tpc)
+	}
+	if val := GetSMT(); val != "4" {
+		t.Error(val)
+	}
+	if val := PPCSMTValue("on"); val != "4" {
+		t.Error(val)
+	}
+	if val := PPCSMTValue("off"); val != "1" {
+		t.Error(val)
+	}
+	if valid, _ := IsValidSMT("8"); valid {
+		t.Error("reports valid, but shouldn't")
+	}
+	if err := SetSMT("2", false); err != nil {
+		t.Error(err)
+	}
+	if val := GetSMT(); val != "2" {
+		t.Error(val)
+	}
+	if !isCPUonline("cpu5") || isCPUonline("cpu6") {
+		t.Error("wrong online state of the threads of the second core")
+	}
+	// invalid value is skipped
+	if err := SetSMT("8", false); err != nil {
+		t.Error(err)
+	}
+	if val := GetSMT(); val != "2" {
+		t.Error(val)
+	}
+	// inconsistent SMT setting
+	if err := os.WriteFile(path.Join(cpuDir, "cpu6", "online"), []byte("1"), 0644); err != nil {
+		t.Error(err)
+	}
+	if val := GetSMT(); val != "all:none" {
+		t.Error(val)
+	}
+	if err := SetSMT("on", true); err != nil {
+		t.Error(err)
+	}
+	if val := GetSMT(); val != "4" {
+		t.Error(val)
+	}
+
+	ppcCPUTree = "/unknownDir"
+	if val := GetSMT(); val != "all:none" {
+		t.Error(val)
+	}
+}
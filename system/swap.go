@@ -0,0 +1,186 @@
+package system
+
+// handling of the swap devices
+// read from /proc/swaps
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procSwaps contains the active swap devices
+var procSwaps = "/proc/swaps"
+
+// sizeMBUnits contains the multiplier to MB of the size units used by the
+// swap size rules
+var sizeMBUnits = map[string]float64{
+	"":  1,
+	"k": 1.0 / 1024,
+	"m": 1,
+	"g": 1024,
+	"t": 1024 * 1024,
+}
+
+// SwapDevice describes an active swap device of /proc/swaps
+type SwapDevice struct {
+	Name     string
+	Type     string // partition, file or zram
+	SizeKB   uint64
+	Priority int
+}
+
+// GetSwapDevices returns the active swap devices from /proc/swaps
+func GetSwapDevices() ([]SwapDevice, error) {
+	devs := []SwapDevice{}
+	content, err := os.ReadFile(procSwaps)
+	if err != nil {
+		return devs, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		// Filename Type Size Used Priority
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[0] == "Filename" {
+			continue
+		}
+		size, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		prio, err := strconv.Atoi(fields[4])
+		if err != nil {
+			continue
+		}
+		swapType := fields[1]
+		if strings.HasPrefix(path.Base(fields[0]), "zram") {
+			swapType = "zram"
+		}
+		devs = append(devs, SwapDevice{Name: fields[0], Type: swapType, SizeKB: size, Priority: prio})
+	}
+	return devs, nil
+}
+
+// GetSwapSizeMB returns the size of all active swap devices in MB
+func GetSwapSizeMB(devs []SwapDevice) uint64 {
+	size := uint64(0)
+	for _, dev := range devs {
+		size = size + dev.SizeKB
+	}
+	return size / 1024
+}
+
+// GetSwapTypes returns the sorted list of the types of the active swap
+// devices without duplicates
+func GetSwapTypes(devs []SwapDevice) []string {
+	types := []string{}
+	for _, dev := range devs {
+		found := false
+		for _, t := range types {
+			if t == dev.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			types = append(types, dev.Type)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// GetSwapPriorities returns the sorted list of the priorities of the
+// active swap devices without duplicates
+func GetSwapPriorities(devs []SwapDevice) []int {
+	prios := []int{}
+	for _, dev := range devs {
+		found := false
+		for _, p := range prios {
+			if p == dev.Priority {
+				found = true
+				break
+			}
+		}
+		if !found {
+			prios = append(prios, dev.Priority)
+		}
+	}
+	sort.Ints(prios)
+	return prios
+}
+
+// IsValidSwapType checks, if the value is a supported swap device type
+func IsValidSwapType(value string) bool {
+	return value == "partition" || value == "file" || value == "zram" || value == "none"
+}
+
+// SwapSizeRequired returns the swap size in MB required by the size rule
+// for a system with 'memMB' MB of main memory.
+// A rule is a comma separated list of entries '<memory limit>:<swap size>'.
+// The first entry with a memory limit greater or equal to the main memory
+// of the system is used, '*' matches all memory sizes. An entry without
+// memory limit matches all memory sizes too.
+// The sizes can have the units K, M (default), G and T, the swap size can
+// be a percentage of the main memory too.
+// Example: '8G:200%, 32G:16G, *:32G'
+func SwapSizeRequired(rule string, memMB uint64) (uint64, error) {
+	for _, entry := range strings.Split(rule, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		limit := "*"
+		size := entry
+		if fields := strings.SplitN(entry, ":", 2); len(fields) == 2 {
+			limit = strings.TrimSpace(fields[0])
+			size = strings.TrimSpace(fields[1])
+		}
+		if limit != "*" {
+			limitMB, err := sizeToMB(limit)
+			if err != nil {
+				return 0, fmt.Errorf("wrong memory limit '%s' in swap size rule '%s'", limit, rule)
+			}
+			if float64(memMB) > limitMB {
+				continue
+			}
+		}
+		if strings.HasSuffix(size, "%") {
+			pct, err := strconv.ParseFloat(strings.TrimSuffix(size, "%"), 64)
+			if err != nil || pct < 0 {
+				return 0, fmt.Errorf("wrong swap size '%s' in swap size rule '%s'", size, rule)
+			}
+			return uint64(math.Round(float64(memMB) * pct / 100)), nil
+		}
+		sizeMB, err := sizeToMB(size)
+		if err != nil {
+			return 0, fmt.Errorf("wrong swap size '%s' in swap size rule '%s'", size, rule)
+		}
+		return uint64(math.Round(sizeMB)), nil
+	}
+	return 0, fmt.Errorf("no matching entry for %dMB main memory in swap size rule '%s'", memMB, rule)
+}
+
+// sizeToMB converts a size with the units K, M (default), G or T into MB
+func sizeToMB(value string) (float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	unit := ""
+	if len(value) > 0 {
+		if last := value[len(value)-1:]; last < "0" || last > "9" {
+			unit = last
+			value = value[:len(value)-1]
+		}
+	}
+	mult, ok := sizeMBUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("wrong size unit '%s'", unit)
+	}
+	num, err := strconv.ParseFloat(value, 64)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("wrong size '%s'", value)
+	}
+	return num * mult, nil
+}
@@ -0,0 +1,73 @@
+package system
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestGetSwapDevices(t *testing.T) {
+	oldProcSwaps := procSwaps
+	defer func() { procSwaps = oldProcSwaps }()
+	procSwaps = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/swaps")
+
+	devs, err := GetSwapDevices()
+	if err != nil {
+		t.Error(err)
+	}
+	exp := []SwapDevice{
+		{Name: "/dev/sda2", Type: "partition", SizeKB: 2097148, Priority: -2},
+		{Name: "/swapfile", Type: "file", SizeKB: 1048572, Priority: -3},
+		{Name: "/dev/zram0", Type: "zram", SizeKB: 4194300, Priority: 100},
+	}
+	if !reflect.DeepEqual(devs, exp) {
+		t.Errorf("expected: '%+v', got: '%+v'", exp, devs)
+	}
+	if size := GetSwapSizeMB(devs); size != 7167 {
+		t.Error(size)
+	}
+	if types := GetSwapTypes(devs); !reflect.DeepEqual(types, []string{"file", "partition", "zram"}) {
+		t.Error(types)
+	}
+	if prios := GetSwapPriorities(devs); !reflect.DeepEqual(prios, []int{-3, -2, 100}) {
+		t.Error(prios)
+	}
+
+	procSwaps = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/swaps_empty")
+	devs, err = GetSwapDevices()
+	if err != nil || len(devs) != 0 {
+		t.Error(devs, err)
+	}
+	procSwaps = "/tmp/saptune_test_not_existing/swaps"
+	if _, err := GetSwapDevices(); err == nil {
+		t.Error("missing file should be reported as error")
+	}
+}
+
+func TestSwapSizeRequired(t *testing.T) {
+	rule := "8G:200%, 32G:16G, *:32G"
+	tests := map[uint64]uint64{4096: 8192, 8192: 16384, 16384: 16384, 65536: 32768}
+	for mem, exp := range tests {
+		if req, err := SwapSizeRequired(rule, mem); err != nil || req != exp {
+			t.Errorf("%d - expected: '%d', got: '%d' (%v)", mem, exp, req, err)
+		}
+	}
+	if req, err := SwapSizeRequired("2048", 65536); err != nil || req != 2048 {
+		t.Error(req, err)
+	}
+	if req, err := SwapSizeRequired("512K", 65536); err != nil || req != 1 {
+		t.Error(req, err)
+	}
+	if req, err := SwapSizeRequired("50%", 1000); err != nil || req != 500 {
+		t.Error(req, err)
+	}
+	for _, wrong := range []string{"8X:2G", "8G:many", "8G:2G", "", "abc%"} {
+		if _, err := SwapSizeRequired(wrong, 65536); err == nil {
+			t.Errorf("rule '%s' should be reported as error", wrong)
+		}
+	}
+	if !IsValidSwapType("zram") || IsValidSwapType("disk") {
+		t.Error("wrong check of swap type")
+	}
+}
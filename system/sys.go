@@ -18,11 +18,22 @@ func GetSysString(parameter string) (string, error) {
 
 // getKeyStringFromPath generalizes the extraction of a string from path
 func getKeyStringFromPath(basePath string, parameter string, logFrom string) (string, error) {
+	return cachedFact(basePath, parameter, func() (string, error) {
+		return readKeyStringFromPath(basePath, parameter, logFrom)
+	})
+}
+
+// readKeyStringFromPath reads the string value of a key from path
+func readKeyStringFromPath(basePath string, parameter string, logFrom string) (string, error) {
+	return readKeyStringFromFile(path.Join(basePath, strings.Replace(parameter, ".", "/", -1)), parameter, logFrom)
+}
+
+// readKeyStringFromFile reads the string value of a key from its file
+func readKeyStringFromFile(srcFile string, parameter string, logFrom string) (string, error) {
 	// Seams that os.ReadFile reads only 512 Bytes, if it can not detect the
 	// filesize (which is the case for /proc/sys files, returns always 0)
 	// This might not enough for sysctl parameter like
 	// 'net.ipv4.ip_local_reserved_ports'
-	srcFile := path.Join(basePath, strings.Replace(parameter, ".", "/", -1))
 	file, err := os.Open(srcFile)
 	if err != nil {
 		WarningLog("failed to read %v string key '%s': %v", logFrom, parameter, err)
@@ -44,13 +55,16 @@ func getKeyStringFromPath(basePath string, parameter string, logFrom string) (st
 // GetSysChoice read a /sys/ key that comes with current value and alternative
 // choices, return the current choice or empty string.
 func GetSysChoice(parameter string) (string, error) {
-	val, err := os.ReadFile(path.Join("/sys", strings.Replace(parameter, ".", "/", -1)))
+	val, err := cachedFact("syschoice", parameter, func() (string, error) {
+		val, err := os.ReadFile(path.Join("/sys", strings.Replace(parameter, ".", "/", -1)))
+		return string(val), err
+	})
 	if err != nil {
 		WarningLog("failed to read sys key of choices '%s': %v", parameter, err)
 		return "PNA", err
 	}
 	// Split up the choices
-	allChoices := consecutiveSpaces.Split(string(val), -1)
+	allChoices := consecutiveSpaces.Split(val, -1)
 	for _, choice := range allChoices {
 		if len(choice) > 2 && choice[0] == '[' && choice[len(choice)-1] == ']' {
 			return choice[1 : len(choice)-1], nil
@@ -59,6 +73,25 @@ func GetSysChoice(parameter string) (string, error) {
 	return "", nil
 }
 
+// GetSysChoices read a /sys/ key that comes with current value and
+// alternative choices, return all available choices (without the brackets
+// of the current choice).
+func GetSysChoices(parameter string) ([]string, error) {
+	val, err := cachedFact("syschoice", parameter, func() (string, error) {
+		val, err := os.ReadFile(path.Join("/sys", strings.Replace(parameter, ".", "/", -1)))
+		return string(val), err
+	})
+	if err != nil {
+		WarningLog("failed to read sys key of choices '%s': %v", parameter, err)
+		return []string{}, err
+	}
+	choices := []string{}
+	for _, choice := range strings.Fields(val) {
+		choices = append(choices, strings.TrimSuffix(strings.TrimPrefix(choice, "["), "]"))
+	}
+	return choices, nil
+}
+
 // GetSysInt read an integer /sys/ key.
 func GetSysInt(parameter string) (int, error) {
 	value, err := GetSysString(parameter)
@@ -75,6 +108,7 @@ func SetSysString(parameter, value string) error {
 		WarningLog("value is '%s', so sys key '%s' is/was not supported by os, skipping.", value, parameter)
 		return nil
 	}
+	forgetSysFacts(parameter)
 	err := os.WriteFile(path.Join("/sys", strings.Replace(parameter, ".", "/", -1)), []byte(value), 0644)
 	if os.IsNotExist(err) {
 		WarningLog("sys key '%s' is not supported by os, skipping.", parameter)
@@ -97,6 +131,7 @@ func TestSysString(parameter, value string) error {
 		WarningLog("failed to get sys key '%s': %v", parameter, err)
 		return err
 	}
+	forgetSysFacts(parameter)
 	if err = os.WriteFile(path.Join("/sys", strings.Replace(parameter, ".", "/", -1)), []byte(value), 0644); err == nil {
 		// set key back to previous value, because this was only a test
 		err = os.WriteFile(path.Join("/sys", strings.Replace(parameter, ".", "/", -1)), []byte(save), 0644)
@@ -127,6 +162,16 @@ func GetSysSearchParam(syskey string) (string, string) {
 		}
 	}
 
+	// additional queue and I/O scheduler tunables
+	if name, tbdev := GetBlockTunable(syskey); name != "" {
+		return "sys:" + strings.Replace(BlockTunableKey(name, tbdev), "/", ".", -1), "sys"
+	}
+	for _, name := range BlockTunableNames() {
+		if bdev != "" && strings.HasSuffix(syskey, ".queue."+strings.Replace(BlockTunables[name].File, "/", ".", -1)) {
+			return name + "_" + bdev, "block"
+		}
+	}
+
 	switch {
 	case syskey == "THP":
 		searchParam = "sys:" + SysKernelTHPEnabled
@@ -134,6 +179,12 @@ func GetSysSearchParam(syskey string) (string, string) {
 	case syskey == "sys:"+SysKernelTHPEnabled:
 		searchParam = "THP"
 		sect = "vm"
+	case SysVMKeys[syskey] != "":
+		searchParam = "sys:" + SysVMKeys[syskey]
+		sect = "sys"
+	case vmKeyOfSysKey(syskey) != "":
+		searchParam = vmKeyOfSysKey(syskey)
+		sect = "vm"
 	case syskey == "KSM":
 		searchParam = "sys:" + SysKSMRun
 		sect = "sys"
@@ -168,6 +219,17 @@ func GetSysSearchParam(syskey string) (string, string) {
 	return searchParam, sect
 }
 
+// vmKeyOfSysKey returns the key of the [vm] section related to the given
+// /sys/ key (e.g. 'sys:kernel.mm.transparent_hugepage.defrag')
+func vmKeyOfSysKey(syskey string) string {
+	for key, sysKey := range SysVMKeys {
+		if syskey == "sys:"+sysKey {
+			return key
+		}
+	}
+	return ""
+}
+
 // GetNrTags returns the value from /sys/block/<bdev>/mq/0/nr_tags and the
 // related scheduler
 func GetNrTags(key string) (int, string, string) {
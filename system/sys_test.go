@@ -1,6 +1,8 @@
 package system
 
 import (
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -234,3 +236,35 @@ func TestGetNrTags(t *testing.T) {
 	}
 	t.Logf("nrtags is '%+v', elev is '%+v', bdev is '%+v'\n", nrtags, elev, bdev)
 }
+
+func TestGetSysChoices(t *testing.T) {
+	if _, err := os.Stat("/sys/kernel/mm/transparent_hugepage/enabled"); err != nil {
+		t.Skip("transparent huge pages not supported by the kernel")
+	}
+	choices, err := GetSysChoices("kernel/mm/transparent_hugepage/enabled")
+	if err != nil {
+		t.Error(err)
+	}
+	current, _ := GetSysChoice("kernel/mm/transparent_hugepage/enabled")
+	found := false
+	for _, choice := range choices {
+		if strings.ContainsAny(choice, "[]") {
+			t.Errorf("choice '%s' contains brackets", choice)
+		}
+		if choice == current {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("current choice '%s' not in '%+v'", current, choices)
+	}
+	if choices, err := GetSysChoices("kernel/not_avail"); err == nil || len(choices) != 0 {
+		t.Errorf("unexpected choices '%+v'", choices)
+	}
+	if param, sect := GetSysSearchParam("THP_DEFRAG"); param != "sys:"+SysKernelTHPDefrag || sect != "sys" {
+		t.Errorf("wrong search param '%s', '%s'", param, sect)
+	}
+	if param, sect := GetSysSearchParam("sys:" + SysKSMSleepMillisecs); param != "KSM_SLEEP_MILLISECS" || sect != "vm" {
+		t.Errorf("wrong search param '%s', '%s'", param, sect)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -19,8 +20,25 @@ const (
 	SysctlDirtyBackgroundRatio      = "vm.dirty_background_ratio"
 	SysKernelTHPEnabled             = "kernel.mm.transparent_hugepage.enabled"
 	SysKSMRun                       = "kernel.mm.ksm.run"
+	SysKernelTHPDefrag              = "kernel.mm.transparent_hugepage.defrag"
+	SysKernelTHPKhugepagedDefrag    = "kernel.mm.transparent_hugepage.khugepaged.defrag"
+	SysKernelTHPMaxPtesNone         = "kernel.mm.transparent_hugepage.khugepaged.max_ptes_none"
+	SysKernelTHPUseZeroPage         = "kernel.mm.transparent_hugepage.use_zero_page"
+	SysKSMPagesToScan               = "kernel.mm.ksm.pages_to_scan"
+	SysKSMSleepMillisecs            = "kernel.mm.ksm.sleep_millisecs"
 )
 
+// SysVMKeys maps the additional keys of the [vm] section to the related
+// /sys/ keys
+var SysVMKeys = map[string]string{
+	"THP_DEFRAG":                   SysKernelTHPDefrag,
+	"THP_KHUGEPAGED_DEFRAG":        SysKernelTHPKhugepagedDefrag,
+	"THP_KHUGEPAGED_MAX_PTES_NONE": SysKernelTHPMaxPtesNone,
+	"THP_USE_ZERO_PAGE":            SysKernelTHPUseZeroPage,
+	"KSM_PAGES_TO_SCAN":            SysKSMPagesToScan,
+	"KSM_SLEEP_MILLISECS":          SysKSMSleepMillisecs,
+}
+
 // sysctlDirs contains all locations sysctl is searching for parameter settings.
 var sysctlDirs = sysctlSearchLocations()
 
@@ -28,6 +46,14 @@ var sysctlParms = sysctlDefined{}
 var sysctlWarn = map[string]string{}
 var sysctlExcludeList = map[string]string{}
 
+// sysctlGlobExcludes contains the keys excluded from the wildcard keys
+// ('-net.ipv4.conf.lo.rp_filter') per sysctl config file
+var sysctlGlobExcludes = map[string][]string{}
+
+// sysctlProcDir is the location of the sysctl parameters, used for the
+// expansion of the wildcard keys
+var sysctlProcDir = "/proc/sys"
+
 // sysctlEntry contains the 'sysctl config filename - value' pair
 type sysctlEntry struct {
 	File  string
@@ -56,11 +82,13 @@ func sysctlSearchLocations() []string {
 
 // ChkForSysctlDoubles checks if the given sysctl parameter is additional set
 // in a sysctl system configuration file
+// Wildcard keys like 'net.ipv4.conf.*.rp_filter' are taken into account
+// as long as the parameter is not excluded in the same file
 func ChkForSysctlDoubles(param string) string {
 	info := ""
-	if len(sysctlParms[param]) > 0 {
+	if doubles := sysctlDoubles(param); len(doubles) > 0 {
 		// found double
-		for _, entries := range sysctlParms[param] {
+		for _, entries := range doubles {
 			if _, ok := sysctlExcludeList[entries.File]; ok {
 				continue
 			}
@@ -78,6 +106,28 @@ func ChkForSysctlDoubles(param string) string {
 	return info
 }
 
+// sysctlDoubles returns the sysctl config file entries of the parameter.
+// First the entries of the parameter itself, followed by the entries of the
+// matching wildcard keys sorted by the wildcard key
+func sysctlDoubles(param string) sysctlConf {
+	doubles := append(sysctlConf{}, sysctlParms[param]...)
+	globs := []string{}
+	for key := range sysctlParms {
+		if IsSysctlGlob(key) && MatchSysctlKey(key, param) {
+			globs = append(globs, key)
+		}
+	}
+	sort.Strings(globs)
+	for _, glob := range globs {
+		for _, entry := range sysctlParms[glob] {
+			if !matchSysctlKeys(sysctlGlobExcludes[entry.File], param) {
+				doubles = append(doubles, entry)
+			}
+		}
+	}
+	return doubles
+}
+
 // printDoubleWarning checks, if we need to print a sysctl double warning
 func printDoubleWarning(param, info string) {
 	if _, ok := sysctlWarn[param]; !ok {
@@ -95,11 +145,19 @@ func CollectGlobalSysctls(excludeDirs []string) {
 	getSysctlFilelist(excludeDirs, sysctlExcludeList, true)
 	getSysctlFilelist(sysctlDirs, fileList, false)
 	for _, sfile := range fileList {
-		sconf, err := parseSysctlConfFile(sfile)
+		if isSysctlDropIn(sfile) {
+			// the saptune sysctl drop-in file contains the
+			// values applied by saptune, so no double
+			continue
+		}
+		sconf, excludes, err := parseSysctlConfFile(sfile)
 		if err != nil {
 			// skip file
 			continue
 		}
+		if len(excludes) > 0 {
+			sysctlGlobExcludes[sfile] = excludes
+		}
 		for param := range sconf {
 			sysctlcnf := append(sysctlParms[param], sconf[param])
 			sysctlParms[param] = sysctlcnf
@@ -165,34 +223,127 @@ func getAllSysctlFiles(dirs []string) []string {
 }
 
 // parseSysctlConfFile parses a special sysctl config file and returns
-// the key-value pairs of the contained sysctl parameters
-func parseSysctlConfFile(file string) (map[string]sysctlEntry, error) {
+// the key-value pairs of the contained sysctl parameters and the keys
+// excluded from the wildcard keys
+func parseSysctlConfFile(file string) (map[string]sysctlEntry, []string, error) {
 	entries := make(map[string]sysctlEntry)
+	excludes := []string{}
 	content, err := ReadConfigFile(file, false)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	for _, line := range strings.Split(string(content), "\n") {
 		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "#") {
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
 			// Line is a comment
 			continue
 		}
 		if eqChar := strings.IndexRune(line, '='); eqChar != -1 {
 			// Line is a key-value pair
-			key := strings.TrimSpace(line[0:eqChar])
+			// a leading '-' only means 'ignore failures'
+			key := SysctlDotKey(strings.TrimPrefix(strings.TrimSpace(line[0:eqChar]), "-"))
 			entries[key] = sysctlEntry{
 				File:  file,
 				Value: strings.Trim(strings.TrimSpace(line[eqChar+1:]), `"`),
 			}
+		} else if strings.HasPrefix(line, "-") {
+			// Line excludes a key from the wildcard keys
+			excludes = append(excludes, SysctlDotKey(strings.TrimSpace(line[1:])))
+		}
+	}
+	return entries, excludes, nil
+}
+
+// SysctlDotKey returns the sysctl key in the '.' separated notation.
+// If the first separator of the key is a '/', '/' and '.' are interchanged
+// (see sysctl.d(5)), so 'net/ipv4/conf/eth0.100/rp_filter' results in
+// 'net.ipv4.conf.eth0/100.rp_filter'
+func SysctlDotKey(key string) string {
+	if sep := strings.IndexAny(key, "./"); sep == -1 || key[sep] == '.' {
+		return key
+	}
+	return swapSysctlSeparators(key)
+}
+
+// sysctlKeyPath returns the path of the sysctl key relative to /proc/sys.
+// A '/' inside of the '.' separated notation is part of a key component
+// (see sysctl.d(5)), so 'net.ipv4.conf.eth0/100.rp_filter' results in
+// 'net/ipv4/conf/eth0.100/rp_filter'
+func sysctlKeyPath(key string) string {
+	return swapSysctlSeparators(SysctlDotKey(key))
+}
+
+// swapSysctlSeparators interchanges '/' and '.' in the sysctl key
+func swapSysctlSeparators(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/':
+			return '.'
+		case '.':
+			return '/'
+		}
+		return r
+	}, key)
+}
+
+// IsSysctlGlob checks, if the sysctl key contains wildcards
+func IsSysctlGlob(key string) bool {
+	return strings.ContainsAny(key, "*?[")
+}
+
+// MatchSysctlKey checks, if the sysctl key matches the wildcard key.
+// The wildcards do not match the separator '.', so
+// 'net.ipv4.conf.*.rp_filter' matches 'net.ipv4.conf.eth0.rp_filter' and
+// the VLAN interface 'net.ipv4.conf.eth0/100.rp_filter', but not
+// 'net.ipv4.conf.eth0.100.rp_filter'
+func MatchSysctlKey(glob, key string) bool {
+	match, err := path.Match(sysctlKeyPath(glob), sysctlKeyPath(key))
+	return err == nil && match
+}
+
+// matchSysctlKeys checks, if the sysctl key matches one of the (wildcard)
+// keys
+func matchSysctlKeys(globs []string, key string) bool {
+	for _, glob := range globs {
+		if MatchSysctlKey(glob, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandSysctlGlob returns the sorted list of the sysctl keys available in
+// the system, which match the wildcard key, but none of the excluded
+// (wildcard) keys.
+// A '.' inside of a key component (e.g. the VLAN interface 'eth0.100') is
+// written as '/' in the returned keys ('net.ipv4.conf.eth0/100.rp_filter')
+func ExpandSysctlGlob(glob string, excludes []string) []string {
+	keys := []string{}
+	files, err := filepath.Glob(path.Join(sysctlProcDir, sysctlKeyPath(glob)))
+	if err != nil {
+		WarningLog("wrong wildcard sysctl key '%s' - %v", glob, err)
+		return keys
+	}
+	for _, file := range files {
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			continue
+		}
+		key := swapSysctlSeparators(strings.TrimPrefix(file, sysctlProcDir+"/"))
+		if matchSysctlKeys(excludes, key) {
+			continue
 		}
+		keys = append(keys, key)
 	}
-	return entries, nil
+	return keys
 }
 
 // GetSysctlString read a sysctl key and return the string value.
+// A '/' inside of the key is part of a key component (e.g. the VLAN
+// interface in 'net.ipv4.conf.eth0/100.rp_filter')
 func GetSysctlString(parameter string) (string, error) {
-	return getKeyStringFromPath("/proc/sys", parameter, "sysctl")
+	return cachedFact("/proc/sys", parameter, func() (string, error) {
+		return readKeyStringFromFile(path.Join("/proc/sys", sysctlKeyPath(parameter)), parameter, "sysctl")
+	})
 }
 
 // GetSysctlInt read an integer sysctl key.
@@ -238,7 +389,8 @@ func SetSysctlString(parameter, value string) error {
 	if value == "" {
 		value = "\n"
 	}
-	err := os.WriteFile(path.Join("/proc/sys", strings.Replace(parameter, ".", "/", -1)), []byte(value), 0644)
+	forgetFact("/proc/sys", parameter)
+	err := os.WriteFile(path.Join("/proc/sys", sysctlKeyPath(parameter)), []byte(value), 0644)
 	if os.IsNotExist(err) {
 		WarningLog("sysctl key '%s' is not supported by os, skipping.", parameter)
 	} else if err != nil {
@@ -1,6 +1,11 @@
 package system
 
-import "testing"
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
 
 func TestReadSysctl(t *testing.T) {
 	if value, err := GetSysctlInt("vm.max_map_count"); err != nil {
@@ -148,3 +153,127 @@ func TestIsValidSysctlLocations(t *testing.T) {
 		t.Errorf("file '%s' reported as valid sysctl file location, but is invalid", file)
 	}
 }
+
+func TestSysctlDotKey(t *testing.T) {
+	tests := map[string]string{
+		"net.ipv4.conf.eth0.rp_filter":     "net.ipv4.conf.eth0.rp_filter",
+		"net/ipv4/conf/eth0/rp_filter":     "net.ipv4.conf.eth0.rp_filter",
+		"net/ipv4/conf/eth0.100/rp_filter": "net.ipv4.conf.eth0/100.rp_filter",
+		"net.ipv4.conf.eth0/100.rp_filter": "net.ipv4.conf.eth0/100.rp_filter",
+		"kernel":                           "kernel",
+	}
+	for key, exp := range tests {
+		if val := SysctlDotKey(key); val != exp {
+			t.Errorf("'%s' - expected: '%s', got: '%s'", key, exp, val)
+		}
+	}
+}
+
+func TestSysctlKeyPath(t *testing.T) {
+	tests := map[string]string{
+		"vm.swappiness":                    "vm/swappiness",
+		"net.ipv4.conf.eth0/100.rp_filter": "net/ipv4/conf/eth0.100/rp_filter",
+		"net/ipv4/conf/eth0.100/rp_filter": "net/ipv4/conf/eth0.100/rp_filter",
+	}
+	for key, exp := range tests {
+		if val := sysctlKeyPath(key); val != exp {
+			t.Errorf("'%s' - expected: '%s', got: '%s'", key, exp, val)
+		}
+	}
+	// both notations read the same parameter
+	if _, err := os.Stat("/proc/sys/net/ipv4/conf/lo/rp_filter"); err == nil {
+		val1, err1 := GetSysctlString("net.ipv4.conf.lo.rp_filter")
+		val2, err2 := GetSysctlString("net/ipv4/conf/lo/rp_filter")
+		if err1 != nil || err2 != nil || val1 != val2 {
+			t.Errorf("'%s', '%v' - '%s', '%v'", val1, err1, val2, err2)
+		}
+	}
+}
+
+func TestMatchSysctlKey(t *testing.T) {
+	if !IsSysctlGlob("net.ipv4.conf.*.rp_filter") || IsSysctlGlob("net.ipv4.conf.all.rp_filter") {
+		t.Error("wrong detection of wildcard keys")
+	}
+	tests := []struct {
+		glob, key string
+		match     bool
+	}{
+		{"net.ipv4.conf.*.rp_filter", "net.ipv4.conf.eth0.rp_filter", true},
+		{"net.ipv4.conf.*.rp_filter", "net.ipv4.conf.eth0.arp_ignore", false},
+		{"net.ipv4.conf.*.rp_filter", "net.ipv4.conf.eth0.100.rp_filter", false},
+		{"net.ipv4.conf.*.rp_filter", "net.ipv4.conf.eth0/100.rp_filter", true},
+		{"net.ipv4.conf.eth0/*.rp_filter", "net/ipv4/conf/eth0.100/rp_filter", true},
+		{"net.ipv4.conf.eth?.rp_filter", "net.ipv4.conf.eth1.rp_filter", true},
+		{"net.ipv4.conf.[^l]*.rp_filter", "net.ipv4.conf.lo.rp_filter", false},
+		{"net.ipv4.conf.lo.rp_filter", "net.ipv4.conf.lo.rp_filter", true},
+		{"net.ipv4.conf.[.rp_filter", "net.ipv4.conf.lo.rp_filter", false},
+	}
+	for _, tst := range tests {
+		if match := MatchSysctlKey(tst.glob, tst.key); match != tst.match {
+			t.Errorf("'%s' - '%s': expected '%v', got '%v'", tst.glob, tst.key, tst.match, match)
+		}
+	}
+}
+
+func TestExpandSysctlGlob(t *testing.T) {
+	oldProcDir := sysctlProcDir
+	defer func() { sysctlProcDir = oldProcDir }()
+	sysctlProcDir = path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/procsys")
+
+	// the '.' of the VLAN interface 'eth0.100' is written as '/'
+	exp := []string{"net.ipv4.conf.all.rp_filter", "net.ipv4.conf.default.rp_filter", "net.ipv4.conf.eth0.rp_filter", "net.ipv4.conf.eth0/100.rp_filter", "net.ipv4.conf.eth1.rp_filter", "net.ipv4.conf.lo.rp_filter"}
+	if keys := ExpandSysctlGlob("net.ipv4.conf.*.rp_filter", []string{}); !reflect.DeepEqual(keys, exp) {
+		t.Errorf("expected: '%+v', got: '%+v'", exp, keys)
+	}
+	exp = []string{"net.ipv4.conf.eth0.arp_ignore", "net.ipv4.conf.eth1.arp_ignore"}
+	if keys := ExpandSysctlGlob("net.ipv4.conf.*.arp_ignore", []string{"net.ipv4.conf.lo.arp_ignore", "net.ipv4.conf.*l*.arp_ignore", "net/ipv4/conf/eth0.100/arp_ignore"}); !reflect.DeepEqual(keys, exp) {
+		t.Errorf("expected: '%+v', got: '%+v'", exp, keys)
+	}
+	if keys := ExpandSysctlGlob("net.ipv4.conf.*", []string{}); len(keys) != 0 {
+		t.Errorf("directories should not be expanded, got: '%+v'", keys)
+	}
+	if keys := ExpandSysctlGlob("net.ipv4.conf.[.rp_filter", []string{}); len(keys) != 0 {
+		t.Errorf("expected empty list, got: '%+v'", keys)
+	}
+}
+
+func TestSysctlGlobDoubles(t *testing.T) {
+	oldParms := sysctlParms
+	oldExcludes := sysctlGlobExcludes
+	defer func() {
+		sysctlParms = oldParms
+		sysctlGlobExcludes = oldExcludes
+	}()
+	sysctlParms = sysctlDefined{}
+	sysctlGlobExcludes = map[string][]string{}
+
+	sfile := path.Join(os.Getenv("GOPATH"), "/src/github.com/SUSE/saptune/testdata/sysctl.d/50-saptune-glob.conf")
+	entries, excludes, err := parseSysctlConfFile(sfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(excludes, []string{"net.ipv4.conf.all.rp_filter", "net.ipv4.conf.lo.rp_filter"}) {
+		t.Errorf("got: '%+v'", excludes)
+	}
+	for _, key := range []string{"net.ipv4.conf.*.rp_filter", "net.ipv4.conf.eth0.arp_ignore", "vm.swappiness"} {
+		if _, ok := entries[key]; !ok {
+			t.Errorf("missing key '%s' in '%+v'", key, entries)
+		}
+	}
+	for key, entry := range entries {
+		sysctlParms[key] = append(sysctlParms[key], entry)
+	}
+	sysctlGlobExcludes[sfile] = excludes
+	sysctlParms["net.ipv4.conf.eth0.rp_filter"] = sysctlConf{sysctlEntry{File: "/etc/sysctl.conf", Value: "1"}}
+
+	exp := "sysctl config file /etc/sysctl.conf(1), " + sfile + "(2)"
+	if info := ChkForSysctlDoubles("net.ipv4.conf.eth0.rp_filter"); info != exp {
+		t.Errorf("expected: '%s', got: '%s'", exp, info)
+	}
+	if info := ChkForSysctlDoubles("net.ipv4.conf.lo.rp_filter"); info != "" {
+		t.Errorf("excluded key should not be reported, got: '%s'", info)
+	}
+	if info := ChkForSysctlDoubles("net.ipv4.conf.eth1.arp_ignore"); info != "" {
+		t.Errorf("got: '%s'", info)
+	}
+}
@@ -0,0 +1,292 @@
+package system
+
+// handling of the saptune sysctl drop-in file
+// contains the sysctl values applied by saptune, so that systemd-sysctl
+// (e.g. during the hotplug of a network interface) sets the same values
+// as saptune and does not override them.
+// The file records the start values of the sysctl keys too. If the file in
+// /etc/sysctl.d survives a reboot, systemd-sysctl sets the tuned values
+// during boot and saptune uses the recorded start values instead of the
+// already tuned system values
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// sysctlDropInFiles contains the supported locations of the saptune
+// sysctl drop-in file. The name ensures, that the file is read as last one
+// by systemd-sysctl
+var sysctlDropInFiles = map[string]string{
+	"etc": "/etc/sysctl.d/zz-saptune.conf",
+	"run": "/run/sysctl.d/zz-saptune.conf",
+}
+
+// sysctlDropInLocation is the location of the saptune sysctl drop-in file
+// in use ('etc' or 'run'). 'no', if the drop-in file is disabled
+var sysctlDropInLocation = "no"
+
+// sysctlDropInHeader is written at the beginning of the drop-in file
+var sysctlDropInHeader = `# This file is generated by saptune and contains the sysctl values of the
+# applied Notes. Do not edit, the changes will be lost during the next
+# 'saptune note apply', 'saptune note revert' or 'saptune note refresh'.
+`
+
+// sysctlDropInStartPrefix marks the comment lines of the drop-in file, which
+// contain the start values of the sysctl keys
+var sysctlDropInStartPrefix = "# start: "
+
+// IsValidSysctlDropIn checks, if the value is a supported location of the
+// saptune sysctl drop-in file ('etc' or 'run') or 'no'
+func IsValidSysctlDropIn(location string) bool {
+	_, ok := sysctlDropInFiles[location]
+	return ok || location == "no"
+}
+
+// SetSysctlDropIn sets the location of the saptune sysctl drop-in file.
+// 'etc' - /etc/sysctl.d, 'run' - /run/sysctl.d, 'no' disables the drop-in
+// file
+func SetSysctlDropIn(location string) {
+	if !IsValidSysctlDropIn(location) {
+		WarningLog("wrong value '%s' for the sysctl drop-in file location, the drop-in file is disabled", location)
+		location = "no"
+	}
+	sysctlDropInLocation = location
+}
+
+// SysctlDropInLocation returns the location of the saptune sysctl drop-in
+// file in use ('etc' or 'run') or 'no', if the drop-in file is disabled
+func SysctlDropInLocation() string {
+	return sysctlDropInLocation
+}
+
+// SysctlDropIn returns the saptune sysctl drop-in file in use or an empty
+// string, if the drop-in file is disabled
+func SysctlDropIn() string {
+	return sysctlDropInFiles[sysctlDropInLocation]
+}
+
+// isSysctlDropIn checks, if the file is one of the saptune sysctl drop-in
+// files
+func isSysctlDropIn(file string) bool {
+	for _, dropIn := range sysctlDropInFiles {
+		if file == dropIn {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSysctlDropInValue writes the sysctl value and the start value of the
+// key to the saptune sysctl drop-in file. An already recorded start value
+// is kept. Nothing to do, if the drop-in file is disabled
+func SetSysctlDropInValue(key, value, start string) error {
+	dropIn := SysctlDropIn()
+	if dropIn == "" {
+		return nil
+	}
+	values, starts, err := readSysctlDropIn(dropIn)
+	if err != nil {
+		return err
+	}
+	// multiple fields are separated by one blank
+	values[key] = strings.Join(strings.Fields(value), " ")
+	if _, ok := starts[key]; !ok {
+		starts[key] = strings.Join(strings.Fields(start), " ")
+	}
+	return writeSysctlDropIn(dropIn, values, starts)
+}
+
+// SysctlDropInStartValue returns the start value of the sysctl key recorded
+// in the saptune sysctl drop-in file. Used, if the drop-in file survived a
+// reboot and systemd-sysctl has already set the tuned value during boot
+func SysctlDropInStartValue(key string) (string, bool) {
+	dropIn := SysctlDropIn()
+	if dropIn == "" {
+		return "", false
+	}
+	_, starts, err := readSysctlDropIn(dropIn)
+	if err != nil {
+		return "", false
+	}
+	start, ok := starts[SysctlDotKey(key)]
+	return start, ok
+}
+
+// ValidSysctlDropInStartValue returns the start value of the sysctl key
+// recorded in the saptune sysctl drop-in file, if the recorded start value
+// is still valid for the current value of the key.
+// The recorded start value is stale, if the current value is not the value
+// of the drop-in file (systemd-sysctl has not set the tuned value during
+// boot) or if a sysctl config file defining the key (e.g. /etc/sysctl.conf)
+// was changed after the drop-in file was written. In the second case the
+// configured value of the newest of these files is the start value
+func ValidSysctlDropInStartValue(key, current string) (string, bool) {
+	dropIn := SysctlDropIn()
+	if dropIn == "" {
+		return "", false
+	}
+	key = SysctlDotKey(key)
+	values, starts, err := readSysctlDropIn(dropIn)
+	if err != nil {
+		return "", false
+	}
+	start, ok := starts[key]
+	if !ok || strings.Join(strings.Fields(current), " ") != values[key] {
+		return "", false
+	}
+	dropInInfo, err := os.Stat(dropIn)
+	if err != nil {
+		return "", false
+	}
+	newest := dropInInfo.ModTime()
+	fileList := make(map[string]string)
+	getSysctlFilelist(sysctlDirs, fileList, false)
+	for sfile := range fileList {
+		if isSysctlDropIn(sfile) {
+			continue
+		}
+		info, err := os.Stat(sfile)
+		if err != nil || !info.ModTime().After(newest) {
+			continue
+		}
+		sconf, _, err := parseSysctlConfFile(sfile)
+		if err != nil {
+			continue
+		}
+		if entry, ok := sconf[key]; ok {
+			start = strings.Join(strings.Fields(entry.Value), " ")
+			newest = info.ModTime()
+		}
+	}
+	return start, true
+}
+
+// RemoveSysctlDropInValues removes the sysctl keys from the saptune sysctl
+// drop-in file. The drop-in file is removed, if no key is left.
+// Nothing to do, if the drop-in file is disabled
+func RemoveSysctlDropInValues(keys ...string) error {
+	dropIn := SysctlDropIn()
+	if dropIn == "" {
+		return nil
+	}
+	values, starts, err := readSysctlDropIn(dropIn)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		delete(values, key)
+		delete(starts, key)
+	}
+	return writeSysctlDropIn(dropIn, values, starts)
+}
+
+// RemoveSysctlDropIn removes the saptune sysctl drop-in file in use.
+// Nothing to do, if the drop-in file is disabled
+func RemoveSysctlDropIn() error {
+	dropIn := SysctlDropIn()
+	if dropIn == "" {
+		return nil
+	}
+	return writeSysctlDropIn(dropIn, map[string]string{}, map[string]string{})
+}
+
+// CleanUpSysctlDropIn removes the saptune sysctl drop-in files of all
+// locations
+func CleanUpSysctlDropIn() {
+	for _, dropIn := range sysctlDropInFiles {
+		if err := os.Remove(dropIn); err != nil && !os.IsNotExist(err) {
+			WarningLog("failed to remove the saptune sysctl drop-in file '%s' - %v", dropIn, err)
+		}
+	}
+}
+
+// MoveSysctlDropIn moves the content of the saptune sysctl drop-in file
+// from the old to the new location ('etc', 'run' or 'no'). For 'no' the
+// drop-in file is removed
+func MoveSysctlDropIn(oldLocation, newLocation string) error {
+	oldFile := sysctlDropInFiles[oldLocation]
+	newFile := sysctlDropInFiles[newLocation]
+	if oldFile == "" || oldFile == newFile {
+		return nil
+	}
+	values, starts, err := readSysctlDropIn(oldFile)
+	if err != nil {
+		return err
+	}
+	if newFile != "" {
+		if err := writeSysctlDropIn(newFile, values, starts); err != nil {
+			return err
+		}
+	}
+	return writeSysctlDropIn(oldFile, map[string]string{}, map[string]string{})
+}
+
+// readSysctlDropIn reads the key-value pairs and the recorded start values
+// of the saptune sysctl drop-in file. A missing file is an empty drop-in file
+func readSysctlDropIn(file string) (map[string]string, map[string]string, error) {
+	values := make(map[string]string)
+	starts := make(map[string]string)
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return values, starts, nil
+	}
+	entries, _, err := parseSysctlConfFile(file)
+	if err != nil {
+		return values, starts, err
+	}
+	for key, entry := range entries {
+		values[key] = entry.Value
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return values, starts, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, sysctlDropInStartPrefix) {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimPrefix(line, sysctlDropInStartPrefix), "=", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		starts[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
+	}
+	return values, starts, nil
+}
+
+// writeSysctlDropIn writes the key-value pairs sorted by key and the start
+// values of these keys to the saptune sysctl drop-in file. Without
+// key-value pairs the file is removed
+func writeSysctlDropIn(file string, values, starts map[string]string) error {
+	if len(values) == 0 {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	content := sysctlDropInHeader
+	for _, key := range keys {
+		if start, ok := starts[key]; ok {
+			content = content + fmt.Sprintf("%s%s = %s\n", sysctlDropInStartPrefix, key, start)
+		}
+		content = content + fmt.Sprintf("%s = %s\n", key, values[key])
+	}
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return err
+	}
+	// write to a temporary file and rename it, so systemd-sysctl never
+	// reads a partly written file
+	tmpFile := file + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, file)
+}
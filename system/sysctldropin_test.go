@@ -0,0 +1,184 @@
+package system
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestSysctlDropIn(t *testing.T) {
+	tstDir, err := os.MkdirTemp("", "saptune_sysctl_dropin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDropInFiles := sysctlDropInFiles
+	oldLocation := sysctlDropInLocation
+	defer func() {
+		sysctlDropInFiles = oldDropInFiles
+		sysctlDropInLocation = oldLocation
+		os.RemoveAll(tstDir)
+	}()
+	etcFile := path.Join(tstDir, "etc/sysctl.d/zz-saptune.conf")
+	runFile := path.Join(tstDir, "run/sysctl.d/zz-saptune.conf")
+	sysctlDropInFiles = map[string]string{"etc": etcFile, "run": runFile}
+
+	if !IsValidSysctlDropIn("no") || !IsValidSysctlDropIn("etc") || !IsValidSysctlDropIn("run") || IsValidSysctlDropIn("yes") {
+		t.Error("wrong check of the drop-in file locations")
+	}
+	SetSysctlDropIn("yes")
+	if SysctlDropInLocation() != "no" || SysctlDropIn() != "" {
+		t.Errorf("wrong location should disable the drop-in file, got '%s'", SysctlDropIn())
+	}
+	// disabled, nothing to do
+	if err := SetSysctlDropInValue("vm.swappiness", "10", "60"); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(etcFile); !os.IsNotExist(err) {
+		t.Error("drop-in file written, but disabled")
+	}
+
+	SetSysctlDropIn("etc")
+	if SysctlDropIn() != etcFile {
+		t.Errorf("expected '%s', got '%s'", etcFile, SysctlDropIn())
+	}
+	if err := SetSysctlDropInValue("vm.swappiness", "10", "60"); err != nil {
+		t.Error(err)
+	}
+	if err := SetSysctlDropInValue("net.ipv4.ip_local_port_range", "1024\t65535", "32768\t60999"); err != nil {
+		t.Error(err)
+	}
+	if err := SetSysctlDropInValue("vm.swappiness", "20", "10"); err != nil {
+		t.Error(err)
+	}
+	// the first recorded start value is kept
+	exp := sysctlDropInHeader + "# start: net.ipv4.ip_local_port_range = 32768 60999\nnet.ipv4.ip_local_port_range = 1024 65535\n# start: vm.swappiness = 60\nvm.swappiness = 20\n"
+	if content, _ := os.ReadFile(etcFile); string(content) != exp {
+		t.Errorf("expected: '%s', got: '%s'", exp, string(content))
+	}
+	if start, ok := SysctlDropInStartValue("vm/swappiness"); !ok || start != "60" {
+		t.Errorf("expected start value '60', got '%s' (%v)", start, ok)
+	}
+	if _, ok := SysctlDropInStartValue("vm.dirty_ratio"); ok {
+		t.Error("start value found for a key not in the drop-in file")
+	}
+	if !isSysctlDropIn(etcFile) || isSysctlDropIn("/etc/sysctl.conf") {
+		t.Error("wrong detection of the drop-in file")
+	}
+
+	// move to /run
+	if err := MoveSysctlDropIn("etc", "run"); err != nil {
+		t.Error(err)
+	}
+	SetSysctlDropIn("run")
+	if _, err := os.Stat(etcFile); !os.IsNotExist(err) {
+		t.Error("old drop-in file not removed")
+	}
+	if content, _ := os.ReadFile(runFile); string(content) != exp {
+		t.Errorf("expected: '%s', got: '%s'", exp, string(content))
+	}
+
+	if err := RemoveSysctlDropInValues("vm.swappiness", "vm.not_available"); err != nil {
+		t.Error(err)
+	}
+	exp = sysctlDropInHeader + "# start: net.ipv4.ip_local_port_range = 32768 60999\nnet.ipv4.ip_local_port_range = 1024 65535\n"
+	if content, _ := os.ReadFile(runFile); string(content) != exp {
+		t.Errorf("expected: '%s', got: '%s'", exp, string(content))
+	}
+	// last key removed, drop-in file removed
+	if err := RemoveSysctlDropInValues("net.ipv4.ip_local_port_range"); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(runFile); !os.IsNotExist(err) {
+		t.Error("empty drop-in file not removed")
+	}
+
+	// disable
+	if err := SetSysctlDropInValue("vm.swappiness", "10", "60"); err != nil {
+		t.Error(err)
+	}
+	if err := MoveSysctlDropIn("run", "no"); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(runFile); !os.IsNotExist(err) {
+		t.Error("drop-in file not removed")
+	}
+	_ = SetSysctlDropInValue("vm.swappiness", "10", "60")
+	CleanUpSysctlDropIn()
+	if _, err := os.Stat(runFile); !os.IsNotExist(err) {
+		t.Error("drop-in file not removed")
+	}
+}
+
+func TestValidSysctlDropInStartValue(t *testing.T) {
+	tstDir, err := os.MkdirTemp("", "saptune_sysctl_dropin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDropInFiles := sysctlDropInFiles
+	oldLocation := sysctlDropInLocation
+	oldSysctlDirs := sysctlDirs
+	defer func() {
+		sysctlDropInFiles = oldDropInFiles
+		sysctlDropInLocation = oldLocation
+		sysctlDirs = oldSysctlDirs
+		os.RemoveAll(tstDir)
+	}()
+	etcFile := path.Join(tstDir, "etc/sysctl.d/zz-saptune.conf")
+	sysctlConf := path.Join(tstDir, "etc/sysctl.conf")
+	sysctlDropInFiles = map[string]string{"etc": etcFile}
+	sysctlDirs = []string{sysctlConf, path.Join(tstDir, "etc/sysctl.d/")}
+
+	SetSysctlDropIn("no")
+	if _, ok := ValidSysctlDropInStartValue("vm.swappiness", "10"); ok {
+		t.Error("start value found, but drop-in file disabled")
+	}
+	SetSysctlDropIn("etc")
+	if err := SetSysctlDropInValue("vm.swappiness", "10", "60"); err != nil {
+		t.Error(err)
+	}
+	if start, ok := ValidSysctlDropInStartValue("vm/swappiness", "10"); !ok || start != "60" {
+		t.Errorf("expected start value '60', got '%s' (%v)", start, ok)
+	}
+	// the value of the drop-in file is not set, stale start value
+	if start, ok := ValidSysctlDropInStartValue("vm.swappiness", "60"); ok {
+		t.Errorf("stale start value '%s' used", start)
+	}
+	if _, ok := ValidSysctlDropInStartValue("vm.dirty_ratio", "10"); ok {
+		t.Error("start value found for a key not in the drop-in file")
+	}
+
+	// /etc/sysctl.conf changed before the drop-in file was written
+	if err := os.WriteFile(sysctlConf, []byte("vm.swappiness = 30\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dropInTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(etcFile, dropInTime, dropInTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(sysctlConf, dropInTime.Add(-time.Hour), dropInTime.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if start, ok := ValidSysctlDropInStartValue("vm.swappiness", "10"); !ok || start != "60" {
+		t.Errorf("expected start value '60', got '%s' (%v)", start, ok)
+	}
+	// /etc/sysctl.conf changed after the drop-in file was written, the
+	// configured value is the start value
+	if err := os.Chtimes(sysctlConf, dropInTime.Add(time.Minute), dropInTime.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if start, ok := ValidSysctlDropInStartValue("vm.swappiness", "10"); !ok || start != "30" {
+		t.Errorf("expected start value '30', got '%s' (%v)", start, ok)
+	}
+
+	if err := RemoveSysctlDropIn(); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(etcFile); !os.IsNotExist(err) {
+		t.Error("drop-in file not removed")
+	}
+	SetSysctlDropIn("no")
+	if err := RemoveSysctlDropIn(); err != nil {
+		t.Error(err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unicode"
@@ -28,6 +29,10 @@ const SaptuneSavedStateDir = "/run/saptune/saved_state"
 // separated from the note state file directory
 const SaptuneParameterStateDir = "/run/saptune/parameter"
 
+// SaptunePristineStateDir defines the persistent directory where to store
+// the pristine baseline of the parameters, which survives a reboot
+const SaptunePristineStateDir = "/var/lib/saptune/pristine"
+
 // RPMBldVers is the version of the RPM build process (suse_version)
 // defaults to '15'
 // needs to be a string as replacement with -X during build does not work
@@ -38,6 +43,9 @@ var RPMBldVers = "15"
 // map to hold the current available systemd services
 var services map[string]string
 
+// servicesMutex protects the lazy initialisation of 'services'
+var servicesMutex sync.Mutex
+
 // stdOutOrg contains the origin stdout for resetting, if needed
 var stdOutOrg = os.Stdout
 
@@ -0,0 +1,172 @@
+package system
+
+// handling of the time synchronisation
+// state of chronyd, systemd-timesyncd or ntpd read by chronyc and timedatectl
+
+import (
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// timeDaemons are the supported time synchronisation daemons in the order
+// they are checked
+var timeDaemons = []string{"chronyd", "systemd-timesyncd", "ntpd"}
+
+var chronycCmd = "/usr/bin/chronyc"
+var timedatectlCmd = "/usr/bin/timedatectl"
+
+// timeDaemonActive is used to mock the state of the time daemons in the tests
+var timeDaemonActive = func(daemon string) bool {
+	active, _ := SystemctlIsRunning(daemon)
+	return active
+}
+
+// timesyncCmdOutput is used to mock the output of chronyc and timedatectl
+// in the tests
+var timesyncCmdOutput = func(cmd string, args ...string) (string, error) {
+	out, err := exec.Command(cmd, args...).Output()
+	DebugLog("timesyncCmdOutput - %s %s : '%+v'", cmd, strings.Join(args, " "), err)
+	return string(out), err
+}
+
+// isTimeOffset matches a time offset like '100ms', '0.5 s' or '50us'
+var isTimeOffset = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(ns|us|µs|ms|s)?$`)
+
+// offsetUnits contains the multiplier to milliseconds
+var offsetUnits = map[string]float64{
+	"ns": 1e-6,
+	"us": 1e-3,
+	"µs": 1e-3,
+	"":   1,
+	"ms": 1,
+	"s":  1e3,
+}
+
+// ActiveTimeDaemon returns the name of the active time synchronisation
+// daemon or 'none'
+func ActiveTimeDaemon() string {
+	for _, daemon := range timeDaemons {
+		if timeDaemonActive(daemon) {
+			return daemon
+		}
+	}
+	return "none"
+}
+
+// TimeSynchronized returns 'yes', if the system clock is synchronised,
+// otherwise 'no'. 'NA', if the state is not available
+func TimeSynchronized() string {
+	if ActiveTimeDaemon() == "chronyd" {
+		tracking, err := chronyTracking()
+		if err != nil {
+			InfoLog("time synchronisation state not available - %v", err)
+			return "NA"
+		}
+		// Leap status
+		if tracking[13] == "Normal" || tracking[13] == "Insert second" || tracking[13] == "Delete second" {
+			return "yes"
+		}
+		return "no"
+	}
+	out, err := timesyncCmdOutput(timedatectlCmd, "show", "--property=NTPSynchronized", "--value")
+	if err != nil {
+		InfoLog("time synchronisation state not available - %v", err)
+		return "NA"
+	}
+	switch strings.TrimSpace(out) {
+	case "yes":
+		return "yes"
+	case "no":
+		return "no"
+	}
+	return "NA"
+}
+
+// TimeOffsetMS returns the current offset of the system clock to the time
+// source in milliseconds (absolute value). Only available for chronyd
+func TimeOffsetMS() (float64, error) {
+	if daemon := ActiveTimeDaemon(); daemon != "chronyd" {
+		return 0, fmt.Errorf("time offset not available for time daemon '%s'", daemon)
+	}
+	tracking, err := chronyTracking()
+	if err != nil {
+		return 0, err
+	}
+	// System time in seconds
+	offset, err := strconv.ParseFloat(tracking[4], 64)
+	if err != nil {
+		return 0, fmt.Errorf("wrong time offset '%s' reported by chronyc", tracking[4])
+	}
+	return math.Abs(offset) * 1e3, nil
+}
+
+// TimeSources returns the number of configured time sources of the active
+// time daemon
+func TimeSources() (int, error) {
+	switch daemon := ActiveTimeDaemon(); daemon {
+	case "chronyd":
+		out, err := timesyncCmdOutput(chronycCmd, "-n", "-c", "sources")
+		if err != nil {
+			return 0, err
+		}
+		sources := 0
+		for _, line := range strings.Split(out, "\n") {
+			if strings.TrimSpace(line) != "" {
+				sources++
+			}
+		}
+		return sources, nil
+	case "systemd-timesyncd":
+		out, err := timesyncCmdOutput(timedatectlCmd, "show-timesync", "--property=SystemNTPServers", "--property=LinkNTPServers", "--value")
+		if err != nil {
+			return 0, err
+		}
+		sources := len(strings.Fields(out))
+		if sources == 0 {
+			// no configured servers, systemd-timesyncd uses
+			// the fallback servers
+			out, err = timesyncCmdOutput(timedatectlCmd, "show-timesync", "--property=FallbackNTPServers", "--value")
+			if err != nil {
+				return 0, err
+			}
+			sources = len(strings.Fields(out))
+		}
+		return sources, nil
+	default:
+		return 0, fmt.Errorf("number of time sources not available for time daemon '%s'", daemon)
+	}
+}
+
+// TimeOffsetToMS converts a time offset threshold like '100ms', '0.5s' or
+// '50us' into milliseconds. Default unit is ms
+func TimeOffsetToMS(value string) (float64, error) {
+	fields := isTimeOffset.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if fields == nil {
+		return 0, fmt.Errorf("wrong time offset '%s', use ns, us, ms or s", value)
+	}
+	num, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("wrong time offset '%s' - %v", value, err)
+	}
+	return num * offsetUnits[fields[2]], nil
+}
+
+// chronyTracking returns the fields of 'chronyc -c tracking'
+func chronyTracking() ([]string, error) {
+	out, err := timesyncCmdOutput(chronycCmd, "-n", "-c", "tracking")
+	if err != nil {
+		return nil, err
+	}
+	// Reference ID, Address, Stratum, Ref time, System time, Last offset,
+	// RMS offset, Frequency, Residual freq, Skew, Root delay,
+	// Root dispersion, Update interval, Leap status
+	fields := strings.Split(strings.TrimSpace(out), ",")
+	if len(fields) < 14 {
+		return nil, fmt.Errorf("unexpected output of 'chronyc -c tracking': '%s'", strings.TrimSpace(out))
+	}
+	return fields, nil
+}
@@ -0,0 +1,106 @@
+package system
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var chronyTrackingOut = "C0A80101,192.168.1.1,3,1760000000.123456789,-0.000123456,0.000010,0.000020,-12.345,0.001,0.020,0.001234,0.000567,64.2,Normal\n"
+var chronySourcesOut = "^,*,192.168.1.1,2,6,377,10,-0.000012,-0.000010,0.000123\n^,+,192.168.1.2,2,6,377,11,0.000021,0.000023,0.000130\n^,-,192.168.1.3,3,6,377,12,0.000301,0.000310,0.000250\n"
+
+func mockTimesync(daemon string, outputs map[string]string) func() {
+	oldActive := timeDaemonActive
+	oldOutput := timesyncCmdOutput
+	timeDaemonActive = func(d string) bool {
+		return d == daemon
+	}
+	timesyncCmdOutput = func(cmd string, args ...string) (string, error) {
+		if out, ok := outputs[strings.Join(args, " ")]; ok {
+			return out, nil
+		}
+		return "", fmt.Errorf("command '%s %s' failed", cmd, strings.Join(args, " "))
+	}
+	return func() {
+		timeDaemonActive = oldActive
+		timesyncCmdOutput = oldOutput
+	}
+}
+
+func TestTimesyncChrony(t *testing.T) {
+	restore := mockTimesync("chronyd", map[string]string{"-n -c tracking": chronyTrackingOut, "-n -c sources": chronySourcesOut})
+	defer restore()
+
+	if daemon := ActiveTimeDaemon(); daemon != "chronyd" {
+		t.Error(daemon)
+	}
+	if synced := TimeSynchronized(); synced != "yes" {
+		t.Error(synced)
+	}
+	if offset, err := TimeOffsetMS(); err != nil || fmt.Sprintf("%.3f", offset) != "0.123" {
+		t.Error(offset, err)
+	}
+	if sources, err := TimeSources(); err != nil || sources != 3 {
+		t.Error(sources, err)
+	}
+
+	restore2 := mockTimesync("chronyd", map[string]string{"-n -c tracking": strings.Replace(chronyTrackingOut, "Normal", "Not synchronised", 1)})
+	defer restore2()
+	if synced := TimeSynchronized(); synced != "no" {
+		t.Error(synced)
+	}
+	restore3 := mockTimesync("chronyd", map[string]string{"-n -c tracking": "506F6F6C,,0\n"})
+	defer restore3()
+	if synced := TimeSynchronized(); synced != "NA" {
+		t.Error(synced)
+	}
+	if _, err := TimeOffsetMS(); err == nil {
+		t.Error("wrong output of chronyc should be reported as error")
+	}
+}
+
+func TestTimesyncTimesyncd(t *testing.T) {
+	restore := mockTimesync("systemd-timesyncd", map[string]string{
+		"show --property=NTPSynchronized --value":                                     "no\n",
+		"show-timesync --property=SystemNTPServers --property=LinkNTPServers --value": "\n\n",
+		"show-timesync --property=FallbackNTPServers --value":                         "0.suse.pool.ntp.org 1.suse.pool.ntp.org\n",
+	})
+	defer restore()
+
+	if daemon := ActiveTimeDaemon(); daemon != "systemd-timesyncd" {
+		t.Error(daemon)
+	}
+	if synced := TimeSynchronized(); synced != "no" {
+		t.Error(synced)
+	}
+	if _, err := TimeOffsetMS(); err == nil {
+		t.Error("time offset should not be available for systemd-timesyncd")
+	}
+	if sources, err := TimeSources(); err != nil || sources != 2 {
+		t.Error(sources, err)
+	}
+
+	restore2 := mockTimesync("", map[string]string{})
+	defer restore2()
+	if daemon := ActiveTimeDaemon(); daemon != "none" {
+		t.Error(daemon)
+	}
+	if synced := TimeSynchronized(); synced != "NA" {
+		t.Error(synced)
+	}
+	if _, err := TimeSources(); err == nil {
+		t.Error("number of time sources should not be available without time daemon")
+	}
+}
+
+func TestTimeOffsetToMS(t *testing.T) {
+	tests := map[string]float64{"100ms": 100, "0.5s": 500, "50us": 0.05, "50 µs": 0.05, "20": 20, "2000000ns": 2}
+	for val, exp := range tests {
+		if ms, err := TimeOffsetToMS(val); err != nil || ms != exp {
+			t.Errorf("'%s' - expected: '%f', got: '%f' (%v)", val, exp, ms, err)
+		}
+	}
+	if _, err := TimeOffsetToMS("1min"); err == nil {
+		t.Error("'1min' should be reported as error")
+	}
+}
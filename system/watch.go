@@ -0,0 +1,71 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+)
+
+// SaptuneWatchStatusFile contains the machine-readable result of the last
+// drift detection run of 'saptune service watch'
+const SaptuneWatchStatusFile = "/run/saptune/watch/status.json"
+
+// SaptuneWatchTimer is the systemd timer triggering the drift detection
+const SaptuneWatchTimer = "saptune-watch.timer"
+
+// watchTimerDropIn is the drop-in file used to set the interval of the
+// saptune-watch.timer
+var watchTimerDropIn = "/etc/systemd/system/saptune-watch.timer.d/saptune-interval.conf"
+
+// ChkWatchInterval checks, if the given interval is a valid drift detection
+// interval in seconds (minimum 60)
+func ChkWatchInterval(interval string) error {
+	ival, err := strconv.Atoi(interval)
+	if err != nil || ival < 60 {
+		return fmt.Errorf("wrong value '%s' for the drift detection interval. Only numbers of seconds greater than or equal to 60 are supported", interval)
+	}
+	return nil
+}
+
+// SetWatchInterval writes the drop-in file for the saptune-watch.timer
+// with the given interval (in seconds), if the content differs from the
+// existing drop-in. Returns true, if the drop-in file was changed.
+// The caller is responsible for the systemd daemon-reload
+func SetWatchInterval(interval string) (bool, error) {
+	if err := ChkWatchInterval(interval); err != nil {
+		return false, err
+	}
+	content := fmt.Sprintf("# created by saptune, do not edit\n# use 'saptune configure WATCH_INTERVAL' to change the interval\n[Timer]\nOnUnitActiveSec=\nOnUnitActiveSec=%ss\n", interval)
+	if cont, err := os.ReadFile(watchTimerDropIn); err == nil && string(cont) == content {
+		return false, nil
+	}
+	if err := os.MkdirAll(path.Dir(watchTimerDropIn), 0755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(watchTimerDropIn, []byte(content), 0644); err != nil {
+		return false, err
+	}
+	InfoLog("drift detection interval of '%s' set to '%s' seconds", SaptuneWatchTimer, interval)
+	return true, nil
+}
+
+// SyncWatchInterval syncs the drop-in file for the saptune-watch.timer with
+// the interval from the saptune configuration (WATCH_INTERVAL), as the
+// configuration file can be changed without 'saptune configure'.
+// An empty interval removes the drop-in file, so the default interval of
+// the timer unit is used. Returns true, if the drop-in file was changed.
+// The caller is responsible for the systemd daemon-reload
+func SyncWatchInterval(interval string) (bool, error) {
+	if interval != "" {
+		return SetWatchInterval(interval)
+	}
+	if err := os.Remove(watchTimerDropIn); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	InfoLog("drift detection interval of '%s' reset to the default of the timer unit", SaptuneWatchTimer)
+	return true, nil
+}
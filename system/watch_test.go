@@ -0,0 +1,69 @@
+package system
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestChkWatchInterval(t *testing.T) {
+	for _, val := range []string{"60", "900", "3600"} {
+		if err := ChkWatchInterval(val); err != nil {
+			t.Errorf("'%s' should be a valid interval - %v", val, err)
+		}
+	}
+	for _, val := range []string{"", "59", "-1", "15min"} {
+		if err := ChkWatchInterval(val); err == nil {
+			t.Errorf("'%s' should not be a valid interval", val)
+		}
+	}
+}
+
+func TestSetWatchInterval(t *testing.T) {
+	oldDropIn := watchTimerDropIn
+	defer func() { watchTimerDropIn = oldDropIn }()
+	watchTimerDropIn = "/tmp/saptune-watch.timer.d/saptune-interval.conf"
+	defer os.RemoveAll(path.Dir(watchTimerDropIn))
+
+	changed, err := SetWatchInterval("600")
+	if err != nil || !changed {
+		t.Errorf("expected changed drop-in, got: '%v', '%v'", changed, err)
+	}
+	cont, _ := os.ReadFile(watchTimerDropIn)
+	exp := "# created by saptune, do not edit\n# use 'saptune configure WATCH_INTERVAL' to change the interval\n[Timer]\nOnUnitActiveSec=\nOnUnitActiveSec=600s\n"
+	if string(cont) != exp {
+		t.Errorf("got: '%s', expected: '%s'", string(cont), exp)
+	}
+	changed, err = SetWatchInterval("600")
+	if err != nil || changed {
+		t.Errorf("expected unchanged drop-in, got: '%v', '%v'", changed, err)
+	}
+	if _, err = SetWatchInterval("10"); err == nil {
+		t.Error("expected an error for an invalid interval")
+	}
+
+	// sync with the saptune configuration
+	changed, err = SyncWatchInterval("600")
+	if err != nil || changed {
+		t.Errorf("expected unchanged drop-in, got: '%v', '%v'", changed, err)
+	}
+	changed, err = SyncWatchInterval("300")
+	if err != nil || !changed {
+		t.Errorf("expected changed drop-in, got: '%v', '%v'", changed, err)
+	}
+	if _, err = SyncWatchInterval("10"); err == nil {
+		t.Error("expected an error for an invalid interval")
+	}
+	// empty interval, default of the timer unit
+	changed, err = SyncWatchInterval("")
+	if err != nil || !changed {
+		t.Errorf("expected removed drop-in, got: '%v', '%v'", changed, err)
+	}
+	if _, err := os.Stat(watchTimerDropIn); !os.IsNotExist(err) {
+		t.Error("drop-in file not removed")
+	}
+	changed, err = SyncWatchInterval("")
+	if err != nil || changed {
+		t.Errorf("expected unchanged drop-in, got: '%v', '%v'", changed, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/SUSE/saptune/system"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 // Operator definitions
@@ -20,7 +21,15 @@ const (
 type Operator string
 
 // RegexKeyOperatorValue breaks up a line into key, operator, value.
-var RegexKeyOperatorValue = regexp.MustCompile(`([\w.+_-]+)\s*([<=>]+)\s*["']*(.*?)["']*$`)
+var RegexKeyOperatorValue = regexp.MustCompile(`([\w./+_-]+)\s*([<=>]+)\s*["']*(.*?)["']*$`)
+
+// isSysctlGlob breaks up a line of the [sysctl] section with a wildcard key
+// like 'net.ipv4.conf.*.rp_filter = 1' into key, operator, value.
+var isSysctlGlob = regexp.MustCompile(`^([\w./+_-]*[*?\[][\w./+_*?\[\]^-]*)\s*([<=>]+)\s*["']*(.*?)["']*$`)
+
+// isSysctlExclude matches a line of the [sysctl] section, which excludes a
+// key from the wildcard keys like '-net.ipv4.conf.lo.rp_filter'
+var isSysctlExclude = regexp.MustCompile(`^-\s*([\w./+_*?\[\]^-]+)$`)
 
 // regKey gives the parameter part of the line from the note definition file
 var regKey = regexp.MustCompile(`(.*)\s*[<=>]+\s*["']*.*?["']*$`)
@@ -38,6 +47,10 @@ var excludeDirs = make([]string, 0)
 // counter to control the [sysctl] section
 var sysctlCnt = 0
 
+// parseMutex serialises the parsing of the configuration files, as the
+// parser uses the package level counters and block device list
+var parseMutex sync.Mutex
+
 // INIEntry contains a single key-value pair in INI file.
 type INIEntry struct {
 	Section  string
@@ -79,13 +92,19 @@ func splitLineIntoKOV(curSection, line string) []string {
 		kov = []string{"", "", "", line}
 	} else {
 		// check for unsupported '/' in the parameter name
+		// sysctl keys support the '/' notation of sysctl.d(5), e.g.
+		// 'net.ipv4.conf.eth0/100.rp_filter' for the VLAN interface
+		// 'eth0.100'
 		param := regKey.FindStringSubmatch(line)
-		if len(param) > 0 && strings.Contains(param[1], "/") {
+		if len(param) > 0 && strings.Contains(param[1], "/") && curSection != "sysctl" {
 			system.WarningLog("line '%v' contains an unsupported parameter syntax. Skipping line", line)
 			return nil
 		}
 		kov = RegexKeyOperatorValue.FindStringSubmatch(line)
-		if curSection == "grub" || curSection == "sys" || curSection == "service" {
+		if curSection == "sysctl" && len(kov) > 1 {
+			kov[1] = system.SysctlDotKey(kov[1])
+		}
+		if curSection == "grub" || curSection == "sys" || curSection == "service" || curSection == "swap" || curSection == "timesync" || curSection == "mitigations" {
 			kov = splitSectLine(curSection, line, kov)
 		}
 	}
@@ -153,6 +172,8 @@ func ParseINIFile(fileName string, autoCreate bool) (*INIFile, error) {
 
 // ParseINI parse the content of the configuration file
 func ParseINI(input string) *INIFile {
+	parseMutex.Lock()
+	defer parseMutex.Unlock()
 	ret := &INIFile{
 		AllValues: make([]INIEntry, 0, 64),
 		KeyValue:  make(map[string]map[string]INIEntry),
@@ -165,6 +186,8 @@ func ParseINI(input string) *INIFile {
 	currentSection := ""
 	currentEntriesArray := make([]INIEntry, 0, 8)
 	currentEntriesMap := make(map[string]INIEntry)
+	sysctlGlobs := []INIEntry{}
+	sysctlExcludes := []string{}
 	for _, line := range strings.Split(input, "\n") {
 		line = strings.TrimSpace(line)
 		if len(line) == 0 {
@@ -191,12 +214,15 @@ func ParseINI(input string) *INIFile {
 		if line[0] == '[' {
 			// Save previous section, if valid
 			if currentSection != "" && !skipSection {
+				currentEntriesArray, currentEntriesMap = writeSysctlGlobData(sysctlGlobs, sysctlExcludes, currentEntriesArray, currentEntriesMap)
 				ret.KeyValue[currentSection] = currentEntriesMap
 				ret.AllValues = append(ret.AllValues, currentEntriesArray...)
 			}
 
 			// Start a new section
 			chkOk := true
+			sysctlGlobs = []INIEntry{}
+			sysctlExcludes = []string{}
 			if skipSection {
 				skipSection = false
 			}
@@ -228,6 +254,11 @@ func ParseINI(input string) *INIFile {
 				// check of section tags needed
 				chkOk, bdevs = chkSecTags(sectionFields, bdevs)
 			}
+			if chkOk && sectionFields[0] == "block" && len(bdevs) != 0 && !isTagAvail("blkstack", sectionFields) {
+				// no layer of the block device stacks requested,
+				// use the default selection
+				bdevs = system.SelectBlockDeviceLayer(bdevs, "")
+			}
 			if chkOk {
 				currentSection = sectionFields[0]
 				currentEntriesArray = make([]INIEntry, 0, 8)
@@ -239,6 +270,12 @@ func ParseINI(input string) *INIFile {
 			continue
 		}
 
+		// collect the wildcard keys and the excluded keys of the
+		// sysctl section. They are expanded at the end of the section
+		next, sysctlGlobs, sysctlExcludes = collectSysctlGlobs(currentSection, line, sysctlGlobs, sysctlExcludes)
+		if next {
+			continue
+		}
 		// Break apart a line into key, operator, value.
 		kov := splitLineIntoKOV(currentSection, line)
 		if kov == nil {
@@ -273,15 +310,18 @@ func ParseINI(input string) *INIFile {
 	if reminder != "" {
 		// Save previous section
 		if currentSection != "" {
+			currentEntriesArray, currentEntriesMap = writeSysctlGlobData(sysctlGlobs, sysctlExcludes, currentEntriesArray, currentEntriesMap)
 			ret.KeyValue[currentSection] = currentEntriesMap
 			ret.AllValues = append(ret.AllValues, currentEntriesArray...)
 		}
 		// write the reminder section data
 		currentEntriesArray, currentEntriesMap, currentSection = writeReminderSectionData(reminder)
+		sysctlGlobs = []INIEntry{}
 	}
 
 	// Save last section
 	if currentSection != "" {
+		currentEntriesArray, currentEntriesMap = writeSysctlGlobData(sysctlGlobs, sysctlExcludes, currentEntriesArray, currentEntriesMap)
 		ret.KeyValue[currentSection] = currentEntriesMap
 		ret.AllValues = append(ret.AllValues, currentEntriesArray...)
 	}
@@ -295,7 +335,7 @@ func blkInfoNeeded(sectFields []string) bool {
 	if sectFields[0] == "block" {
 		found = true
 	} else {
-		tags := []string{"blkvendor", "blkmodel", "blkpat"}
+		tags := []string{"blkvendor", "blkmodel", "blkpat", "blkwwn", "blkserial", "blkbyid", "blkstack"}
 		for _, tag := range tags {
 			if isTagAvail(tag, sectFields) {
 				found = true
@@ -310,11 +350,18 @@ func blkInfoNeeded(sectFields []string) bool {
 func blockDevCollect(sectFields, bDev []string, bCnt int) (int, []string) {
 	// collect the block device infos only ONCE
 	if bCnt == 0 && blkInfoNeeded(sectFields) {
-		system.NoticeLog("block device related section settings detected: Traversing all block devices can take a considerable amount of time.")
 		bCnt = bCnt + 1
 		// blockDev all valid block devices of the
 		// system regardless of any section tag
-		bDev = system.CollectBlockDeviceInfo()
+		// use the stored block device information refreshed by the
+		// change markers of the udev rule, if available
+		bdevConf, err := system.GetBlockDeviceInfo()
+		if err == nil && len(bdevConf.AllBlockDevs) != 0 {
+			bDev = bdevConf.AllBlockDevs
+		} else {
+			system.NoticeLog("block device related section settings detected: Traversing all block devices can take a considerable amount of time.")
+			bDev = system.CollectBlockDeviceInfo()
+		}
 	}
 	return bCnt, bDev
 }
@@ -433,6 +480,52 @@ func writeBlockSectionData(curSec string, bdevs, kov []string, curEntriesArray [
 	return next, curEntriesArray, curEntriesMap
 }
 
+// collectSysctlGlobs collects the wildcard keys and the keys excluded from
+// the wildcard keys of the [sysctl] section
+func collectSysctlGlobs(curSec, line string, globs []INIEntry, excludes []string) (bool, []INIEntry, []string) {
+	if curSec != "sysctl" {
+		return false, globs, excludes
+	}
+	if excl := isSysctlExclude.FindStringSubmatch(line); len(excl) > 0 {
+		return true, globs, append(excludes, system.SysctlDotKey(excl[1]))
+	}
+	kov := isSysctlGlob.FindStringSubmatch(line)
+	if len(kov) == 0 {
+		return false, globs, excludes
+	}
+	entry := INIEntry{
+		Section:  curSec,
+		Key:      system.SysctlDotKey(kov[1]),
+		Operator: Operator(kov[2]),
+		Value:    strings.Replace(kov[3], " ", "\t", -1),
+	}
+	return true, append(globs, entry), excludes
+}
+
+// writeSysctlGlobData expands the wildcard keys of the [sysctl] section to
+// the matching sysctl parameters available in the system (e.g. one entry
+// per network interface for 'net.ipv4.conf.*.rp_filter').
+// Excluded keys are skipped. Keys explicitly defined in the section or by a
+// previous wildcard key take precedence
+func writeSysctlGlobData(globs []INIEntry, excludes []string, curEntriesArray []INIEntry, curEntriesMap map[string]INIEntry) ([]INIEntry, map[string]INIEntry) {
+	for _, glob := range globs {
+		keys := system.ExpandSysctlGlob(glob.Key, excludes)
+		if len(keys) == 0 {
+			system.InfoLog("no sysctl parameter available for the wildcard key '%s', skipping", glob.Key)
+		}
+		for _, key := range keys {
+			if _, ok := curEntriesMap[key]; ok {
+				continue
+			}
+			entry := glob
+			entry.Key = key
+			curEntriesArray = append(curEntriesArray, entry)
+			curEntriesMap[entry.Key] = entry
+		}
+	}
+	return curEntriesArray, curEntriesMap
+}
+
 // writeMultiValueData handles tunables with more than one value
 func writeMultiValueData(curSec string, kov []string, curEntriesArray []INIEntry, curEntriesMap map[string]INIEntry) ([]INIEntry, map[string]INIEntry) {
 	value := strings.Replace(kov[3], " ", "\t", -1)
@@ -396,3 +396,52 @@ func TestGetSysctlExcludes(t *testing.T) {
 	t.Log(excludeDirs)
 	excludeDirs = excludeDirsOrg
 }
+
+func TestParseINISysctlGlobs(t *testing.T) {
+	iniGlob := `
+[sysctl]
+net.ipv4.conf.*.rp_filter = 2
+-net.ipv4.conf.lo.rp_filter
+net.ipv4.conf.default.rp_filter = 1
+net.ipv4.conf.saptune_not_existing*.arp_ignore = 1
+
+[reminder]
+# glob test
+`
+	ini := ParseINI(iniGlob)
+	if _, ok := ini.KeyValue["sysctl"]["net.ipv4.conf.lo.rp_filter"]; ok {
+		t.Error("excluded key 'net.ipv4.conf.lo.rp_filter' found")
+	}
+	if entry := ini.KeyValue["sysctl"]["net.ipv4.conf.all.rp_filter"]; entry.Value != "2" || entry.Section != "sysctl" || entry.Operator != OperatorEqual {
+		t.Errorf("wrong entry for 'net.ipv4.conf.all.rp_filter': '%+v'", entry)
+	}
+	if entry := ini.KeyValue["sysctl"]["net.ipv4.conf.default.rp_filter"]; entry.Value != "1" {
+		t.Errorf("explicit key should take precedence, got: '%+v'", entry)
+	}
+	for _, entry := range ini.AllValues {
+		if system.IsSysctlGlob(entry.Key) || entry.Key == "net.ipv4.conf.lo.rp_filter" {
+			t.Errorf("wrong entry '%+v'", entry)
+		}
+	}
+	if len(ini.AllValues) != len(ini.KeyValue["sysctl"])+1 {
+		t.Errorf("expanded keys are not unique: '%+v'", ini.AllValues)
+	}
+	if _, ok := ini.KeyValue["reminder"]["reminder"]; !ok {
+		t.Error("missing reminder section")
+	}
+}
+
+func TestParseINISysctlSlashKey(t *testing.T) {
+	ini := ParseINI("[sysctl]\nnet.ipv4.conf.eth0/100.rp_filter = 2\nnet/ipv4/conf/eth0.100/arp_ignore = 1\n-net.ipv4.conf.eth0/100.arp_filter\n\n[sys]\nkernel/mm/ksm/run = 1\n")
+	if entry := ini.KeyValue["sysctl"]["net.ipv4.conf.eth0/100.rp_filter"]; entry.Value != "2" || entry.Operator != OperatorEqual {
+		t.Errorf("wrong entry for 'net.ipv4.conf.eth0/100.rp_filter': '%+v'", entry)
+	}
+	// '/' separated notation is normalized
+	if entry := ini.KeyValue["sysctl"]["net.ipv4.conf.eth0/100.arp_ignore"]; entry.Value != "1" {
+		t.Errorf("wrong entry for 'net.ipv4.conf.eth0/100.arp_ignore': '%+v'", entry)
+	}
+	// '/' is still not supported in other sections
+	if len(ini.AllValues) != 2 || len(ini.KeyValue["sys"]) != 0 {
+		t.Errorf("unexpected entries: '%+v'", ini.AllValues)
+	}
+}
@@ -57,8 +57,10 @@ func chkSecTags(secFields, blkDev []string) (bool, []string) {
 			ret = chkCspTags(tagField[1], secFields)
 		case "virt":
 			ret = chkVirtTags(tagField[1], secFields)
-		case "blkvendor", "blkmodel", "blkpat":
+		case "blkvendor", "blkmodel", "blkpat", "blkwwn", "blkserial", "blkbyid":
 			ret, blkDev = chkBlkTags(tagField[0], tagField[1], secFields, blkDev)
+		case "blkstack":
+			ret, blkDev = chkBlkStackTags(tagField[1], secFields, blkDev)
 		case "vendor", "model":
 			ret = chkHWTags(tagField[0], tagField[1], secFields)
 		case "pmu_name":
@@ -324,6 +326,12 @@ func chkBlkTags(info, tagField string, secFields, actbdev []string) (bool, []str
 	ret := false
 	info = strings.TrimPrefix(info, "blk")
 	tagExpr := fmt.Sprintf(".*%s.*", tagField)
+	switch info {
+	case "wwn", "serial", "byid":
+		// stable identifiers need an exact match, otherwise the
+		// identifier of one device may select other devices too
+		tagExpr = "^" + regexp.QuoteMeta(tagField) + "$"
+	}
 	// vendor or model
 	blkInfo := strings.ToUpper(info)
 	if info == "pat" {
@@ -365,3 +373,21 @@ func chkBlkTags(info, tagField string, secFields, actbdev []string) (bool, []str
 	}
 	return ret, bdev
 }
+
+// chkBlkStackTags checks, if the blkstack section tag is valid and selects
+// the block devices of the requested layer of the block device stacks
+// (top, leaves or both)
+func chkBlkStackTags(tagField string, secFields, actbdev []string) (bool, []string) {
+	switch tagField {
+	case "top", "leaves", "both":
+	default:
+		system.WarningLog("wrong value '%s' for section tag 'blkstack' in section definition '%v'. Only 'top', 'leaves' or 'both' supported. Skipping whole section with all lines till next valid section definition", tagField, secFields)
+		return false, actbdev
+	}
+	bdev := system.SelectBlockDeviceLayer(actbdev, tagField)
+	if len(bdev) == 0 {
+		system.InfoLog("block device stack layer '%s' in section definition '%v' does not match any available block device of the running system. Skipping whole section with all lines till next valid section definition", tagField, secFields)
+		return false, bdev
+	}
+	return true, bdev
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/SUSE/saptune/system"
 	"os"
 	"path"
+	"reflect"
 	"testing"
 )
 
@@ -204,3 +205,17 @@ func TestIsTagAvail(t *testing.T) {
 		t.Error("expected 'false', because of wrong syntax, but got 'true'")
 	}
 }
+
+func TestChkBlkStackTags(t *testing.T) {
+	secFields := []string{"block", "blkstack=middle"}
+	if ret, bdevs := chkBlkStackTags("middle", secFields, []string{"sda"}); ret || !reflect.DeepEqual(bdevs, []string{"sda"}) {
+		t.Errorf("expected invalid tag value, got '%v', '%+v'", ret, bdevs)
+	}
+	secFields = []string{"block", "blkstack=both"}
+	if ret, bdevs := chkBlkStackTags("both", secFields, []string{"sda", "dm-0"}); !ret || !reflect.DeepEqual(bdevs, []string{"sda", "dm-0"}) {
+		t.Errorf("expected all block devices, got '%v', '%+v'", ret, bdevs)
+	}
+	if ret, _ := chkBlkStackTags("top", secFields, []string{}); ret {
+		t.Error("expected 'false' for an empty list of block devices, but got 'true'")
+	}
+}